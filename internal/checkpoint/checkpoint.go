@@ -0,0 +1,235 @@
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Key identifies the import a checkpoint belongs to. A checkpoint is only
+// reused when every field matches the current run.
+type Key struct {
+	FileHash    string `json:"file_hash"`
+	Table       string `json:"table"`
+	BatchSize   int    `json:"batch_size"`
+	MappingHash string `json:"mapping_hash"`
+}
+
+// Batch records a committed batch and the file offset and source line just
+// past its last row.
+type Batch struct {
+	Num    int   `json:"num"`
+	Offset int64 `json:"offset"`
+	Line   int   `json:"line"`
+	Rows   int   `json:"rows"`
+}
+
+// Partial records the rows of a failed batch that were committed or
+// rejected before it failed, by source line.
+type Partial struct {
+	Num   int   `json:"num"`
+	Lines []int `json:"lines"`
+}
+
+// Checkpoint tracks which batches of a file have been committed to the target
+// table. Batches 0..Through-1 are all committed and end at Offset (and Line);
+// batches committed out of order beyond that point are kept in Pending until
+// the gap closes. Batches that failed part way through are kept in Partial
+// until they are committed.
+type Checkpoint struct {
+	FilePath  string    `json:"file_path"`
+	Key       Key       `json:"key"`
+	Through   int       `json:"through"`
+	Offset    int64     `json:"offset"`
+	Line      int       `json:"line"`
+	Rows      int       `json:"rows"`
+	Pending   []Batch   `json:"pending,omitempty"`
+	Partial   []Partial `json:"partial,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	path string
+}
+
+// PathFor returns where the checkpoint for csvPath is stored. With an empty
+// stateDir the checkpoint sits next to the CSV; otherwise it goes under
+// stateDir, named after the file plus a hash of its absolute path so files
+// with the same name in different directories don't collide.
+func PathFor(stateDir, csvPath string) string {
+	if stateDir == "" {
+		return csvPath + ".checkpoint.json"
+	}
+	abs, err := filepath.Abs(csvPath)
+	if err != nil {
+		abs = csvPath
+	}
+	sum := sha256.Sum256([]byte(abs))
+	name := fmt.Sprintf("%s.%s.checkpoint.json", filepath.Base(csvPath), hex.EncodeToString(sum[:4]))
+	return filepath.Join(stateDir, name)
+}
+
+// HashFile returns the hex SHA-256 of the file's contents.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashStrings returns the hex SHA-256 of parts, used to fingerprint a column
+// mapping.
+func HashStrings(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// New returns an empty checkpoint for csvPath that will be saved at path.
+func New(path, csvPath string, key Key) *Checkpoint {
+	return &Checkpoint{FilePath: csvPath, Key: key, path: path}
+}
+
+// Load reads the checkpoint at path. It returns nil and no error when the
+// file does not exist.
+func Load(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint %s: %w", path, err)
+	}
+	c.path = path
+	return &c, nil
+}
+
+// Path returns the file the checkpoint is saved to.
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// Committed returns the set of batch numbers committed beyond Through.
+func (c *Checkpoint) Committed() map[int]bool {
+	set := make(map[int]bool, len(c.Pending))
+	for _, b := range c.Pending {
+		set[b.Num] = true
+	}
+	return set
+}
+
+// PendingRows returns the number of rows in batches committed beyond Through,
+// and the rows already settled in partially loaded batches.
+func (c *Checkpoint) PendingRows() int {
+	n := 0
+	for _, b := range c.Pending {
+		n += b.Rows
+	}
+	for _, p := range c.Partial {
+		n += len(p.Lines)
+	}
+	return n
+}
+
+// Settled returns, per partially loaded batch, the source lines of its rows
+// already committed or rejected.
+func (c *Checkpoint) Settled() map[int]map[int]bool {
+	settled := make(map[int]map[int]bool, len(c.Partial))
+	for _, p := range c.Partial {
+		lines := make(map[int]bool, len(p.Lines))
+		for _, l := range p.Lines {
+			lines[l] = true
+		}
+		settled[p.Num] = lines
+	}
+	return settled
+}
+
+// CommitPartial records the rows at lines of batch num as committed or
+// rejected, though the rest of the batch failed, and saves the checkpoint.
+func (c *Checkpoint) CommitPartial(num int, lines []int) error {
+	i := sort.Search(len(c.Partial), func(i int) bool { return c.Partial[i].Num >= num })
+	if i == len(c.Partial) || c.Partial[i].Num != num {
+		c.Partial = slices.Insert(c.Partial, i, Partial{Num: num})
+	}
+	p := &c.Partial[i]
+	p.Lines = append(p.Lines, lines...)
+	slices.Sort(p.Lines)
+	p.Lines = slices.Compact(p.Lines)
+	return c.Save()
+}
+
+// Commit records b as committed and saves the checkpoint. The rows of b
+// settled by an earlier partial load are added to its row count.
+func (c *Checkpoint) Commit(b Batch) error {
+	for i, p := range c.Partial {
+		if p.Num == b.Num {
+			b.Rows += len(p.Lines)
+			c.Partial = slices.Delete(c.Partial, i, i+1)
+			break
+		}
+	}
+	c.Pending = append(c.Pending, b)
+	sort.Slice(c.Pending, func(i, j int) bool { return c.Pending[i].Num < c.Pending[j].Num })
+
+	// Fold any now-contiguous batches into the committed prefix.
+	for len(c.Pending) > 0 && c.Pending[0].Num == c.Through {
+		c.Offset = c.Pending[0].Offset
+		c.Line = c.Pending[0].Line
+		c.Rows += c.Pending[0].Rows
+		c.Through++
+		c.Pending = c.Pending[1:]
+	}
+	return c.Save()
+}
+
+// Save writes the checkpoint atomically (temp file + rename).
+func (c *Checkpoint) Save() error {
+	c.UpdatedAt = time.Now().UTC()
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding checkpoint: %w", err)
+	}
+
+	if dir := filepath.Dir(c.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("replacing checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the checkpoint file. A missing file is not an error.
+func (c *Checkpoint) Remove() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package checkpoint
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitFoldsContiguousBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv.checkpoint.json")
+	cp := New(path, "data.csv", Key{FileHash: "abc", Table: "dbo.T", BatchSize: 10})
+
+	// Batches complete out of order, as they do with several workers.
+	for _, b := range []Batch{
+		{Num: 1, Offset: 200, Rows: 10},
+		{Num: 3, Offset: 400, Rows: 10},
+		{Num: 0, Offset: 100, Rows: 10},
+	} {
+		if err := cp.Commit(b); err != nil {
+			t.Fatalf("commit batch %d: %v", b.Num, err)
+		}
+	}
+
+	if cp.Through != 2 || cp.Offset != 200 || cp.Rows != 20 {
+		t.Errorf("expected prefix through=2 offset=200 rows=20, got through=%d offset=%d rows=%d",
+			cp.Through, cp.Offset, cp.Rows)
+	}
+	if got := cp.Committed(); len(got) != 1 || !got[3] {
+		t.Errorf("expected only batch 3 pending, got %v", got)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if loaded.Key != cp.Key || loaded.Through != 2 || loaded.PendingRows() != 10 {
+		t.Errorf("reloaded checkpoint does not match: %+v", loaded)
+	}
+}
+
+func TestCommitPartial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.csv.checkpoint.json")
+	cp := New(path, "data.csv", Key{FileHash: "abc", Table: "dbo.T", BatchSize: 10})
+
+	// Batch 0 fails twice, settling some rows each time, then loads the rest.
+	if err := cp.CommitPartial(0, []int{2, 3, 7}); err != nil {
+		t.Fatalf("commit partial: %v", err)
+	}
+	if err := cp.CommitPartial(0, []int{7, 4}); err != nil {
+		t.Fatalf("commit partial: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	settled := loaded.Settled()[0]
+	if len(settled) != 4 || !settled[2] || !settled[4] || !settled[7] || loaded.PendingRows() != 4 {
+		t.Errorf("expected lines 2, 3, 4 and 7 settled, got %v", settled)
+	}
+
+	if err := loaded.Commit(Batch{Num: 0, Offset: 100, Rows: 6}); err != nil {
+		t.Fatalf("commit batch 0: %v", err)
+	}
+	if loaded.Through != 1 || loaded.Rows != 10 || len(loaded.Partial) != 0 {
+		t.Errorf("expected batch 0 committed with 10 rows, got through=%d rows=%d partial=%v",
+			loaded.Through, loaded.Rows, loaded.Partial)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	cp, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || cp != nil {
+		t.Fatalf("expected nil checkpoint and no error, got %v, %v", cp, err)
+	}
+}
+
+func TestPathFor(t *testing.T) {
+	if got := PathFor("", "in/data.csv"); got != "in/data.csv.checkpoint.json" {
+		t.Errorf("unexpected default path %s", got)
+	}
+	a := PathFor("state", "a/data.csv")
+	b := PathFor("state", "b/data.csv")
+	if a == b || filepath.Dir(a) != "state" {
+		t.Errorf("expected distinct paths under state/, got %s and %s", a, b)
+	}
+}
+
+func TestWatermarksRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "watermarks.json")
+	w, err := LoadWatermarks(path)
+	if err != nil || len(w.Tables) != 0 {
+		t.Fatalf("expected empty watermarks, got %v, %v", w, err)
+	}
+	if err := w.Set("dbo.ORDERS", Watermark{Column: "RV", Value: "0x00000000000007D1", File: "ORDERS_inserts_20260211_170255.csv"}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	loaded, err := LoadWatermarks(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	mark, ok := loaded.Get("DBO.orders")
+	if !ok || mark.Value != "0x00000000000007D1" || mark.Column != "RV" || mark.UpdatedAt.IsZero() {
+		t.Errorf("reloaded watermark does not match: %+v", mark)
+	}
+}
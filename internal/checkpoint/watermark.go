@@ -0,0 +1,122 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Watermark is the highest value of a table's watermark column exported so
+// far, as text (see export --incremental).
+type Watermark struct {
+	Column string `json:"column"`
+	Value  string `json:"value"`
+	// File is the export that reached Value.
+	File      string    `json:"file"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Watermarks holds the watermark of each incrementally exported table,
+// keyed by lower-case schema.table.
+type Watermarks struct {
+	Tables map[string]Watermark `json:"tables"`
+
+	path string
+}
+
+// LoadWatermarks reads the watermarks saved at path. A missing file gives
+// an empty set.
+func LoadWatermarks(path string) (*Watermarks, error) {
+	w := &Watermarks{Tables: map[string]Watermark{}, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading watermarks: %w", err)
+	}
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("parsing watermarks %s: %w", path, err)
+	}
+	if w.Tables == nil {
+		w.Tables = map[string]Watermark{}
+	}
+	return w, nil
+}
+
+// Path returns the file the watermarks are saved to.
+func (w *Watermarks) Path() string {
+	return w.path
+}
+
+// Set records mark for table and saves the watermarks.
+func (w *Watermarks) Set(table string, mark Watermark) error {
+	mark.UpdatedAt = time.Now().UTC()
+	w.Tables[tableKey(table)] = mark
+	return w.Save()
+}
+
+// Get returns the watermark recorded for table, if any.
+func (w *Watermarks) Get(table string) (Watermark, bool) {
+	mark, ok := w.Tables[tableKey(table)]
+	return mark, ok
+}
+
+// Save writes the watermarks atomically (temp file + rename), syncing the
+// file and its directory so a recorded watermark survives a crash.
+func (w *Watermarks) Save() error {
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding watermarks: %w", err)
+	}
+
+	dir := filepath.Dir(w.path)
+	if dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+
+	tmp := w.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("writing watermarks: %w", err)
+	}
+	defer os.Remove(tmp) //nolint:errcheck // no-op once renamed
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("writing watermarks: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing watermarks: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing watermarks: %w", err)
+	}
+	if err := os.Rename(tmp, w.path); err != nil {
+		return fmt.Errorf("replacing watermarks: %w", err)
+	}
+	return SyncDir(dir)
+}
+
+// SyncDir flushes dir's entries to disk, making renames into it durable.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", dir, err)
+	}
+	return nil
+}
+
+func tableKey(table string) string {
+	return strings.ToLower(table)
+}
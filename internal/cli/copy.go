@@ -0,0 +1,529 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+	"github.com/walkerscm/scaleSyncGo/internal/worker"
+)
+
+var copyCmd = &cobra.Command{
+	Use:   "copy",
+	Short: "Copy tables from one target database to another",
+	Long: `Stream the rows of tables in one target database (--from) straight into the
+same tables in another (--to), upserting on the destination's primary key as
+import does. Only the columns both tables have are copied.
+
+Name the tables with --tables, or copy every source table the destination
+also has with --all, narrowed with --include and --exclude glob patterns.
+A pattern without a schema, such as ORDER*, matches the table name alone.`,
+	RunE: runCopy,
+}
+
+func init() {
+	copyCmd.Flags().String("env", ".env", "path to .env file")
+	copyCmd.Flags().String("from", "", `target to copy from: "prod" or "test"`)
+	copyCmd.Flags().String("to", "", `target to copy into: "prod" or "test"`)
+	copyCmd.Flags().StringSlice("tables", nil, "tables to copy as schema.name")
+	copyCmd.Flags().Bool("all", false, "copy every source table that also exists in the destination")
+	copyCmd.Flags().StringSlice("include", nil, "with --all, only copy tables matching these glob patterns")
+	copyCmd.Flags().StringSlice("exclude", nil, "with --all, skip tables matching these glob patterns")
+	copyCmd.Flags().Int("batch-size", 1000, "rows per batch")
+	copyCmd.Flags().Int("workers", 4, "parallel worker count")
+	copyCmd.Flags().String("oversize", database.OversizeError, "values too long or precise for their column: error (reject the row), truncate or null")
+	copyCmd.Flags().BoolP("yes", "y", false, "skip confirmation prompt")
+	copyCmd.MarkFlagRequired("from") //nolint:errcheck
+	copyCmd.MarkFlagRequired("to")   //nolint:errcheck
+	copyCmd.MarkFlagsMutuallyExclusive("tables", "all")
+	copyCmd.MarkFlagsOneRequired("tables", "all")
+	rootCmd.AddCommand(copyCmd)
+}
+
+// copyPair is a table to copy, as spelled in the source and destination.
+type copyPair struct {
+	Source string
+	Dest   string
+}
+
+// copyStats summarises the copy of one table. It is also the table's entry
+// in the JSON report.
+type copyStats struct {
+	Source string `json:"source_table"`
+	Table  string `json:"table"`
+	// Status is "ok", "partial" (some rows or batches failed) or "failed".
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Read     int    `json:"rows_read"`
+	Inserted int    `json:"rows_inserted"`
+	Rejected int    `json:"rows_rejected"`
+	// SkippedColumns are source columns the destination table doesn't have.
+	SkippedColumns []string `json:"skipped_columns"`
+	FailedBatches  int      `json:"failed_batches"`
+	// Errors holds the first few batch errors.
+	Errors      []string `json:"errors"`
+	DurationMS  int64    `json:"duration_ms"`
+	RowsPerSec  float64  `json:"rows_per_sec"`
+	RejectsPath string   `json:"rejects_file,omitempty"`
+}
+
+// copyReport is the machine-readable outcome of a copy run.
+type copyReport struct {
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Tables []copyStats `json:"tables"`
+	// Missing are tables selected by --all that the destination lacks.
+	Missing      []string `json:"not_in_destination"`
+	Succeeded    int      `json:"succeeded"`
+	Failed       int      `json:"failed"`
+	TotalRows    int      `json:"total_rows"`
+	RejectedRows int      `json:"rejected_rows"`
+}
+
+func runCopy(cmd *cobra.Command, args []string) error {
+	envPath, _ := cmd.Flags().GetString("env")
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	tables, _ := cmd.Flags().GetStringSlice("tables")
+	all, _ := cmd.Flags().GetBool("all")
+	include, _ := cmd.Flags().GetStringSlice("include")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	workers, _ := cmd.Flags().GetInt("workers")
+	oversize, _ := cmd.Flags().GetString("oversize")
+	autoConfirm, _ := cmd.Flags().GetBool("yes")
+
+	if from == to {
+		return fmt.Errorf("--from and --to must name different targets")
+	}
+	if !all && (len(include) > 0 || len(exclude) > 0) {
+		return fmt.Errorf("--include and --exclude only apply with --all")
+	}
+	for _, p := range append(append([]string{}, include...), exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("bad table pattern %q: %w", p, err)
+		}
+	}
+	if err := database.CheckOversize(oversize); err != nil {
+		return fmt.Errorf("--oversize: %w", err)
+	}
+	opts := importOptions{BatchSize: batchSize, Workers: workers, Oversize: oversize}
+	out := humanOut()
+
+	src, err := connectTarget(envPath, from, out)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dest, err := connectTarget(envPath, to, out)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	ctx := context.Background()
+	srcTables, err := src.ListTables(ctx)
+	if err != nil {
+		return fmt.Errorf("listing %s tables: %w", from, err)
+	}
+	destTables, err := dest.ListTables(ctx)
+	if err != nil {
+		return fmt.Errorf("listing %s tables: %w", to, err)
+	}
+	for i, t := range tables {
+		tables[i] = qualifyTable(strings.TrimSpace(t), src.DefaultSchema())
+	}
+	pairs, missing, err := selectCopyTables(srcTables, destTables, tables, all, include, exclude)
+	if err != nil {
+		return err
+	}
+
+	report := copyReport{From: from, To: to, Tables: []copyStats{}, Missing: []string{}}
+	report.Missing = append(report.Missing, missing...)
+	finish := func(err error) error {
+		if jsonOutput() {
+			if jerr := printJSON(report); jerr != nil {
+				return jerr
+			}
+		}
+		return err
+	}
+
+	fmt.Fprintln(out)
+	if len(missing) > 0 {
+		fmt.Fprintf(out, "Not in %s: %d table(s), skipped: %s\n", to, len(missing), strings.Join(missing, ", "))
+	}
+	if len(pairs) == 0 {
+		fmt.Fprintln(out, "No tables to copy. Nothing to do.")
+		return finish(nil)
+	}
+	fmt.Fprintf(out, "Copying %d table(s) from %s to %s:\n", len(pairs), from, to)
+	for _, p := range pairs {
+		fmt.Fprintf(out, "  %s → %s\n", p.Source, p.Dest)
+	}
+	fmt.Fprintln(out)
+
+	if !autoConfirm {
+		confirmPrompt := promptui.Prompt{
+			Label:     fmt.Sprintf("Copy %d table(s) into %s", len(pairs), to),
+			IsConfirm: true,
+			Stdout:    promptOut(),
+		}
+		if _, err := confirmPrompt.Run(); err != nil {
+			fmt.Fprintln(out, "Copy cancelled.")
+			return finish(nil)
+		}
+	}
+
+	partial := 0
+	for i, p := range pairs {
+		fmt.Fprintf(out, "\n[%d/%d] Copying %s → %s...\n", i+1, len(pairs), p.Source, p.Dest)
+		stats, err := copyTable(ctx, src, dest, p, opts, out)
+		switch {
+		case err != nil && stats.Inserted == 0:
+			stats.Status = "failed"
+		case err != nil, stats.Rejected > 0:
+			stats.Status = "partial"
+		default:
+			stats.Status = "ok"
+		}
+		if err != nil {
+			stats.Error = err.Error()
+			fmt.Fprintf(out, "ERROR: %s: %v\n", p.Dest, err)
+		}
+		if stats.Status == "failed" {
+			report.Failed++
+		} else {
+			report.Succeeded++
+			if stats.Status == "partial" {
+				partial++
+			}
+		}
+		report.TotalRows += stats.Inserted
+		report.RejectedRows += stats.Rejected
+		report.Tables = append(report.Tables, stats)
+	}
+
+	fmt.Fprintln(out, "\n=== Copy Summary ===")
+	fmt.Fprintf(out, "Tables copied:   %d\n", len(pairs))
+	fmt.Fprintf(out, "Succeeded:       %d\n", report.Succeeded)
+	fmt.Fprintf(out, "Failed:          %d\n", report.Failed)
+	fmt.Fprintf(out, "Total rows:      %d\n", report.TotalRows)
+	fmt.Fprintf(out, "Rejected rows:   %d\n", report.RejectedRows)
+	logger.Info("copy finished", "from", from, "to", to, "tables", len(pairs), "succeeded", report.Succeeded,
+		"failed", report.Failed, "rows", report.TotalRows, "rejected", report.RejectedRows)
+
+	switch {
+	case report.Failed == len(pairs):
+		return finish(fmt.Errorf("all %d table(s) failed", report.Failed))
+	case report.Failed > 0 || partial > 0:
+		return finish(partialFailure(fmt.Errorf("%d of %d table(s) failed, %d row(s) rejected",
+			report.Failed, len(pairs), report.RejectedRows)))
+	}
+	return finish(nil)
+}
+
+// selectCopyTables resolves the tables to copy against the source and
+// destination table lists, matching names case-insensitively. Tables named in
+// named must exist on both sides. With all, every source table matching an
+// include pattern (any, when there are none) and no exclude pattern is
+// copied; those the destination lacks are returned in missing.
+func selectCopyTables(srcTables, destTables, named []string, all bool, include, exclude []string) (pairs []copyPair, missing []string, err error) {
+	srcLookup := make(map[string]string, len(srcTables))
+	for _, t := range srcTables {
+		srcLookup[strings.ToLower(t)] = t
+	}
+	destLookup := make(map[string]string, len(destTables))
+	for _, t := range destTables {
+		destLookup[strings.ToLower(t)] = t
+	}
+
+	if !all {
+		for _, t := range named {
+			s, ok := srcLookup[strings.ToLower(t)]
+			if !ok {
+				return nil, nil, fmt.Errorf("table %s not found in the source database", t)
+			}
+			d, ok := destLookup[strings.ToLower(t)]
+			if !ok {
+				return nil, nil, fmt.Errorf("table %s not found in the destination database", t)
+			}
+			pairs = append(pairs, copyPair{Source: s, Dest: d})
+		}
+		return pairs, nil, nil
+	}
+
+	for _, t := range srcTables {
+		if len(include) > 0 && !matchTable(t, include) || matchTable(t, exclude) {
+			continue
+		}
+		d, ok := destLookup[strings.ToLower(t)]
+		if !ok {
+			missing = append(missing, t)
+			continue
+		}
+		pairs = append(pairs, copyPair{Source: t, Dest: d})
+	}
+	return pairs, missing, nil
+}
+
+// matchTable reports whether schemaTable matches any of the glob patterns,
+// ignoring case. Patterns without a schema match the table name alone.
+func matchTable(schemaTable string, patterns []string) bool {
+	for _, p := range patterns {
+		name := strings.ToLower(schemaTable)
+		if !strings.Contains(p, ".") {
+			_, name = database.SplitSchemaTable(name)
+		}
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// insertableColumns drops the columns of a destination table that can't be
+// written: computed and generated columns, and SQL Server rowversion columns,
+// which INFORMATION_SCHEMA reports as timestamp.
+func insertableColumns(driver string, cols []database.TableColumn) []database.TableColumn {
+	var keep []database.TableColumn
+	for _, c := range cols {
+		if c.Computed {
+			continue
+		}
+		if driver == database.DriverSQLServer &&
+			(strings.EqualFold(c.DataType, "timestamp") || strings.EqualFold(c.DataType, "rowversion")) {
+			continue
+		}
+		keep = append(keep, c)
+	}
+	return keep
+}
+
+// copyRejectsPath returns where rows refused while copying into schemaTable
+// are written.
+func copyRejectsPath(schemaTable string) string {
+	return filepath.Join(reportsDir, schemaTable+".copy"+csvutil.RejectsSuffix)
+}
+
+// copyTable streams the rows of p.Source into p.Dest through the worker pool,
+// upserting on the destination's primary key. Only the columns both tables
+// have are copied; the values keep their database types all the way, and
+// text is copied exactly, untrimmed. Progress is written to w.
+//
+// Rows the destination refuses are written to a rejects file in reports/ and
+// don't fail the copy. An error is returned only when whole batches could
+// not be loaded or the source could not be read to the end.
+func copyTable(ctx context.Context, src, dest database.Destination, p copyPair, opts importOptions, w io.Writer) (copyStats, error) {
+	stats := copyStats{Source: p.Source, Table: p.Dest, SkippedColumns: []string{}, Errors: []string{}}
+	log := logger.With("source", p.Source, "table", p.Dest)
+
+	// Intersect the two tables' columns
+	srcCols, err := src.GetTableColumns(ctx, p.Source)
+	if err != nil {
+		return stats, fmt.Errorf("getting source columns: %w", err)
+	}
+	destCols, err := dest.GetTableColumns(ctx, p.Dest)
+	if err != nil {
+		return stats, fmt.Errorf("getting table columns: %w", err)
+	}
+	names := make([]string, len(srcCols))
+	for i, c := range srcCols {
+		names[i] = c.Name
+	}
+	mr, err := database.MapColumns(names, insertableColumns(dest.Driver(), destCols), nil)
+	if err != nil {
+		return stats, fmt.Errorf("column mapping: %w", err)
+	}
+	if len(mr.Mapped) == 0 {
+		return stats, fmt.Errorf("%s and %s have no columns in common", p.Source, p.Dest)
+	}
+	if mr.Skipped != nil {
+		stats.SkippedColumns = mr.Skipped
+	}
+
+	// Select only the shared columns, so they line up with the mapping
+	selected := make([]string, len(mr.Mapped))
+	dbColumns := make([]string, len(mr.Mapped))
+	for i := range mr.Mapped {
+		m := &mr.Mapped[i]
+		selected[i] = m.CSVName
+		dbColumns[i] = m.DBColumn.Name
+		m.CSVIndex = i
+		m.Oversize = opts.Oversize
+	}
+	fmt.Fprintf(w, "Copying %d of %d columns\n", len(selected), len(srcCols))
+	if len(mr.Skipped) > 0 {
+		fmt.Fprintf(w, "Skipped %d source columns (not in destination): %s\n", len(mr.Skipped), strings.Join(mr.Skipped, ", "))
+	}
+
+	pkColumns, err := dest.GetPrimaryKeyColumns(ctx, p.Dest)
+	if err != nil {
+		return stats, fmt.Errorf("getting primary key columns: %w", err)
+	}
+	if len(pkColumns) > 0 {
+		fmt.Fprintf(w, "Primary key: %s\n", strings.Join(pkColumns, ", "))
+	} else {
+		fmt.Fprintln(w, "No primary key found — using insert-only mode")
+	}
+	hasIdentity, err := dest.HasIdentityColumn(ctx, p.Dest)
+	if err != nil {
+		return stats, fmt.Errorf("checking identity column: %w", err)
+	}
+
+	// Clear the last run's rejects before anything starts that would have to
+	// be stopped if that fails.
+	rejects := newRejectWriter(copyRejectsPath(p.Dest), selected, false)
+	if err := os.Remove(rejects.path); err != nil && !os.IsNotExist(err) {
+		return stats, fmt.Errorf("removing old rejects file: %w", err)
+	}
+
+	stream, err := database.StreamQuery(ctx, src.DB(), src.BuildSelect(p.Source, selected, "", ""))
+	if err != nil {
+		return stats, err
+	}
+	defer stream.Close()
+	log.Info("copy started", "columns", len(selected), "primary_key", pkColumns)
+
+	pool := worker.NewPool(dest, p.Dest, dbColumns, pkColumns, hasIdentity, mr.Mapped, opts.Workers, log)
+	pool.Start(ctx)
+
+	// Feed batches. Strings are marked Verbatim so text is copied untrimmed
+	// and empty strings stay empty. readRows and readErr are only read after
+	// the results channel is drained.
+	var readRows int
+	var readErr error
+	go func() {
+		defer pool.Done()
+		batchNum := 0
+		var values [][]interface{}
+		var lines []int
+		submit := func() {
+			pool.Submit(worker.Job{BatchNum: batchNum, Values: values, Lines: lines})
+			batchNum++
+			values, lines = nil, nil
+		}
+		for stream.Next() {
+			row, err := stream.Values()
+			if err != nil {
+				readErr = err
+				break
+			}
+			vals := make([]interface{}, len(row))
+			for i, v := range row {
+				if s, ok := v.(string); ok {
+					vals[i] = worker.Verbatim(s)
+				} else {
+					vals[i] = v
+				}
+			}
+			readRows++
+			values = append(values, vals)
+			lines = append(lines, readRows)
+			if len(values) == opts.BatchSize {
+				submit()
+			}
+		}
+		if readErr == nil {
+			if err := stream.Err(); err != nil {
+				readErr = fmt.Errorf("reading rows: %w", err)
+			}
+		}
+		if len(values) > 0 {
+			submit()
+		}
+		if readErr != nil {
+			log.Error("reading source", "row", readRows, "err", readErr)
+		}
+	}()
+
+	// Collect results
+	start := time.Now()
+	bar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Copying"),
+		progressbar.OptionSetWriter(w),
+		progressbar.OptionShowCount(),
+		progressbar.OptionThrottle(200*time.Millisecond),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetItsString("rows"),
+		progressbar.OptionOnCompletion(func() { fmt.Fprintln(w) }),
+	)
+	var rejectsErr error
+	for result := range pool.Results() {
+		stats.Inserted += result.RowCount
+		if rejectsErr == nil {
+			rejectsErr = rejects.Write(result.Rejects)
+		}
+		if result.Err != nil {
+			stats.FailedBatches++
+			if len(stats.Errors) < 10 {
+				stats.Errors = append(stats.Errors, result.Err.Error())
+			}
+		}
+		bar.Add(result.RowCount + len(result.Rejects)) //nolint:errcheck
+	}
+	bar.Finish() //nolint:errcheck
+
+	if err := rejects.Close(); err != nil && rejectsErr == nil {
+		rejectsErr = err
+	}
+	stats.Read = readRows
+	stats.Rejected = rejects.Count()
+	if stats.Rejected > 0 {
+		stats.RejectsPath = rejects.path
+	}
+
+	// Summary
+	elapsed := time.Since(start)
+	stats.DurationMS = elapsed.Milliseconds()
+	stats.RowsPerSec = float64(stats.Inserted) / elapsed.Seconds()
+
+	fmt.Fprintf(w, "\n--- Copy Summary ---\n")
+	fmt.Fprintf(w, "Source:              %s\n", p.Source)
+	fmt.Fprintf(w, "Table:               %s\n", p.Dest)
+	fmt.Fprintf(w, "Rows read:           %d\n", stats.Read)
+	fmt.Fprintf(w, "Rows inserted:       %d\n", stats.Inserted)
+	fmt.Fprintf(w, "Rows rejected:       %d\n", stats.Rejected)
+	fmt.Fprintf(w, "Duration:            %s\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "Throughput:          %.0f rows/sec\n", stats.RowsPerSec)
+	if stats.FailedBatches > 0 {
+		fmt.Fprintf(w, "Failed batches:      %d\n", stats.FailedBatches)
+	}
+	if stats.Rejected > 0 {
+		fmt.Fprintf(w, "Rejects written to:  %s\n", stats.RejectsPath)
+	}
+	if rejectsErr != nil {
+		fmt.Fprintf(w, "WARNING: rejects file incomplete: %v\n", rejectsErr)
+		log.Warn("rejects file incomplete", "err", rejectsErr)
+	}
+
+	log.Info("copy finished",
+		"read", stats.Read,
+		"inserted", stats.Inserted,
+		"rejected", stats.Rejected,
+		"failed_batches", stats.FailedBatches,
+		"duration", elapsed,
+		"rows_per_sec", int(stats.RowsPerSec))
+
+	if len(stats.Errors) > 0 {
+		fmt.Fprintln(w, "\nFirst errors:")
+		for _, e := range stats.Errors {
+			fmt.Fprintf(w, "  - %s\n", e)
+		}
+		return stats, fmt.Errorf("%d batch errors during copy", stats.FailedBatches)
+	}
+	if readErr != nil {
+		return stats, fmt.Errorf("reading %s: %w", p.Source, readErr)
+	}
+	return stats, nil
+}
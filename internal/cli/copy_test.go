@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+func TestSelectCopyTables(t *testing.T) {
+	src := []string{"dbo.CUSTOMERS", "dbo.ORDERS", "dbo.ORDER_LINES", "audit.ORDERS_LOG", "dbo.SCRATCH"}
+	dest := []string{"dbo.Customers", "dbo.ORDERS", "dbo.ORDER_LINES", "audit.ORDERS_LOG"}
+
+	pairs, missing, err := selectCopyTables(src, dest, []string{"dbo.customers", "dbo.ORDERS"}, false, nil, nil)
+	want := []copyPair{{"dbo.CUSTOMERS", "dbo.Customers"}, {"dbo.ORDERS", "dbo.ORDERS"}}
+	if err != nil || !reflect.DeepEqual(pairs, want) || missing != nil {
+		t.Fatalf("named: got %v, %v, %v; want %v", pairs, missing, err, want)
+	}
+	if _, _, err := selectCopyTables(src, dest, []string{"dbo.SCRATCH"}, false, nil, nil); err == nil {
+		t.Error("named table missing from the destination: want error")
+	}
+
+	pairs, missing, err = selectCopyTables(src, dest, nil, true, []string{"order*", "dbo.s*"}, []string{"*_lines"})
+	want = []copyPair{{"dbo.ORDERS", "dbo.ORDERS"}, {"audit.ORDERS_LOG", "audit.ORDERS_LOG"}}
+	if err != nil || !reflect.DeepEqual(pairs, want) || !reflect.DeepEqual(missing, []string{"dbo.SCRATCH"}) {
+		t.Fatalf("all: got %v, missing %v, %v; want %v", pairs, missing, err, want)
+	}
+}
+
+func TestInsertableColumns(t *testing.T) {
+	cols := []database.TableColumn{
+		{Name: "ID", DataType: "int"},
+		{Name: "TOTAL", DataType: "decimal", Computed: true},
+		{Name: "VER", DataType: "timestamp"},
+		{Name: "NAME", DataType: "nvarchar"},
+	}
+	names := func(cols []database.TableColumn) []string {
+		var out []string
+		for _, c := range cols {
+			out = append(out, c.Name)
+		}
+		return out
+	}
+	if got, want := names(insertableColumns(database.DriverSQLServer, cols)), []string{"ID", "NAME"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sqlserver: got %v, want %v", got, want)
+	}
+	// timestamp is a plain date/time type on Postgres.
+	if got, want := names(insertableColumns(database.DriverPostgres, cols)), []string{"ID", "VER", "NAME"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("postgres: got %v, want %v", got, want)
+	}
+}
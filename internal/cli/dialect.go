@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+)
+
+// addInputFlags adds the flags describing how input files are written.
+func addInputFlags(cmd *cobra.Command) {
+	cmd.Flags().String("delimiter", "", `field delimiter: a character, "tab" or "auto" (default ",")`)
+	cmd.Flags().String("quote", "", `quote character, or "none" (default '"')`)
+	cmd.Flags().String("comment", "", "skip lines starting with this character")
+	cmd.Flags().String("encoding", "", `input encoding, e.g. utf-16le or windows-1252, or "auto" (default utf-8)`)
+	cmd.Flags().Bool("sniff", false, "detect the delimiter and encoding from the start of each file")
+	cmd.Flags().StringSlice("keep-json", nil, `JSON Lines: nested objects to load as JSON text instead of flattening, by dotted path, or "*" for all`)
+	cmd.Flags().Int("header-row", 0, "Excel: row number holding the column names (default: detect)")
+}
+
+// dialectFlags returns the dialect given on the command line. Fields not
+// given are left empty, to be filled from config.yaml by dialectFor.
+func dialectFlags(cmd *cobra.Command) (csvutil.Dialect, error) {
+	var d csvutil.Dialect
+	d.Delimiter, _ = cmd.Flags().GetString("delimiter")
+	d.Quote, _ = cmd.Flags().GetString("quote")
+	d.Comment, _ = cmd.Flags().GetString("comment")
+	d.Encoding, _ = cmd.Flags().GetString("encoding")
+	if sniff, _ := cmd.Flags().GetBool("sniff"); sniff {
+		d = csvutil.Dialect{Delimiter: csvutil.AutoDetect, Encoding: csvutil.AutoDetect}.Merge(d)
+	}
+	if err := d.Validate(); err != nil {
+		return d, fmt.Errorf("invalid CSV dialect: %w", err)
+	}
+	return d, nil
+}
+
+// dialectFor returns the dialect of files loaded into schemaTable: the
+// dialects entry for "*" in config.yaml, overridden by the table's own
+// entry, overridden by the flags.
+func dialectFor(schemaTable string, flags csvutil.Dialect) csvutil.Dialect {
+	var all, table csvutil.Dialect
+	if cfg != nil {
+		for _, e := range cfg.Dialects {
+			d := csvutil.Dialect{Delimiter: e.Delimiter, Quote: e.Quote, Comment: e.Comment, Encoding: e.Encoding}
+			switch {
+			case e.Table == "*":
+				all = d
+			case strings.EqualFold(e.Table, schemaTable):
+				table = d
+			}
+		}
+	}
+	return all.Merge(table).Merge(flags)
+}
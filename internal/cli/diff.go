@@ -0,0 +1,725 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+	"github.com/walkerscm/scaleSyncGo/internal/worker"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the rows of an input file with a table",
+	Long: `Match the rows of an input file to the rows of a table on the table's
+primary key and report the rows only in the file and the rows whose values
+differ, column by column. With --only-in-table or --where, also report the
+table rows the file lacks. Values are converted as import would load them
+before comparing. Nothing is written to the database.`,
+	RunE: runDiff,
+}
+
+// Diff report formats accepted by --format.
+const (
+	diffText     = "text"
+	diffCSV      = "csv"
+	diffMarkdown = "markdown"
+)
+
+func init() {
+	diffCmd.Flags().String("env", ".env", "path to .env file")
+	diffCmd.Flags().String("file", "", "path to input file")
+	diffCmd.Flags().String("sheet", "", "Excel: sheet to read from --file, by name or 1-based position")
+	diffCmd.Flags().String("table", "", "table to compare with as schema.name")
+	diffCmd.Flags().String("mapping", "", "column mapping file (YAML/JSON) (default: the one stored under mappings/)")
+	diffCmd.Flags().String("where", "", "only compare table rows matching this SQL predicate; implies --only-in-table")
+	diffCmd.Flags().Bool("only-in-table", false, "also list the table rows the file lacks, reading every key of the table")
+	diffCmd.Flags().String("format", diffText, "report format: text, csv or markdown")
+	diffCmd.Flags().String("report", "", "write the report here (default: reports/diff-<table>-<timestamp>.<ext>)")
+	diffCmd.MarkFlagRequired("file")  //nolint:errcheck
+	diffCmd.MarkFlagRequired("table") //nolint:errcheck
+	addInputFlags(diffCmd)
+	rootCmd.AddCommand(diffCmd)
+}
+
+// diffResult lists the differences between a file and a table.
+type diffResult struct {
+	FileRows int `json:"file_rows"`
+	// TableRows counts the table rows matching a file row or, once the
+	// table is scanned, every row in scope.
+	TableRows int `json:"table_rows"`
+	Same      int `json:"rows_same"`
+	// TableScanned is set when the table was read for rows the file lacks;
+	// OnlyInTable is empty otherwise.
+	TableScanned bool `json:"table_scanned"`
+	// DuplicateKeys counts file rows whose key an earlier row already had;
+	// the last of them is compared.
+	DuplicateKeys int          `json:"duplicate_keys"`
+	OnlyInFile    []string     `json:"only_in_file"`
+	OnlyInTable   []string     `json:"only_in_table"`
+	Changed       []changedRow `json:"changed"`
+	// Unparseable lists the rows whose values import would reject; they
+	// aren't compared.
+	Unparseable []unparseableRow `json:"unparseable"`
+}
+
+// unparseableRow is a row whose values couldn't be converted for the table:
+// a file row, by its line, or a table row, by its key.
+type unparseableRow struct {
+	Source string `json:"source"` // "file" or "table"
+	Line   int    `json:"line,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Error  string `json:"error"`
+}
+
+// changedRow is a row in both the file and the table whose values differ.
+type changedRow struct {
+	Key     string         `json:"key"`
+	Columns []columnChange `json:"columns"`
+}
+
+// columnChange is one differing value; nil is NULL.
+type columnChange struct {
+	Column string  `json:"column"`
+	Table  *string `json:"table_value"`
+	File   *string `json:"file_value"`
+}
+
+// diffReport is the machine-readable outcome of diff.
+type diffReport struct {
+	File   string `json:"file"`
+	Table  string `json:"table"`
+	Report string `json:"report"`
+	diffResult
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	envPath, _ := cmd.Flags().GetString("env")
+	target, _ := cmd.Flags().GetString("target")
+	filePath, _ := cmd.Flags().GetString("file")
+	sheet, _ := cmd.Flags().GetString("sheet")
+	tableName, _ := cmd.Flags().GetString("table")
+	mappingPath, _ := cmd.Flags().GetString("mapping")
+	where, _ := cmd.Flags().GetString("where")
+	onlyInTable, _ := cmd.Flags().GetBool("only-in-table")
+	format, _ := cmd.Flags().GetString("format")
+	reportPath, _ := cmd.Flags().GetString("report")
+
+	ext, ok := map[string]string{diffText: ".txt", diffCSV: ".csv", diffMarkdown: ".md"}[format]
+	if !ok {
+		return fmt.Errorf("unknown --format %q (want %s, %s or %s)", format, diffText, diffCSV, diffMarkdown)
+	}
+	if _, err := os.Stat(csvutil.SourcePath(filePath)); err != nil {
+		return fmt.Errorf("input file not found: %s", filePath)
+	}
+	dialect, err := dialectFlags(cmd)
+	if err != nil {
+		return err
+	}
+	opts := importOptions{MappingPath: mappingPath, Dialect: dialect}
+	opts.KeepJSON, _ = cmd.Flags().GetStringSlice("keep-json")
+	opts.HeaderRow, _ = cmd.Flags().GetInt("header-row")
+
+	path := filePath
+	if csvutil.IsWorkbook(filePath) {
+		if path, err = selectSheet(filePath, sheet, true); err != nil {
+			return err
+		}
+	} else if sheet != "" {
+		return fmt.Errorf("--sheet needs --file naming an .xlsx workbook")
+	}
+	if reportPath == "" {
+		reportPath = filepath.Join(reportsDir,
+			fmt.Sprintf("diff-%s-%s%s", tableName, time.Now().Format("20060102-150405"), ext))
+	}
+	out := humanOut()
+
+	dest, err := connectTarget(envPath, target, out)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	ctx := context.Background()
+	res, err := diffTable(ctx, dest, path, tableName, where, onlyInTable, opts, out)
+	if err != nil {
+		return err
+	}
+	if err := writeDiffReport(reportPath, format, csvutil.DisplayName(path), tableName, res); err != nil {
+		return err
+	}
+	logger.Info("diff finished", "file", csvutil.DisplayName(path), "table", tableName,
+		"only_in_file", len(res.OnlyInFile), "only_in_table", len(res.OnlyInTable), "changed", len(res.Changed),
+		"unparseable", len(res.Unparseable))
+
+	fmt.Fprintf(out, "\n--- Diff Summary ---\n")
+	fmt.Fprintf(out, "File rows:          %d\n", res.FileRows)
+	fmt.Fprintf(out, "Table rows:         %d\n", res.TableRows)
+	fmt.Fprintf(out, "Same:               %d\n", res.Same)
+	fmt.Fprintf(out, "Only in file:       %d\n", len(res.OnlyInFile))
+	if res.TableScanned {
+		fmt.Fprintf(out, "Only in table:      %d\n", len(res.OnlyInTable))
+	} else {
+		fmt.Fprintf(out, "Only in table:      not checked (use --only-in-table or --where)\n")
+	}
+	fmt.Fprintf(out, "Changed:            %d\n", len(res.Changed))
+	if len(res.Unparseable) > 0 {
+		fmt.Fprintf(out, "Unparseable:        %d (not compared)\n", len(res.Unparseable))
+	}
+	if res.DuplicateKeys > 0 {
+		fmt.Fprintf(out, "Duplicate keys:     %d (the last row of each was compared)\n", res.DuplicateKeys)
+	}
+	fmt.Fprintf(out, "Report written to:  %s\n", reportPath)
+
+	if jsonOutput() {
+		return printJSON(diffReport{File: path, Table: tableName, Report: reportPath, diffResult: *res})
+	}
+	return nil
+}
+
+// uncomparedTypes are column types read back from the table in a form the
+// file's text can't be compared with.
+var uncomparedTypes = map[string]bool{"geography": true, "geometry": true, "hierarchyid": true}
+
+// diffLookupParams caps the parameters of one query looking up file rows in
+// the table, below SQL Server's limit of 2100.
+const diffLookupParams = 2000
+
+// diffTable compares the input file at path with schemaTable, or the rows of
+// it matching where. The file is mapped onto the table as import maps it and
+// read in batches; the table rows with each batch's keys are looked up and
+// compared with the file's. Only the keys are kept from one batch to the
+// next. With scanTable, or a where, the table's keys are then read too, to
+// find the rows the file lacks. Rows on either side whose values don't
+// convert are listed as unparseable instead. Progress is written to w.
+func diffTable(ctx context.Context, dest database.Destination, path, schemaTable, where string, scanTable bool, opts importOptions, w io.Writer) (*diffResult, error) {
+	plan, err := planImport(ctx, dest, path, schemaTable, opts, w)
+	if err != nil {
+		return nil, err
+	}
+	defer plan.reader.Close()
+	if len(plan.pkColumns) == 0 {
+		return nil, fmt.Errorf("%s has no primary key to match rows on", schemaTable)
+	}
+
+	// Compare the columns the file supplies
+	var compared []database.ColumnMapping
+	var skipped []string
+	for _, m := range plan.mapResult.Mapped {
+		switch {
+		case m.CSVIndex < 0:
+		case uncomparedTypes[strings.ToLower(m.DBColumn.DataType)]:
+			skipped = append(skipped, m.DBColumn.Name)
+		default:
+			compared = append(compared, m)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "Not compared (spatial or hierarchyid): %s\n", strings.Join(skipped, ", "))
+	}
+	d, err := newDiffer(compared, plan.pkColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	// Table rows are read as the columns compared, in the same order, and
+	// converted as the file's were.
+	columns := make([]string, len(compared))
+	tableMapping := make([]database.ColumnMapping, len(compared))
+	for i, m := range compared {
+		columns[i] = m.DBColumn.Name
+		tableMapping[i] = database.ColumnMapping{CSVIndex: i, DBColumn: m.DBColumn}
+	}
+	keyColumns := make([]string, len(d.keyIdx))
+	keyMapping := make([]database.ColumnMapping, len(d.keyIdx))
+	for i, idx := range d.keyIdx {
+		keyColumns[i] = columns[idx]
+		keyMapping[i] = database.ColumnMapping{CSVIndex: i, DBColumn: compared[idx].DBColumn}
+	}
+	scope := func(cond string) string {
+		if where == "" {
+			return cond
+		}
+		if cond == "" {
+			return where
+		}
+		return "(" + where + ") AND " + cond
+	}
+
+	// lookup compares the file rows read since the last call with the
+	// table's rows of the same keys.
+	lookup := func() error {
+		keys := d.pendingKeys()
+		if len(keys) == 0 {
+			return nil
+		}
+		var args []interface{}
+		for _, k := range keys {
+			args = append(args, k...)
+		}
+		query := dest.BuildSelect(schemaTable, columns, scope(database.KeyWhere(dest.Driver(), keyColumns, len(keys))), "")
+		stream, err := database.StreamQuery(ctx, dest.DB(), query, args...)
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+		for stream.Next() {
+			values, err := stream.Values()
+			if err != nil {
+				return err
+			}
+			row := append([]interface{}(nil), values...)
+			converted, errs := worker.ParseValues([][]interface{}{row}, tableMapping)
+			if errs[0] != nil {
+				d.addUnparseableTable(converted[0], errs[0])
+				continue
+			}
+			d.compareTable(converted[0])
+		}
+		if err := stream.Err(); err != nil {
+			return fmt.Errorf("reading %s: %w", schemaTable, err)
+		}
+		d.flush()
+		return nil
+	}
+
+	batchSize := diffLookupParams / len(keyColumns)
+	for {
+		batch, err := plan.reader.ReadRows(batchSize)
+		d.addFileBatch(batch)
+		if lerr := lookup(); lerr != nil {
+			return nil, lerr
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	fmt.Fprintf(w, "Compared %d rows from %s\n", d.result.FileRows, csvutil.DisplayName(path))
+
+	if !scanTable && where == "" {
+		return d.finish(), nil
+	}
+
+	// Read the table's keys to find the rows the file lacks
+	orderBy := make([]string, len(keyColumns))
+	for i, c := range keyColumns {
+		orderBy[i] = database.QuoteIdent(dest.Driver(), c)
+	}
+	stream, err := database.StreamQuery(ctx, dest.DB(), dest.BuildSelect(schemaTable, keyColumns, where, strings.Join(orderBy, ", ")))
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	for stream.Next() {
+		values, err := stream.Values()
+		if err != nil {
+			return nil, err
+		}
+		row := append([]interface{}(nil), values...)
+		converted, _ := worker.ParseValues([][]interface{}{row}, keyMapping)
+		d.scanTable(converted[0])
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", schemaTable, err)
+	}
+	d.result.TableScanned = true
+	return d.finish(), nil
+}
+
+// differ matches file rows to table rows on their primary key and records
+// how they differ. Rows are given as converted for columns. File rows are
+// held only until their table rows have been looked up (see flush); after
+// that just their key and outcome are kept.
+type differ struct {
+	columns []database.ColumnMapping
+	keyIdx  []int
+	// pending holds the file rows not yet looked up, by key, in file order.
+	pending map[string][]interface{}
+	order   []string
+	keys    []string // every file key, in file order
+	outcome map[string]*rowOutcome
+	result  diffResult
+}
+
+// rowOutcome is how the file row with a key compared with the table.
+type rowOutcome struct {
+	found       bool // the table has the key
+	unparseable bool // the table's row didn't convert
+	changes     []columnChange
+}
+
+// newDiffer returns a differ for rows of columns, keyed on pkColumns, which
+// must all be among them.
+func newDiffer(columns []database.ColumnMapping, pkColumns []string) (*differ, error) {
+	d := &differ{
+		columns: columns,
+		pending: map[string][]interface{}{},
+		outcome: map[string]*rowOutcome{},
+		result: diffResult{OnlyInFile: []string{}, OnlyInTable: []string{}, Changed: []changedRow{},
+			Unparseable: []unparseableRow{}},
+	}
+	for _, pk := range pkColumns {
+		idx := -1
+		for i, m := range columns {
+			if strings.EqualFold(m.DBColumn.Name, pk) {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("primary key column %s has no column in the file", pk)
+		}
+		d.keyIdx = append(d.keyIdx, idx)
+	}
+	return d, nil
+}
+
+// key renders the primary key of row, e.g. "ORDER_ID=7, LINE=2".
+func (d *differ) key(row []interface{}) string {
+	keyRow := make([]interface{}, len(d.keyIdx))
+	for i, idx := range d.keyIdx {
+		keyRow[i] = row[idx]
+	}
+	return d.keyOf(keyRow)
+}
+
+// keyOf renders a row of just the primary key's values as key does.
+func (d *differ) keyOf(keyRow []interface{}) string {
+	parts := make([]string, len(d.keyIdx))
+	for i, idx := range d.keyIdx {
+		col := d.columns[idx].DBColumn
+		parts[i] = col.Name + "=" + worker.FormatValue(keyRow[i], col.DataType)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// addFileBatch converts a batch read from the file and records its rows.
+func (d *differ) addFileBatch(batch csvutil.Batch) {
+	var rows [][]interface{}
+	var errs []error
+	if batch.Values != nil {
+		rows, errs = worker.ParseValues(batch.Values, d.columns)
+	} else {
+		rows, errs = worker.ParseBatch(batch.Text, d.columns)
+	}
+	for i, row := range rows {
+		if errs[i] != nil {
+			d.addUnparseable(unparseableRow{Source: "file", Line: batch.Lines[i], Error: errs[i].Error()})
+			continue
+		}
+		d.addFile(row)
+	}
+}
+
+// addFile records a row of the file. A later row with the same key replaces
+// it, even once it has been compared.
+func (d *differ) addFile(row []interface{}) {
+	d.result.FileRows++
+	k := d.key(row)
+	if _, dup := d.outcome[k]; dup {
+		d.result.DuplicateKeys++
+	} else {
+		d.keys = append(d.keys, k)
+	}
+	d.outcome[k] = &rowOutcome{}
+	if _, ok := d.pending[k]; !ok {
+		d.order = append(d.order, k)
+	}
+	d.pending[k] = row
+}
+
+// addUnparseable records a row that couldn't be converted.
+func (d *differ) addUnparseable(r unparseableRow) {
+	if r.Source == "file" {
+		d.result.FileRows++
+	}
+	d.result.Unparseable = append(d.result.Unparseable, r)
+}
+
+// addUnparseableTable records a looked-up table row that couldn't be
+// converted; the file's row of its key is then not compared either.
+func (d *differ) addUnparseableTable(row []interface{}, err error) {
+	k := d.key(row)
+	if o, ok := d.outcome[k]; ok {
+		o.found, o.unparseable = true, true
+	}
+	delete(d.pending, k)
+	d.addUnparseable(unparseableRow{Source: "table", Key: k, Error: err.Error()})
+}
+
+// pendingKeys returns the primary key values of the file rows not yet
+// looked up, in file order.
+func (d *differ) pendingKeys() [][]interface{} {
+	keys := make([][]interface{}, 0, len(d.order))
+	for _, k := range d.order {
+		row := d.pending[k]
+		keyRow := make([]interface{}, len(d.keyIdx))
+		for i, idx := range d.keyIdx {
+			keyRow[i] = row[idx]
+		}
+		keys = append(keys, keyRow)
+	}
+	return keys
+}
+
+// compareTable compares a looked-up row of the table with the file's row of
+// the same key.
+func (d *differ) compareTable(row []interface{}) {
+	k := d.key(row)
+	fileRow, ok := d.pending[k]
+	if !ok {
+		// Matched by the database but not by its text, e.g. a key
+		// differing only in case; the file's row is reported missing.
+		return
+	}
+	delete(d.pending, k)
+
+	o := d.outcome[k]
+	o.found = true
+	for i, m := range d.columns {
+		if !worker.SameValue(row[i], fileRow[i], m.DBColumn) {
+			o.changes = append(o.changes, columnChange{
+				Column: m.DBColumn.Name,
+				Table:  diffValue(row[i], m.DBColumn.DataType),
+				File:   diffValue(fileRow[i], m.DBColumn.DataType),
+			})
+		}
+	}
+}
+
+// flush ends a lookup: the file rows it found no table row for are missing
+// from the table.
+func (d *differ) flush() {
+	clear(d.pending)
+	d.order = d.order[:0]
+}
+
+// scanTable records a row of the table's primary key values, read after
+// every file row was looked up; it is only in the table unless the file
+// has its key.
+func (d *differ) scanTable(keyRow []interface{}) {
+	k := d.keyOf(keyRow)
+	if _, ok := d.outcome[k]; ok {
+		return
+	}
+	d.result.TableRows++
+	d.result.OnlyInTable = append(d.result.OnlyInTable, k)
+}
+
+// finish returns the differences.
+func (d *differ) finish() *diffResult {
+	for _, k := range d.keys {
+		o := d.outcome[k]
+		if o.found {
+			d.result.TableRows++
+		}
+		switch {
+		case o.unparseable:
+		case !o.found:
+			d.result.OnlyInFile = append(d.result.OnlyInFile, k)
+		case len(o.changes) == 0:
+			d.result.Same++
+		default:
+			d.result.Changed = append(d.result.Changed, changedRow{Key: k, Columns: o.changes})
+		}
+	}
+	return &d.result
+}
+
+// diffValue renders a converted value for the report; NULL is nil.
+func diffValue(v interface{}, dataType string) *string {
+	if v == nil {
+		return nil
+	}
+	s := worker.FormatValue(v, dataType)
+	return &s
+}
+
+// writeDiffReport writes res to path in format.
+func writeDiffReport(path, format, file, table string, res *diffResult) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating diff report: %w", err)
+	}
+	defer f.Close()
+
+	switch format {
+	case diffCSV:
+		err = writeDiffCSV(f, res)
+	case diffMarkdown:
+		err = writeDiffMarkdown(f, file, table, res)
+	default:
+		err = writeDiffText(f, file, table, res)
+	}
+	if err != nil {
+		return fmt.Errorf("writing diff report: %w", err)
+	}
+	return f.Close()
+}
+
+// writeDiffCSV writes one record per missing row, per changed value and
+// per unparseable row: change, key, column, table_value, file_value, line,
+// error. NULL is an empty field.
+func writeDiffCSV(w io.Writer, res *diffResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"change", "key", "column", "table_value", "file_value", "line", "error"}) //nolint:errcheck
+	for _, k := range res.OnlyInFile {
+		cw.Write([]string{"only_in_file", k, "", "", "", "", ""}) //nolint:errcheck
+	}
+	for _, k := range res.OnlyInTable {
+		cw.Write([]string{"only_in_table", k, "", "", "", "", ""}) //nolint:errcheck
+	}
+	for _, r := range res.Changed {
+		for _, c := range r.Columns {
+			cw.Write([]string{"changed", r.Key, c.Column, derefOr(c.Table, ""), derefOr(c.File, ""), "", ""}) //nolint:errcheck
+		}
+	}
+	for _, r := range res.Unparseable {
+		cw.Write([]string{"unparseable_in_" + r.Source, r.Key, "", "", "", lineOrEmpty(r.Line), r.Error}) //nolint:errcheck
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeDiffText(w io.Writer, file, table string, res *diffResult) error {
+	fmt.Fprintf(w, "Diff of %s against %s\n", file, table)
+	fmt.Fprintf(w, "Generated: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "File rows: %d, table rows: %d, same: %d\n", res.FileRows, res.TableRows, res.Same)
+
+	fmt.Fprintf(w, "\nOnly in file (%d):\n", len(res.OnlyInFile))
+	for _, k := range res.OnlyInFile {
+		fmt.Fprintf(w, "  %s\n", k)
+	}
+	if res.TableScanned {
+		fmt.Fprintf(w, "\nOnly in table (%d):\n", len(res.OnlyInTable))
+		for _, k := range res.OnlyInTable {
+			fmt.Fprintf(w, "  %s\n", k)
+		}
+	} else {
+		fmt.Fprintf(w, "\nOnly in table: not checked\n")
+	}
+	fmt.Fprintf(w, "\nChanged (%d):\n", len(res.Changed))
+	for _, r := range res.Changed {
+		fmt.Fprintf(w, "  %s\n", r.Key)
+		for _, c := range r.Columns {
+			fmt.Fprintf(w, "    %s: %s → %s\n", c.Column, quotedOrNull(c.Table), quotedOrNull(c.File))
+		}
+	}
+	if len(res.Unparseable) > 0 {
+		fmt.Fprintf(w, "\nUnparseable, not compared (%d):\n", len(res.Unparseable))
+		for _, r := range res.Unparseable {
+			fmt.Fprintf(w, "  %s: %s\n", unparseableWhere(r), r.Error)
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func writeDiffMarkdown(w io.Writer, file, table string, res *diffResult) error {
+	fmt.Fprintf(w, "# Diff Report\n\n")
+	fmt.Fprintf(w, "**File:** `%s`\n", file)
+	fmt.Fprintf(w, "**Table:** `%s`\n", table)
+	fmt.Fprintf(w, "**Generated:** %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	fmt.Fprintf(w, "| File rows | Table rows | Same | Only in file | Only in table | Changed | Unparseable |\n")
+	fmt.Fprintf(w, "|----------:|-----------:|-----:|-------------:|--------------:|--------:|------------:|\n")
+	onlyInTable := "not checked"
+	if res.TableScanned {
+		onlyInTable = strconv.Itoa(len(res.OnlyInTable))
+	}
+	fmt.Fprintf(w, "| %d | %d | %d | %d | %s | %d | %d |\n\n",
+		res.FileRows, res.TableRows, res.Same, len(res.OnlyInFile), onlyInTable, len(res.Changed),
+		len(res.Unparseable))
+
+	for _, section := range []struct {
+		title string
+		keys  []string
+	}{{"Only in File", res.OnlyInFile}, {"Only in Table", res.OnlyInTable}} {
+		if len(section.keys) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "## %s\n\n", section.title)
+		for _, k := range section.keys {
+			fmt.Fprintf(w, "- `%s`\n", k)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(res.Changed) > 0 {
+		fmt.Fprintf(w, "## Changed\n\n")
+		fmt.Fprintf(w, "| Key | Column | Table | File |\n")
+		fmt.Fprintf(w, "|-----|--------|-------|------|\n")
+		for _, r := range res.Changed {
+			for _, c := range r.Columns {
+				fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", r.Key, c.Column, markdownCell(c.Table), markdownCell(c.File))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(res.Unparseable) > 0 {
+		fmt.Fprintf(w, "## Unparseable\n\n")
+		fmt.Fprintf(w, "| Row | Error |\n")
+		fmt.Fprintf(w, "|-----|-------|\n")
+		for _, r := range res.Unparseable {
+			msg := r.Error
+			fmt.Fprintf(w, "| %s | %s |\n", unparseableWhere(r), markdownCell(&msg))
+		}
+	}
+	return nil
+}
+
+// unparseableWhere names an unparseable row: "file line 12" or
+// "table row ID=7".
+func unparseableWhere(r unparseableRow) string {
+	if r.Source == "file" {
+		return fmt.Sprintf("file line %d", r.Line)
+	}
+	return "table row " + r.Key
+}
+
+func lineOrEmpty(line int) string {
+	if line == 0 {
+		return ""
+	}
+	return strconv.Itoa(line)
+}
+
+func derefOr(s *string, null string) string {
+	if s == nil {
+		return null
+	}
+	return *s
+}
+
+func quotedOrNull(s *string) string {
+	if s == nil {
+		return "NULL"
+	}
+	return strconv.Quote(*s)
+}
+
+// markdownCell renders a value for a markdown table cell.
+func markdownCell(s *string) string {
+	if s == nil {
+		return "*NULL*"
+	}
+	v := strings.NewReplacer("|", `\|`, "\n", " ", "\r", "").Replace(*s)
+	return "`" + v + "`"
+}
@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+func TestDiffer(t *testing.T) {
+	columns := []database.ColumnMapping{
+		{CSVIndex: 0, DBColumn: database.TableColumn{Name: "ID", DataType: "int"}},
+		{CSVIndex: 1, DBColumn: database.TableColumn{Name: "STATUS", DataType: "nvarchar", IsNullable: true}},
+	}
+	d, err := newDiffer(columns, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.addFile([]interface{}{int64(1), "open"})
+	d.addFile([]interface{}{int64(2), "closed"})
+	if got := d.pendingKeys(); !reflect.DeepEqual(got, [][]interface{}{{int64(1)}, {int64(2)}}) {
+		t.Fatalf("pending keys = %v, want 1 and 2", got)
+	}
+	d.compareTable([]interface{}{int64(1), "open"})
+	d.compareTable([]interface{}{int64(2), nil})
+	d.flush()
+	d.addFile([]interface{}{int64(4), "new"})
+	d.flush()
+	for _, id := range []int64{1, 2, 3} {
+		d.scanTable([]interface{}{id})
+	}
+	res := d.finish()
+
+	if res.Same != 1 || !reflect.DeepEqual(res.OnlyInFile, []string{"ID=4"}) || !reflect.DeepEqual(res.OnlyInTable, []string{"ID=3"}) {
+		t.Fatalf("unexpected diff: %+v", res)
+	}
+	if res.TableRows != 3 {
+		t.Errorf("table rows = %d, want 3", res.TableRows)
+	}
+	if len(res.Changed) != 1 || res.Changed[0].Key != "ID=2" {
+		t.Fatalf("changed = %+v, want ID=2", res.Changed)
+	}
+	c := res.Changed[0].Columns
+	if len(c) != 1 || c[0].Column != "STATUS" || c[0].Table != nil || *c[0].File != "closed" {
+		t.Fatalf("ID=2 changes = %+v, want STATUS NULL → closed", c)
+	}
+
+	var buf bytes.Buffer
+	if err := writeDiffCSV(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	want := "change,key,column,table_value,file_value,line,error\n" +
+		"only_in_file,ID=4,,,,,\nonly_in_table,ID=3,,,,,\nchanged,ID=2,STATUS,,closed,,\n"
+	if buf.String() != want {
+		t.Errorf("CSV report =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if _, err := newDiffer(columns[1:], []string{"ID"}); err == nil || !strings.Contains(err.Error(), "ID") {
+		t.Errorf("key column missing from the file: got %v", err)
+	}
+}
+
+func TestDifferUnparseable(t *testing.T) {
+	columns := []database.ColumnMapping{
+		{CSVIndex: 0, DBColumn: database.TableColumn{Name: "ID", DataType: "int"}},
+		{CSVIndex: 1, DBColumn: database.TableColumn{Name: "DUE", DataType: "date", IsNullable: true}},
+	}
+	d, err := newDiffer(columns, []string{"ID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.addFileBatch(csvutil.Batch{
+		Text:  [][]string{{"1", "2026-02-11"}, {"2", "next week"}, {"x", ""}},
+		Lines: []int{2, 3, 4},
+	})
+	d.compareTable([]interface{}{int64(1), time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC)})
+	res := d.finish()
+
+	if res.FileRows != 3 || res.Same != 1 || len(res.OnlyInFile) != 0 || len(res.Changed) != 0 {
+		t.Fatalf("unexpected diff: %+v", res)
+	}
+	if len(res.Unparseable) != 2 {
+		t.Fatalf("unparseable = %+v, want lines 3 and 4", res.Unparseable)
+	}
+	for i, line := range []int{3, 4} {
+		r := res.Unparseable[i]
+		if r.Source != "file" || r.Line != line || r.Error == "" {
+			t.Errorf("unparseable[%d] = %+v, want file line %d with an error", i, r, line)
+		}
+	}
+	if !strings.Contains(res.Unparseable[0].Error, "DUE") {
+		t.Errorf("error %q doesn't name the column", res.Unparseable[0].Error)
+	}
+}
+
+func TestDifferDuplicateKeyAcrossLookups(t *testing.T) {
+	columns := []database.ColumnMapping{
+		{CSVIndex: 0, DBColumn: database.TableColumn{Name: "ID", DataType: "int"}},
+		{CSVIndex: 1, DBColumn: database.TableColumn{Name: "STATUS", DataType: "nvarchar", IsNullable: true}},
+	}
+	d, err := newDiffer(columns, []string{"ID"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.addFile([]interface{}{int64(1), "open"})
+	d.compareTable([]interface{}{int64(1), "closed"})
+	d.flush()
+	// The last row with a key is the one compared.
+	d.addFile([]interface{}{int64(1), "closed"})
+	d.compareTable([]interface{}{int64(1), "closed"})
+	d.flush()
+	res := d.finish()
+
+	if res.FileRows != 2 || res.TableRows != 1 || res.DuplicateKeys != 1 || res.Same != 1 || len(res.Changed) != 0 || len(res.OnlyInFile) != 0 {
+		t.Fatalf("unexpected diff: %+v", res)
+	}
+}
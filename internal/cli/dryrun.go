@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+	"github.com/walkerscm/scaleSyncGo/internal/worker"
+)
+
+// validationResult is the machine-readable outcome of a dry run of one file.
+type validationResult struct {
+	File   string         `json:"file"`
+	Table  string         `json:"table"`
+	Rows   int            `json:"rows_checked"`
+	Issues []worker.Issue `json:"issues"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// validateFile is the --dry-run counterpart of importFile: it maps and
+// converts every row of the CSV exactly as an import would, but writes
+// nothing. Problems are reported per column on w and returned in the result;
+// an error is returned if any row would fail to load.
+func validateFile(ctx context.Context, dest database.Destination, csvPath, schemaTable string, opts importOptions, w io.Writer) (validationResult, error) {
+	res := validationResult{File: csvPath, Table: schemaTable, Issues: []worker.Issue{}}
+	opts.DryRun = true
+	fail := func(err error) (validationResult, error) {
+		res.Error = err.Error()
+		return res, err
+	}
+
+	plan, err := planImport(ctx, dest, csvPath, schemaTable, opts, w)
+	if err != nil {
+		return fail(err)
+	}
+	defer plan.reader.Close()
+
+	v := worker.NewValidator(plan.mapResult.Mapped, plan.pkColumns)
+	for {
+		batch, err := plan.reader.ReadRows(opts.BatchSize)
+		if batch.Values != nil {
+			v.CheckValues(batch.Values, batch.Lines)
+		} else {
+			v.Check(batch.Text, batch.Lines)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	issues := v.Issues()
+	res.Rows = v.Rows()
+	res.Issues = issues
+
+	fmt.Fprintf(w, "\n--- Dry Run Summary ---\n")
+	fmt.Fprintf(w, "File:         %s\n", csvutil.DisplayName(csvPath))
+	fmt.Fprintf(w, "Table:        %s\n", schemaTable)
+	fmt.Fprintf(w, "Rows checked: %d\n", v.Rows())
+
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No problems found.")
+		return res, nil
+	}
+
+	fmt.Fprintf(w, "Problems:     %d\n\n", len(issues))
+	for _, is := range issues {
+		fmt.Fprintf(w, "  - %s\n", is)
+	}
+	return fail(fmt.Errorf("dry run found %d problem(s) in %s", len(issues), csvutil.DisplayName(csvPath)))
+}
@@ -0,0 +1,269 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/spf13/cobra"
+	"github.com/walkerscm/scaleSyncGo/internal/checkpoint"
+	"github.com/walkerscm/scaleSyncGo/internal/config"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+	"github.com/walkerscm/scaleSyncGo/internal/worker"
+)
+
+var exportCmd = &cobra.Command{
+	Use:     "export",
+	Aliases: []string{"extract"},
+	Short:   "Export a table or query to a CSV file",
+	Long: `Stream rows from a table or an arbitrary query into a CSV file named
+<TABLE>_inserts_<timestamp>.csv, ready to be loaded again with import or process.
+
+With --incremental, export each table listed under extracts in config.yaml,
+writing only the rows whose watermark column has grown since the last run.`,
+	RunE: runExport,
+}
+
+func init() {
+	exportCmd.Flags().String("env", ".env", "path to .env file")
+	exportCmd.Flags().String("table", "", "table to export as schema.name")
+	exportCmd.Flags().String("query", "", "SQL query to export instead of a table")
+	exportCmd.Flags().String("where", "", "filter for --table (SQL, without WHERE)")
+	exportCmd.Flags().String("order-by", "", "ordering for --table (SQL, without ORDER BY)")
+	exportCmd.Flags().String("name", "", "table name used in the output filename (default: --table's name, or \"query\")")
+	exportCmd.Flags().String("out-dir", ".", "directory to write the CSV to")
+	exportCmd.Flags().String("file", "", "explicit output path (overrides --out-dir and --name)")
+	exportCmd.Flags().Bool("incremental", false, "export the new and changed rows of each table under extracts in config.yaml (or just --table)")
+	exportCmd.Flags().String("watermark-file", "watermarks.json", "with --incremental, where the watermark of each table is kept")
+	exportCmd.MarkFlagsMutuallyExclusive("table", "query")
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) error {
+	envPath, _ := cmd.Flags().GetString("env")
+	target, _ := cmd.Flags().GetString("target")
+	tableName, _ := cmd.Flags().GetString("table")
+	query, _ := cmd.Flags().GetString("query")
+	where, _ := cmd.Flags().GetString("where")
+	orderBy, _ := cmd.Flags().GetString("order-by")
+	name, _ := cmd.Flags().GetString("name")
+	outDir, _ := cmd.Flags().GetString("out-dir")
+	outPath, _ := cmd.Flags().GetString("file")
+	incremental, _ := cmd.Flags().GetBool("incremental")
+	watermarkPath, _ := cmd.Flags().GetString("watermark-file")
+
+	if incremental {
+		if query != "" || where != "" || orderBy != "" || name != "" || outPath != "" {
+			return fmt.Errorf("--incremental exports whole tables as configured in config.yaml; --query, --where, --order-by, --name and --file don't apply")
+		}
+	} else if tableName == "" && query == "" {
+		return fmt.Errorf("one of --table or --query is required")
+	}
+	if query != "" && (where != "" || orderBy != "") {
+		return fmt.Errorf("--where and --order-by only apply to --table; put them in --query instead")
+	}
+	out := humanOut()
+
+	dbCfg, err := config.LoadDatabaseConfig(envPath, target)
+	if err != nil {
+		return fmt.Errorf("loading database config: %w", err)
+	}
+
+	fmt.Fprintf(out, "Connecting to %s/%s...\n", dbCfg.Server, dbCfg.Database)
+	dest, err := database.Open(dbCfg.Driver, dbCfg.ConnectionString(), logger)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer dest.Close()
+	fmt.Fprintln(out, "Connected.")
+
+	ctx := context.Background()
+	if incremental {
+		return exportIncremental(ctx, dest, tableName, outDir, watermarkPath, out)
+	}
+
+	// Build the query; for a table the header comes from its column list.
+	if tableName != "" {
+		tableCols, err := dest.GetTableColumns(ctx, tableName)
+		if err != nil {
+			return fmt.Errorf("getting table columns: %w", err)
+		}
+		if len(tableCols) == 0 {
+			return fmt.Errorf("table %s not found or has no columns", tableName)
+		}
+		columns := make([]string, len(tableCols))
+		for i, c := range tableCols {
+			columns[i] = c.Name
+		}
+		query = dest.BuildSelect(tableName, columns, where, orderBy)
+
+		if name == "" {
+			name = exportTableName(tableName, dest.DefaultSchema())
+		}
+	}
+	if name == "" {
+		name = "query"
+	}
+
+	if outPath == "" {
+		outPath = filepath.Join(outDir, exportFileName(name, time.Now()))
+	}
+
+	start := time.Now()
+	rows, err := exportQuery(ctx, dest.DB(), exportJob{Query: query, Path: outPath}, out)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	logger.Info("export finished", "file", outPath, "rows", rows, "duration", elapsed)
+
+	fmt.Fprintf(out, "\n--- Export Summary ---\n")
+	fmt.Fprintf(out, "File:          %s\n", outPath)
+	fmt.Fprintf(out, "Rows exported: %d\n", rows)
+	fmt.Fprintf(out, "Duration:      %s\n", elapsed.Round(time.Millisecond))
+
+	if jsonOutput() {
+		return printJSON(exportReport{File: outPath, Rows: rows, DurationMS: elapsed.Milliseconds()})
+	}
+	return nil
+}
+
+// exportReport is the machine-readable outcome of an export.
+type exportReport struct {
+	File       string `json:"file"`
+	Rows       int    `json:"rows"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// exportTableName returns the table part of an export file name for
+// schemaTable: its bare name in the default schema, else schema.table, which
+// process resolves back to the same schema.
+func exportTableName(schemaTable, defaultSchema string) string {
+	if schema, table, ok := strings.Cut(schemaTable, "."); ok && strings.EqualFold(schema, defaultSchema) {
+		return table
+	}
+	return schemaTable
+}
+
+// exportFileName returns the <TABLE>_inserts_<timestamp>.csv name that
+// process's default match rule recognises.
+func exportFileName(table string, ts time.Time) string {
+	return fmt.Sprintf("%s_inserts_%s.csv", table, ts.Format("20060102_150405"))
+}
+
+// exportJob is a query for exportQuery to write to a CSV.
+type exportJob struct {
+	Query string
+	Args  []interface{}
+	Path  string
+	// SkipEmpty leaves no file behind when the query returns no rows.
+	SkipEmpty bool
+	// Observe, when set, is called with each row written. The slice is
+	// reused for the next row.
+	Observe func(row []interface{})
+}
+
+// exportQuery streams the result of job.Query into a CSV at job.Path and
+// returns the number of rows written. Rows are written to a temporary file
+// that is only synced to disk and renamed into place once complete, so a
+// half-written export is never picked up by process. Progress is written to
+// w.
+func exportQuery(ctx context.Context, db *sql.DB, job exportJob, w io.Writer) (int, error) {
+	path := job.Path
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return 0, fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, fmt.Errorf("creating export file: %w", err)
+	}
+	defer os.Remove(tmpPath) //nolint:errcheck // no-op once renamed
+	defer f.Close()
+
+	stream, err := database.StreamQuery(ctx, db, job.Query, job.Args...)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+
+	cols := stream.Columns()
+	bw := bufio.NewWriterSize(f, 256*1024)
+	cw := csv.NewWriter(bw)
+
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return 0, fmt.Errorf("writing header: %w", err)
+	}
+
+	bar := progressbar.NewOptions(-1,
+		progressbar.OptionSetDescription("Exporting"),
+		progressbar.OptionSetWriter(w),
+		progressbar.OptionShowCount(),
+		progressbar.OptionThrottle(200*time.Millisecond),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetItsString("rows"),
+	)
+
+	count := 0
+	record := make([]string, len(cols))
+	for stream.Next() {
+		values, err := stream.Values()
+		if err != nil {
+			return count, err
+		}
+		for i, v := range values {
+			record[i] = worker.FormatValue(v, cols[i].DataType)
+		}
+		if err := cw.Write(record); err != nil {
+			return count, fmt.Errorf("writing row %d: %w", count+1, err)
+		}
+		if job.Observe != nil {
+			job.Observe(values)
+		}
+		count++
+		bar.Add(1) //nolint:errcheck
+	}
+	if err := stream.Err(); err != nil {
+		return count, fmt.Errorf("reading rows: %w", err)
+	}
+	bar.Finish() //nolint:errcheck
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return count, fmt.Errorf("writing export: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return count, fmt.Errorf("writing export: %w", err)
+	}
+	if count == 0 && job.SkipEmpty {
+		return 0, nil
+	}
+	if err := f.Sync(); err != nil {
+		return count, fmt.Errorf("syncing export file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return count, fmt.Errorf("closing export file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return count, fmt.Errorf("renaming export file: %w", err)
+	}
+	if err := checkpoint.SyncDir(filepath.Dir(path)); err != nil {
+		return count, fmt.Errorf("syncing export file: %w", err)
+	}
+	return count, nil
+}
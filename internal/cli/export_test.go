@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/walkerscm/scaleSyncGo/internal/filematch"
+)
+
+func TestExportFileNameRoundTrip(t *testing.T) {
+	rules, err := filematch.Compile(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2026, 2, 11, 17, 2, 55, 0, time.UTC)
+	cases := []struct {
+		table, want string
+	}{
+		{"dbo.ORDERS", "dbo.ORDERS"},
+		{"DBO.ORDERS", "dbo.ORDERS"},
+		{"ORDERS", "dbo.ORDERS"},
+		{"sales.Orders", "sales.Orders"},
+	}
+	for _, c := range cases {
+		name := exportFileName(exportTableName(c.table, "dbo"), ts)
+		m, ok := rules.Match(name)
+		if !ok {
+			t.Errorf("%s: %s not matched", c.table, name)
+			continue
+		}
+		if got := m.QualifiedName("dbo"); got != c.want {
+			t.Errorf("%s: %s resolves to %s, want %s", c.table, name, got, c.want)
+		}
+	}
+}
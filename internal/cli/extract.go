@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/walkerscm/scaleSyncGo/internal/checkpoint"
+	"github.com/walkerscm/scaleSyncGo/internal/config"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+// Kinds of watermark column.
+const (
+	watermarkRowversion = "rowversion"
+	watermarkTime       = "time"
+	watermarkInt        = "int"
+)
+
+// extractResult is the outcome of one table of export --incremental.
+type extractResult struct {
+	Table     string `json:"table"`
+	Watermark string `json:"watermark_column"`
+	// From and To are the watermark before and after the export; To is
+	// empty when there were no new rows.
+	From       string `json:"from_watermark,omitempty"`
+	To         string `json:"to_watermark,omitempty"`
+	File       string `json:"file,omitempty"`
+	Rows       int    `json:"rows"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// extractReport is the machine-readable outcome of export --incremental.
+type extractReport struct {
+	Tables    []extractResult `json:"tables"`
+	Succeeded int             `json:"succeeded"`
+	Failed    int             `json:"failed"`
+	TotalRows int             `json:"total_rows"`
+}
+
+// exportIncremental exports the new and changed rows of each table under
+// extracts in config.yaml, or only of table when it is set, into outDir.
+// Each table's watermark is kept in the file at watermarkPath and only
+// advanced once its CSV is safely on disk, so a failed run exports the same
+// rows again rather than losing them.
+func exportIncremental(ctx context.Context, dest database.Destination, table, outDir, watermarkPath string, out io.Writer) error {
+	var extracts []config.Extract
+	if cfg != nil {
+		extracts = cfg.Extracts
+	}
+	for _, ex := range extracts {
+		if ex.Table == "" || ex.Watermark == "" {
+			return fmt.Errorf("extracts: every entry needs a table and a watermark column")
+		}
+	}
+	if table != "" {
+		var only []config.Extract
+		for _, ex := range extracts {
+			if strings.EqualFold(ex.Table, table) {
+				only = append(only, ex)
+			}
+		}
+		if len(only) == 0 {
+			return fmt.Errorf("%s is not listed under extracts in config.yaml", table)
+		}
+		extracts = only
+	}
+	if len(extracts) == 0 {
+		return fmt.Errorf("no extracts configured in config.yaml")
+	}
+
+	marks, err := checkpoint.LoadWatermarks(watermarkPath)
+	if err != nil {
+		return err
+	}
+
+	report := extractReport{Tables: []extractResult{}}
+	for i, ex := range extracts {
+		fmt.Fprintf(out, "\n[%d/%d] Extracting %s (watermark %s)...\n", i+1, len(extracts), ex.Table, ex.Watermark)
+		start := time.Now()
+		res, err := extractTable(ctx, dest, ex, marks, outDir, out)
+		res.DurationMS = time.Since(start).Milliseconds()
+		if err != nil {
+			res.Error = err.Error()
+			report.Failed++
+			fmt.Fprintf(out, "ERROR: %s: %v\n", ex.Table, err)
+			logger.Error("extract failed", "table", ex.Table, "err", err)
+		} else {
+			report.Succeeded++
+		}
+		report.TotalRows += res.Rows
+		report.Tables = append(report.Tables, res)
+	}
+
+	fmt.Fprintln(out, "\n=== Extract Summary ===")
+	fmt.Fprintf(out, "Tables:      %d\n", len(extracts))
+	fmt.Fprintf(out, "Succeeded:   %d\n", report.Succeeded)
+	fmt.Fprintf(out, "Failed:      %d\n", report.Failed)
+	fmt.Fprintf(out, "Total rows:  %d\n", report.TotalRows)
+
+	if jsonOutput() {
+		if err := printJSON(report); err != nil {
+			return err
+		}
+	}
+	switch {
+	case report.Failed == len(extracts):
+		return fmt.Errorf("all %d table(s) failed", report.Failed)
+	case report.Failed > 0:
+		return partialFailure(fmt.Errorf("%d of %d table(s) failed", report.Failed, len(extracts)))
+	}
+	return nil
+}
+
+// extractTable exports the rows of ex.Table above its recorded watermark,
+// in watermark order, to a new <TABLE>_inserts_<timestamp>.csv in outDir,
+// then records the highest watermark written. No file is written when there
+// are no new rows.
+func extractTable(ctx context.Context, dest database.Destination, ex config.Extract, marks *checkpoint.Watermarks, outDir string, w io.Writer) (extractResult, error) {
+	res := extractResult{Table: ex.Table, Watermark: ex.Watermark}
+
+	tableCols, err := dest.GetTableColumns(ctx, ex.Table)
+	if err != nil {
+		return res, fmt.Errorf("getting table columns: %w", err)
+	}
+	if len(tableCols) == 0 {
+		return res, fmt.Errorf("table %s not found or has no columns", ex.Table)
+	}
+	columns := make([]string, len(tableCols))
+	markIdx := -1
+	for i, c := range tableCols {
+		columns[i] = c.Name
+		if strings.EqualFold(c.Name, ex.Watermark) {
+			markIdx = i
+		}
+	}
+	if markIdx < 0 {
+		return res, fmt.Errorf("watermark column %s not found in %s", ex.Watermark, ex.Table)
+	}
+	markCol := tableCols[markIdx]
+	res.Watermark = markCol.Name
+	kind, err := watermarkKind(dest.Driver(), markCol.DataType)
+	if err != nil {
+		return res, fmt.Errorf("watermark column %s: %w", markCol.Name, err)
+	}
+
+	// Resume from the recorded watermark, if any
+	mark, since := marks.Get(ex.Table)
+	var args []interface{}
+	if since {
+		if !strings.EqualFold(mark.Column, markCol.Name) {
+			return res, fmt.Errorf("the watermark in %s was recorded on column %s, not %s; remove it to start over",
+				marks.Path(), mark.Column, markCol.Name)
+		}
+		v, err := decodeWatermark(kind, mark.Value)
+		if err != nil {
+			return res, fmt.Errorf("watermark in %s: %w", marks.Path(), err)
+		}
+		args = append(args, v)
+		res.From = mark.Value
+		fmt.Fprintf(w, "Exporting rows with %s > %s\n", markCol.Name, mark.Value)
+	} else {
+		fmt.Fprintln(w, "No watermark recorded — exporting every row")
+	}
+
+	where := database.WatermarkWhere(dest.Driver(), markCol.Name, kind == watermarkRowversion, since)
+	if ex.Where != "" {
+		if where != "" {
+			where += " AND "
+		}
+		where += "(" + ex.Where + ")"
+	}
+	query := dest.BuildSelect(ex.Table, columns, where, database.QuoteIdent(dest.Driver(), markCol.Name))
+
+	// Rows come in watermark order, so the last non-NULL one is the highest.
+	var high interface{}
+	path := filepath.Join(outDir, exportFileName(exportTableName(ex.Table, dest.DefaultSchema()), time.Now()))
+	rows, err := exportQuery(ctx, dest.DB(), exportJob{
+		Query:     query,
+		Args:      args,
+		Path:      path,
+		SkipEmpty: true,
+		Observe: func(row []interface{}) {
+			if v := row[markIdx]; v != nil {
+				high = v
+			}
+		},
+	}, w)
+	res.Rows = rows
+	if err != nil {
+		return res, err
+	}
+	if rows == 0 {
+		fmt.Fprintln(w, "No new rows.")
+		logger.Info("extract finished", "table", ex.Table, "rows", 0)
+		return res, nil
+	}
+	res.File = path
+	fmt.Fprintf(w, "Wrote %d row(s) to %s\n", rows, path)
+
+	if high == nil {
+		// Every row had a NULL watermark; there is nothing to advance to.
+		return res, nil
+	}
+	value, err := encodeWatermark(kind, high)
+	if err != nil {
+		return res, fmt.Errorf("watermark column %s: %w", markCol.Name, err)
+	}
+	if err := marks.Set(ex.Table, checkpoint.Watermark{Column: markCol.Name, Value: value, File: path}); err != nil {
+		return res, fmt.Errorf("recording watermark (%s was written and will be exported again): %w", path, err)
+	}
+	res.To = value
+	fmt.Fprintf(w, "Watermark:   %s\n", value)
+	logger.Info("extract finished", "table", ex.Table, "rows", rows, "file", path, "watermark", value)
+	return res, nil
+}
+
+// watermarkKind classifies a watermark column of dataType, refusing types
+// that can't serve as one.
+func watermarkKind(driver, dataType string) (string, error) {
+	dt := strings.ToLower(dataType)
+	switch dt {
+	case "rowversion":
+		return watermarkRowversion, nil
+	case "timestamp":
+		// rowversion's old name on SQL Server; a plain datetime on Postgres.
+		if driver == database.DriverSQLServer {
+			return watermarkRowversion, nil
+		}
+		return watermarkTime, nil
+	case "datetime", "datetime2", "smalldatetime", "date", "datetimeoffset", "timestamptz":
+		return watermarkTime, nil
+	case "tinyint", "smallint", "int", "bigint", "int2", "int4", "int8":
+		return watermarkInt, nil
+	}
+	return "", fmt.Errorf("a %s column can't be a watermark; use a rowversion, date/time or integer column", dt)
+}
+
+// encodeWatermark returns the text a watermark value of kind is recorded as.
+func encodeWatermark(kind string, v interface{}) (string, error) {
+	switch kind {
+	case watermarkRowversion:
+		if b, ok := v.([]byte); ok {
+			return "0x" + strings.ToUpper(hex.EncodeToString(b)), nil
+		}
+	case watermarkTime:
+		if t, ok := v.(time.Time); ok {
+			return t.Format(time.RFC3339Nano), nil
+		}
+	case watermarkInt:
+		if n, ok := v.(int64); ok {
+			return strconv.FormatInt(n, 10), nil
+		}
+	}
+	return "", fmt.Errorf("unexpected %T value for a %s watermark", v, kind)
+}
+
+// decodeWatermark parses a recorded watermark back into a query argument.
+func decodeWatermark(kind, s string) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch kind {
+	case watermarkRowversion:
+		if !strings.HasPrefix(s, "0x") {
+			return nil, fmt.Errorf("%q is not a rowversion", s)
+		}
+		v, err = hex.DecodeString(s[2:])
+	case watermarkTime:
+		v, err = time.Parse(time.RFC3339Nano, s)
+	default:
+		v, err = strconv.ParseInt(s, 10, 64)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid %s watermark", s, kind)
+	}
+	return v, nil
+}
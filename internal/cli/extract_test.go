@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+func TestWatermarkRoundTrip(t *testing.T) {
+	cases := []struct {
+		driver, dataType string
+		value            interface{}
+		text             string
+	}{
+		{database.DriverSQLServer, "timestamp", []byte{0, 0, 0, 0, 0, 0, 0x07, 0xD1}, "0x00000000000007D1"},
+		{database.DriverSQLServer, "datetime2", time.Date(2026, 2, 11, 17, 2, 55, 123456700, time.UTC), "2026-02-11T17:02:55.1234567Z"},
+		{database.DriverPostgres, "timestamp", time.Date(2026, 2, 11, 17, 2, 55, 0, time.UTC), "2026-02-11T17:02:55Z"},
+		{database.DriverPostgres, "int8", int64(9001), "9001"},
+	}
+	for _, c := range cases {
+		kind, err := watermarkKind(c.driver, c.dataType)
+		if err != nil {
+			t.Fatalf("watermarkKind(%s, %s): %v", c.driver, c.dataType, err)
+		}
+		text, err := encodeWatermark(kind, c.value)
+		if err != nil || text != c.text {
+			t.Errorf("encodeWatermark(%s, %v) = %q, %v; want %q", kind, c.value, text, err, c.text)
+		}
+		back, err := decodeWatermark(kind, text)
+		if err != nil || !reflect.DeepEqual(back, c.value) {
+			t.Errorf("decodeWatermark(%s, %q) = %#v, %v; want %#v", kind, text, back, err, c.value)
+		}
+	}
+
+	if _, err := watermarkKind(database.DriverSQLServer, "nvarchar"); err == nil {
+		t.Error("nvarchar watermark: want error")
+	}
+}
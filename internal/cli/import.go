@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/manifoldco/promptui"
@@ -16,8 +17,8 @@ import (
 
 var importCmd = &cobra.Command{
 	Use:   "import",
-	Short: "Import a CSV file into an Azure SQL table",
-	Long:  `Interactively select a CSV file and target table, then bulk-insert rows using concurrent workers.`,
+	Short: "Import a CSV, Parquet, JSON Lines or Excel file into a database table",
+	Long:  `Interactively select an input file (or workbook sheet) and target table, then bulk-insert rows using concurrent workers.`,
 	RunE:  runImport,
 }
 
@@ -25,9 +26,23 @@ func init() {
 	importCmd.Flags().String("env", ".env", "path to .env file")
 	importCmd.Flags().Int("batch-size", 1000, "rows per batch")
 	importCmd.Flags().Int("workers", 4, "parallel worker count")
-	importCmd.Flags().String("file", "", "path to CSV file (skips interactive selection)")
+	importCmd.Flags().String("file", "", "path to input file (skips interactive selection)")
+	importCmd.Flags().String("sheet", "", "Excel: sheet to import from --file, by name or 1-based position (default: ask)")
 	importCmd.Flags().String("table", "", "target table as schema.name (skips interactive selection)")
 	importCmd.Flags().BoolP("yes", "y", false, "skip confirmation prompt")
+	importCmd.Flags().Bool("resume", false, "skip batches committed by an earlier interrupted run of the same file")
+	importCmd.Flags().String("state-dir", "", "directory for import checkpoints (default: next to the CSV)")
+	importCmd.Flags().Bool("dry-run", false, "validate every row against the table without writing anything")
+	importCmd.Flags().String("mapping", "", "column mapping file (YAML/JSON); stored under mappings/ for reuse by process")
+	importCmd.Flags().String("mode", modeUpsert, "upsert, or mirror to also delete rows missing from the file")
+	importCmd.Flags().String("scope", "", "with --mode mirror, only delete rows matching this SQL predicate")
+	importCmd.Flags().Float64("max-delete-pct", 10, "with --mode mirror, abort if more than this % of rows in scope would be deleted")
+	importCmd.Flags().String("oversize", database.OversizeError, "values too long or precise for their column: error (reject the row), truncate or null")
+	importCmd.Flags().Bool("evolve-schema", false, "add a nullable column to the table for each file header it lacks, typed from the data")
+	importCmd.Flags().Bool("fail-on-skipped", false, "fail a file that has headers with no table column instead of skipping them")
+	importCmd.Flags().Bool("create-table", false, "create --table from the file's inferred column types if it doesn't exist")
+	addSchemaFlags(importCmd)
+	addInputFlags(importCmd)
 	rootCmd.AddCommand(importCmd)
 }
 
@@ -38,16 +53,88 @@ func runImport(cmd *cobra.Command, args []string) error {
 	workers, _ := cmd.Flags().GetInt("workers")
 	filePath, _ := cmd.Flags().GetString("file")
 	tableName, _ := cmd.Flags().GetString("table")
+	sheet, _ := cmd.Flags().GetString("sheet")
 	autoConfirm, _ := cmd.Flags().GetBool("yes")
+	resume, _ := cmd.Flags().GetBool("resume")
+	stateDir, _ := cmd.Flags().GetString("state-dir")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	mappingPath, _ := cmd.Flags().GetString("mapping")
+	mode, _ := cmd.Flags().GetString("mode")
+	scope, _ := cmd.Flags().GetString("scope")
+	maxDeletePct, _ := cmd.Flags().GetFloat64("max-delete-pct")
+	oversize, _ := cmd.Flags().GetString("oversize")
+	evolve, _ := cmd.Flags().GetBool("evolve-schema")
+	failOnSkipped, _ := cmd.Flags().GetBool("fail-on-skipped")
+	create, _ := cmd.Flags().GetBool("create-table")
+	pkColumns, _ := cmd.Flags().GetStringSlice("primary-key")
+
+	opts := importOptions{
+		BatchSize:    batchSize,
+		Workers:      workers,
+		Resume:       resume,
+		StateDir:     stateDir,
+		MappingPath:  mappingPath,
+		Mode:         mode,
+		Scope:        scope,
+		MaxDeletePct: maxDeletePct,
+		Oversize:     oversize,
+	}
+	opts.EvolveSchema, opts.FailOnSkipped = evolve, failOnSkipped
+	opts.SampleRows, _ = cmd.Flags().GetInt("sample-rows")
+	if err := checkMode(opts); err != nil {
+		return err
+	}
+	if evolve && failOnSkipped {
+		return fmt.Errorf("--evolve-schema and --fail-on-skipped cannot be combined")
+	}
+	if err := database.CheckOversize(oversize); err != nil {
+		return fmt.Errorf("--oversize: %w", err)
+	}
+	dialect, err := dialectFlags(cmd)
+	if err != nil {
+		return err
+	}
+	opts.Dialect = dialect
+	opts.KeepJSON, _ = cmd.Flags().GetStringSlice("keep-json")
+	opts.HeaderRow, _ = cmd.Flags().GetInt("header-row")
+	if sheet != "" && !csvutil.IsWorkbook(filePath) {
+		return fmt.Errorf("--sheet needs --file naming an .xlsx workbook")
+	}
+	switch {
+	case create && tableName == "":
+		return fmt.Errorf("--create-table needs --table naming the table to create")
+	case create && dryRun:
+		return fmt.Errorf("--create-table cannot be combined with --dry-run; use infer-schema to preview the table")
+	case len(pkColumns) > 0 && !create:
+		return fmt.Errorf("--primary-key only applies with --create-table")
+	}
+	out := humanOut()
 
 	// 1. Select CSV file
 	var selectedCSV string
 	if filePath != "" {
 		// Non-interactive: use provided path
-		if _, err := os.Stat(filePath); err != nil {
-			return fmt.Errorf("csv file not found: %s", filePath)
+		if _, err := os.Stat(csvutil.SourcePath(filePath)); err != nil {
+			return fmt.Errorf("input file not found: %s", filePath)
 		}
 		selectedCSV = filePath
+		if strings.EqualFold(filepath.Ext(filePath), csvutil.ZipSuffix) {
+			members, err := csvutil.ArchiveMembers(filePath)
+			if err != nil {
+				return err
+			}
+			if len(members) != 1 {
+				return fmt.Errorf("%s holds %d CSV files; name one as %s", filePath, len(members),
+					csvutil.MemberPath(filePath, "<member.csv>"))
+			}
+			selectedCSV = members[0]
+		}
+		if csvutil.IsWorkbook(filePath) {
+			selectedCSV, err = selectSheet(filePath, sheet, autoConfirm)
+			if err != nil {
+				return err
+			}
+		}
 	} else {
 		// Interactive: scan and prompt
 		cwd, err := os.Getwd()
@@ -57,21 +144,22 @@ func runImport(cmd *cobra.Command, args []string) error {
 
 		csvFiles, err := csvutil.ScanDirectory(cwd)
 		if err != nil {
-			return fmt.Errorf("scanning for CSV files: %w", err)
+			return fmt.Errorf("scanning for input files: %w", err)
 		}
 		if len(csvFiles) == 0 {
-			return fmt.Errorf("no .csv files found in %s", cwd)
+			return fmt.Errorf("no input files found in %s", cwd)
 		}
 
 		names := make([]string, len(csvFiles))
 		for i, f := range csvFiles {
-			names[i] = filepath.Base(f)
+			names[i] = csvutil.DisplayName(f)
 		}
 
 		csvPrompt := promptui.Select{
-			Label: "Select CSV file",
-			Items: names,
-			Size:  15,
+			Label:  "Select input file",
+			Items:  names,
+			Size:   15,
+			Stdout: promptOut(),
 		}
 		csvIdx, _, err := csvPrompt.Run()
 		if err != nil {
@@ -79,7 +167,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 		}
 		selectedCSV = csvFiles[csvIdx]
 	}
-	fmt.Printf("Selected: %s\n", filepath.Base(selectedCSV))
+	fmt.Fprintf(out, "Selected: %s\n", csvutil.DisplayName(selectedCSV))
 
 	// 2. Load database config and connect
 	dbCfg, err := config.LoadDatabaseConfig(envPath, target)
@@ -87,21 +175,24 @@ func runImport(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("loading database config: %w", err)
 	}
 
-	fmt.Printf("Connecting to %s/%s...\n", dbCfg.Server, dbCfg.Database)
-	db, err := database.NewConnection(dbCfg.ConnectionString())
+	fmt.Fprintf(out, "Connecting to %s/%s...\n", dbCfg.Server, dbCfg.Database)
+	dest, err := database.Open(dbCfg.Driver, dbCfg.ConnectionString(), logger)
 	if err != nil {
 		return fmt.Errorf("connecting to database: %w", err)
 	}
-	defer db.Close()
-	fmt.Println("Connected.")
+	defer dest.Close()
+	fmt.Fprintln(out, "Connected.")
 
 	// 3. Select target table
 	ctx := context.Background()
 	var selectedTable string
 	if tableName != "" {
 		selectedTable = tableName
+		if create {
+			selectedTable = qualifyTable(tableName, dest.DefaultSchema())
+		}
 	} else {
-		tables, err := database.ListTables(ctx, db)
+		tables, err := dest.ListTables(ctx)
 		if err != nil {
 			return fmt.Errorf("listing tables: %w", err)
 		}
@@ -114,6 +205,7 @@ func runImport(cmd *cobra.Command, args []string) error {
 			Items:             tables,
 			Size:              15,
 			StartInSearchMode: true,
+			Stdout:            promptOut(),
 			Searcher: func(input string, index int) bool {
 				return strings.Contains(strings.ToLower(tables[index]), strings.ToLower(input))
 			},
@@ -123,26 +215,121 @@ func runImport(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("table selection: %w", err)
 		}
 	}
-	fmt.Printf("Target table: %s\n", selectedTable)
+	fmt.Fprintf(out, "Target table: %s\n", selectedTable)
+
+	// Dry run: validate only, nothing to confirm
+	if dryRun {
+		res, err := validateFile(ctx, dest, selectedCSV, selectedTable, opts, out)
+		if jsonOutput() {
+			if err := printJSON(res); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if mappingPath != "" {
+			return storeMapping(mappingPath, selectedTable, out)
+		}
+		return nil
+	}
+
+	// Create the table if asked to and it's missing
+	if create {
+		ok, err := createTable(ctx, dest, selectedCSV, selectedTable, opts, pkColumns, autoConfirm, out)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Import cancelled.")
+			return nil
+		}
+	}
 
 	// 4. Confirm
 	if !autoConfirm {
 		confirmPrompt := promptui.Prompt{
-			Label:     fmt.Sprintf("Import %s into %s", filepath.Base(selectedCSV), selectedTable),
+			Label:     fmt.Sprintf("Import %s into %s", csvutil.DisplayName(selectedCSV), selectedTable),
 			IsConfirm: true,
+			Stdout:    promptOut(),
 		}
 		if _, err := confirmPrompt.Run(); err != nil {
-			fmt.Println("Import cancelled.")
+			fmt.Fprintln(out, "Import cancelled.")
 			return nil
 		}
 	}
 
 	// 5. Run import using shared helper
-	totalInserted, err := importFile(ctx, db, selectedCSV, selectedTable, batchSize, workers, os.Stdout)
-	if err != nil {
+	stats, err := importFile(ctx, dest, selectedCSV, selectedTable, opts, out)
+	res := newFileResult(stats, err)
+	if jsonOutput() {
+		if err := printJSON(res); err != nil {
+			return err
+		}
+	}
+	if res.Status == "failed" {
 		return err
 	}
-	_ = totalInserted
 
-	return nil
+	// Keep the mapping so process can reuse it for this table
+	if mappingPath != "" {
+		if err := storeMapping(mappingPath, selectedTable, out); err != nil {
+			return err
+		}
+	}
+
+	return res.exitErr(err)
+}
+
+// selectSheet returns the MemberPath of the sheet to import from workbook:
+// the one named or numbered by sheet, the only one, or the one picked at a
+// prompt. With autoConfirm there is no prompt, so a workbook of several
+// sheets needs --sheet.
+func selectSheet(workbook, sheet string, autoConfirm bool) (string, error) {
+	sheets, err := csvutil.WorkbookSheets(workbook)
+	if err != nil {
+		return "", err
+	}
+	if len(sheets) == 0 {
+		return "", fmt.Errorf("%s has no visible sheets", workbook)
+	}
+	names := make([]string, len(sheets))
+	for i, s := range sheets {
+		_, names[i], _ = csvutil.SplitMember(s)
+	}
+
+	if sheet != "" {
+		for i, name := range names {
+			if name == sheet {
+				return sheets[i], nil
+			}
+		}
+		for i, name := range names {
+			if strings.EqualFold(name, sheet) {
+				return sheets[i], nil
+			}
+		}
+		if n, err := strconv.Atoi(sheet); err == nil && n >= 1 && n <= len(sheets) {
+			return sheets[n-1], nil
+		}
+		return "", fmt.Errorf("%s has no sheet %q (sheets: %s)", workbook, sheet, strings.Join(names, ", "))
+	}
+	if len(sheets) == 1 {
+		return sheets[0], nil
+	}
+	if autoConfirm {
+		return "", fmt.Errorf("%s has %d sheets; pick one with --sheet (sheets: %s)", workbook, len(sheets), strings.Join(names, ", "))
+	}
+
+	sheetPrompt := promptui.Select{
+		Label:  "Select sheet",
+		Items:  names,
+		Size:   15,
+		Stdout: promptOut(),
+	}
+	idx, _, err := sheetPrompt.Run()
+	if err != nil {
+		return "", fmt.Errorf("sheet selection: %w", err)
+	}
+	return sheets[idx], nil
 }
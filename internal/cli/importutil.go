@@ -2,7 +2,6 @@ package cli
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
 	"io"
 	"os"
@@ -11,100 +10,390 @@ import (
 	"time"
 
 	"github.com/schollz/progressbar/v3"
+	"github.com/walkerscm/scaleSyncGo/internal/checkpoint"
 	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
 	"github.com/walkerscm/scaleSyncGo/internal/database"
 	"github.com/walkerscm/scaleSyncGo/internal/worker"
 )
 
-// importFile performs the core CSV-to-database import: reads columns, maps headers,
-// runs worker pool, and returns total rows inserted. Progress is written to w.
-func importFile(ctx context.Context, db *sql.DB, csvPath, schemaTable string, batchSize, workers int, w io.Writer) (int, error) {
+// importOptions controls how importFile loads a single file.
+type importOptions struct {
+	BatchSize int
+	Workers   int
+	// Resume skips batches recorded in a matching checkpoint from an
+	// earlier, interrupted run of the same file.
+	Resume bool
+	// StateDir is where checkpoints are kept; empty means next to the CSV.
+	StateDir string
+	// MappingPath is an explicit column mapping file. When empty, a mapping
+	// stored for the target table in mappings/ is used if there is one.
+	MappingPath string
+	// Mode is modeUpsert or modeMirror.
+	Mode string
+	// Scope limits which target rows a mirror may delete (SQL predicate).
+	Scope string
+	// MaxDeletePct aborts a mirror that would delete more than this
+	// percentage of the rows in scope.
+	MaxDeletePct float64
+	// Dialect holds the CSV dialect flags; see dialectFor.
+	Dialect csvutil.Dialect
+	// KeepJSON lists nested JSON Lines objects to load as JSON text.
+	KeepJSON []string
+	// HeaderRow is the header row of Excel sheets, or 0 to detect it.
+	HeaderRow int
+	// Oversize is the policy for values too long or too precise for their
+	// column, where the mapping file sets none.
+	Oversize string
+	// NoProgress suppresses the progress bar, for output that is buffered
+	// rather than shown on a terminal.
+	NoProgress bool
+	// EvolveSchema adds a nullable column to the table for each file header
+	// with no table column, typed from the first SampleRows rows.
+	EvolveSchema bool
+	SampleRows   int
+	// FailOnSkipped fails the file when it has headers with no table column.
+	FailOnSkipped bool
+	// DryRun makes planImport show schema changes without applying them.
+	DryRun bool
+}
+
+// Import modes. Upsert merges the file into the table batch by batch; mirror
+// stages the whole file first and then also deletes target rows the file no
+// longer has.
+const (
+	modeUpsert = "upsert"
+	modeMirror = "mirror"
+)
+
+// checkMode validates the mode-related options.
+func checkMode(opts importOptions) error {
+	switch opts.Mode {
+	case modeUpsert:
+		if opts.Scope != "" {
+			return fmt.Errorf("--scope only applies to --mode mirror")
+		}
+	case modeMirror:
+		if opts.Resume {
+			return fmt.Errorf("--resume is not supported with --mode mirror")
+		}
+		if opts.MaxDeletePct < 0 || opts.MaxDeletePct > 100 {
+			return fmt.Errorf("--max-delete-pct must be between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("unknown --mode %q (want %s or %s)", opts.Mode, modeUpsert, modeMirror)
+	}
+	return nil
+}
+
+// mappingsDir holds the column mapping stored per table, named
+// <schema>.<table>.yaml, for import and process to pick up automatically.
+const mappingsDir = "mappings"
+
+// importStats summarises a finished importFile run. It is also the body of
+// the JSON report for the file.
+type importStats struct {
+	File     string `json:"file"`
+	Table    string `json:"table"`
+	Mode     string `json:"mode"`
+	Inserted int    `json:"rows_inserted"`
+	Rejected int    `json:"rows_rejected"`
+	// Skipped counts rows a resumed checkpoint showed were already committed.
+	Skipped int `json:"rows_resumed"`
+	// Deleted counts target rows a mirror removed.
+	Deleted int64 `json:"rows_deleted"`
+	// SkippedColumns are CSV columns with no matching table column.
+	SkippedColumns []string `json:"skipped_columns"`
+	FailedBatches  int      `json:"failed_batches"`
+	// Errors holds the first few batch errors.
+	Errors     []string `json:"errors"`
+	DurationMS int64    `json:"duration_ms"`
+	RowsPerSec float64  `json:"rows_per_sec"`
+	// RejectsPath is the rejects file, or empty when no rows were rejected.
+	RejectsPath string `json:"rejects_file,omitempty"`
+}
+
+// importPlan is everything importFile and validateFile need to know about a
+// CSV and its target table before reading rows.
+type importPlan struct {
+	reader      csvutil.RowReader
+	dialect     csvutil.Dialect // as read, for CSV input
+	headers     []string
+	mapping     *database.MappingFile // nil when headers are matched by name only
+	mapResult   *database.MapResult
+	dbColumns   []string
+	pkColumns   []string
+	hasIdentity bool
+}
+
+// planImport opens the CSV, maps its headers onto the table's columns and
+// looks up the table's key and identity columns, describing each step on w.
+// The caller must close plan.reader.
+func planImport(ctx context.Context, dest database.Destination, csvPath, schemaTable string, opts importOptions, w io.Writer) (*importPlan, error) {
 	// Get table schema
-	tableCols, err := database.GetTableColumns(ctx, db, schemaTable)
+	tableCols, err := dest.GetTableColumns(ctx, schemaTable)
 	if err != nil {
-		return 0, fmt.Errorf("getting table columns: %w", err)
+		return nil, fmt.Errorf("getting table columns: %w", err)
 	}
 	fmt.Fprintf(w, "Table has %d columns\n", len(tableCols))
 
-	// Open CSV, read headers
-	reader, err := csvutil.NewReader(csvPath)
+	// Open the file, read headers
+	reader, err := csvutil.Open(csvPath, csvutil.Options{
+		Dialect:   dialectFor(schemaTable, opts.Dialect),
+		KeepJSON:  opts.KeepJSON,
+		HeaderRow: opts.HeaderRow,
+	})
 	if err != nil {
-		return 0, fmt.Errorf("opening CSV: %w", err)
+		return nil, fmt.Errorf("opening %s: %w", csvutil.DisplayName(csvPath), err)
+	}
+	plan := &importPlan{reader: reader, headers: reader.Headers()}
+	fail := func(err error) (*importPlan, error) {
+		reader.Close()
+		return nil, err
+	}
+
+	switch r := reader.(type) {
+	case *csvutil.Reader:
+		plan.dialect = r.Dialect()
+		if plan.dialect != (csvutil.Dialect{}) {
+			fmt.Fprintf(w, "CSV dialect: %s\n", plan.dialect)
+		}
+		fmt.Fprintf(w, "CSV has %d columns\n", len(plan.headers))
+	case *csvutil.ParquetReader:
+		fmt.Fprintf(w, "Parquet file has %d columns and %d rows\n", len(plan.headers), r.NumRows())
+	case *csvutil.JSONLReader:
+		fmt.Fprintf(w, "JSON Lines file has %d fields and %d rows\n", len(plan.headers), r.NumRows())
+	case *csvutil.XLSXReader:
+		fmt.Fprintf(w, "Sheet %s has %d columns (headers in row %d)\n", r.Sheet(), len(plan.headers), r.HeaderRow())
 	}
-	defer reader.Close()
 
-	headers := reader.Headers()
-	fmt.Fprintf(w, "CSV has %d columns\n", len(headers))
+	// Load the mapping file, if any
+	mf, mfPath, err := resolveMapping(schemaTable, opts.MappingPath)
+	if err != nil {
+		return fail(err)
+	}
+	if mf != nil {
+		fmt.Fprintf(w, "Using column mapping %s\n", mfPath)
+		plan.mapping = mf
+	}
 
 	// Map columns
-	mapResult, err := database.MapColumns(headers, tableCols)
+	plan.mapResult, err = database.MapColumns(plan.headers, tableCols, mf)
 	if err != nil {
-		return 0, fmt.Errorf("column mapping: %w", err)
+		return fail(fmt.Errorf("column mapping: %w", err))
+	}
+
+	// Add columns for new headers, or refuse them
+	if skipped := plan.mapResult.Skipped; len(skipped) > 0 && opts.EvolveSchema {
+		added, err := evolveSchema(ctx, dest, csvPath, schemaTable, skipped, opts, w)
+		if err != nil {
+			return fail(err)
+		}
+		if added {
+			if tableCols, err = dest.GetTableColumns(ctx, schemaTable); err != nil {
+				return fail(fmt.Errorf("getting table columns: %w", err))
+			}
+			if plan.mapResult, err = database.MapColumns(plan.headers, tableCols, mf); err != nil {
+				return fail(fmt.Errorf("column mapping: %w", err))
+			}
+		}
+	}
+	if skipped := plan.mapResult.Skipped; len(skipped) > 0 && opts.FailOnSkipped {
+		return fail(fmt.Errorf("%d column(s) have no match in %s: %s (--fail-on-skipped)",
+			len(skipped), schemaTable, strings.Join(skipped, ", ")))
 	}
 
-	fmt.Fprintf(w, "Matched %d columns\n", len(mapResult.Mapped))
-	if len(mapResult.Skipped) > 0 {
+	for i := range plan.mapResult.Mapped {
+		if plan.mapResult.Mapped[i].Oversize == "" {
+			plan.mapResult.Mapped[i].Oversize = opts.Oversize
+		}
+	}
+
+	fmt.Fprintf(w, "Matched %d columns\n", len(plan.mapResult.Mapped))
+	if mf != nil {
+		printMapping(w, plan.mapResult)
+	}
+	if len(plan.mapResult.Skipped) > 0 {
 		fmt.Fprintf(w, "Skipped %d CSV columns (no table match): %s\n",
-			len(mapResult.Skipped), strings.Join(mapResult.Skipped, ", "))
+			len(plan.mapResult.Skipped), strings.Join(plan.mapResult.Skipped, ", "))
 	}
 
 	// Build column names list
-	dbColumns := make([]string, len(mapResult.Mapped))
-	for i, m := range mapResult.Mapped {
-		dbColumns[i] = m.DBColumn.Name
+	plan.dbColumns = make([]string, len(plan.mapResult.Mapped))
+	for i, m := range plan.mapResult.Mapped {
+		plan.dbColumns[i] = m.DBColumn.Name
 	}
 
 	// Fetch primary key columns for upsert support
-	pkColumns, err := database.GetPrimaryKeyColumns(ctx, db, schemaTable)
+	plan.pkColumns, err = dest.GetPrimaryKeyColumns(ctx, schemaTable)
 	if err != nil {
-		return 0, fmt.Errorf("getting primary key columns: %w", err)
+		return fail(fmt.Errorf("getting primary key columns: %w", err))
 	}
-	if len(pkColumns) > 0 {
-		fmt.Fprintf(w, "Primary key: %s\n", strings.Join(pkColumns, ", "))
+	if len(plan.pkColumns) > 0 {
+		fmt.Fprintf(w, "Primary key: %s\n", strings.Join(plan.pkColumns, ", "))
 	} else {
 		fmt.Fprintln(w, "No primary key found — using insert-only mode")
 	}
 
 	// Check for identity columns
-	hasIdentity, err := database.HasIdentityColumn(ctx, db, schemaTable)
+	plan.hasIdentity, err = dest.HasIdentityColumn(ctx, schemaTable)
 	if err != nil {
-		return 0, fmt.Errorf("checking identity column: %w", err)
+		return fail(fmt.Errorf("checking identity column: %w", err))
+	}
+	if plan.hasIdentity {
+		fmt.Fprintln(w, "Identity column detected — identity values from the CSV will be inserted as-is")
 	}
-	if hasIdentity {
-		fmt.Fprintln(w, "Identity column detected — IDENTITY_INSERT will be enabled during merge")
+
+	return plan, nil
+}
+
+// importFile performs the core CSV-to-database import: reads columns, maps headers,
+// runs worker pool, and returns how many rows were inserted and rejected.
+// Progress is written to w.
+//
+// Rows the database refuses are isolated by the worker pool and written to a
+// rejects file next to the CSV; they don't fail the import. An error is
+// returned only when whole batches could not be loaded.
+//
+// Every committed batch is recorded in an on-disk checkpoint, which is removed
+// once the file imports cleanly. With opts.Resume a checkpoint left behind by a
+// failed run is used to skip the batches it already committed.
+//
+// In mirror mode the workers load a staging table instead, and the target is
+// only touched once the whole file has staged without errors or rejects: a
+// single MERGE then upserts every row and deletes those missing from the file.
+// Mirror runs keep no checkpoint.
+func importFile(ctx context.Context, dest database.Destination, csvPath, schemaTable string, opts importOptions, w io.Writer) (importStats, error) {
+	stats := importStats{
+		File:           csvPath,
+		Table:          schemaTable,
+		Mode:           opts.Mode,
+		SkippedColumns: []string{},
+		Errors:         []string{},
+	}
+	mirror := opts.Mode == modeMirror
+	log := logger.With("file", csvutil.DisplayName(csvPath), "table", schemaTable)
+
+	plan, err := planImport(ctx, dest, csvPath, schemaTable, opts, w)
+	if err != nil {
+		log.Error("import failed", "err", err)
+		return stats, err
+	}
+	log.Info("import started", "mode", opts.Mode, "columns", len(plan.dbColumns), "primary_key", plan.pkColumns)
+	if plan.mapResult.Skipped != nil {
+		stats.SkippedColumns = plan.mapResult.Skipped
+	}
+	reader := plan.reader
+	defer reader.Close()
+	headers := plan.headers
+
+	// Workers load the target directly, or a staging table when mirroring.
+	loadTable, loadPK, loadIdentity := schemaTable, plan.pkColumns, plan.hasIdentity
+	if mirror {
+		if len(plan.pkColumns) == 0 {
+			return stats, fmt.Errorf("--mode mirror needs a primary key on %s", schemaTable)
+		}
+		stage, err := dest.CreateStagingTable(ctx, schemaTable, plan.dbColumns)
+		if err != nil {
+			return stats, err
+		}
+		defer func() {
+			// Drop the stage even if ctx was cancelled.
+			if err := dest.DropTable(context.Background(), stage); err != nil {
+				fmt.Fprintf(w, "WARNING: %v\n", err)
+				log.Warn("staging table not dropped", "stage", stage, "err", err)
+			}
+		}()
+		fmt.Fprintf(w, "Mirror mode: staging the file in %s\n", stage)
+		loadTable, loadPK, loadIdentity = stage, nil, false
+	}
+
+	// Load or start the checkpoint
+	var cp *checkpoint.Checkpoint
+	var resumed bool
+	var startBatch, resumedRows int
+	committed := map[int]bool{}
+	settled := map[int]map[int]bool{}
+	if !mirror {
+		cp, resumed, err = openCheckpoint(csvPath, schemaTable, opts, plan.dialect, plan.mapResult.Mapped, plan.pkColumns, w)
+		if err != nil {
+			return stats, err
+		}
+		startBatch = cp.Through
+		committed = cp.Committed()
+		settled = cp.Settled()
+		resumedRows = cp.Rows
+		if startBatch > 0 {
+			if err := reader.SeekTo(cp.Offset, cp.Line); err != nil {
+				return stats, err
+			}
+		}
+		if startBatch > 0 || len(committed) > 0 || len(settled) > 0 {
+			fmt.Fprintf(w, "Resuming: %d batch(es) already committed, %d row(s) skipped\n",
+				startBatch+len(committed), resumedRows+cp.PendingRows())
+		}
+	}
+
+	// Clear the last run's rejects before anything starts that would have to
+	// be stopped if that fails.
+	rejects := newRejectWriter(rejectsPath(csvPath), headers, resumed)
+	if !resumed {
+		// A rejects file from an earlier run no longer describes this one.
+		if err := os.Remove(rejects.path); err != nil && !os.IsNotExist(err) {
+			return stats, fmt.Errorf("removing old rejects file: %w", err)
+		}
 	}
 
 	// Start worker pool
-	pool := worker.NewPool(db, schemaTable, dbColumns, pkColumns, hasIdentity, mapResult.Mapped, workers)
+	pool := worker.NewPool(dest, loadTable, plan.dbColumns, loadPK, loadIdentity, plan.mapResult.Mapped, opts.Workers, log)
 	pool.Start(ctx)
 
 	// Progress bar
-	totalRows := countCSVRows(csvPath)
-	bar := progressbar.NewOptions(totalRows,
-		progressbar.OptionSetDescription("Importing"),
-		progressbar.OptionSetWriter(w),
-		progressbar.OptionShowCount(),
-		progressbar.OptionSetWidth(40),
-		progressbar.OptionThrottle(200*time.Millisecond),
-		progressbar.OptionShowIts(),
-		progressbar.OptionSetItsString("rows"),
-		progressbar.OptionOnCompletion(func() { fmt.Fprintln(w) }),
-	)
+	barOut := w
+	if opts.NoProgress {
+		barOut = io.Discard
+	}
+	progress := newImportProgress(reader, csvPath, barOut)
+	progress.rows(resumedRows)
+	if read, _ := reader.Progress(); startBatch > 0 {
+		progress.reached(read)
+	}
 
-	// Feed batches
+	// Feed batches. skippedRows and readErr are only read after the results
+	// channel is drained, which happens after this goroutine calls pool.Done.
+	var skippedRows int
+	var readErr error
 	go func() {
-		batchNum := 0
+		batchNum := startBatch
 		for {
-			rows, err := reader.ReadBatch(batchSize)
-			if len(rows) > 0 {
-				pool.Submit(worker.Job{BatchNum: batchNum, Rows: rows})
+			batch, err := reader.ReadRows(opts.BatchSize)
+			if batch.Len() > 0 {
+				if committed[batchNum] {
+					skippedRows += batch.Len()
+				} else {
+					if lines := settled[batchNum]; lines != nil {
+						n := batch.Len()
+						batch = unsettledRows(batch, lines)
+						skippedRows += n - batch.Len()
+					}
+					read, _ := reader.Progress()
+					pool.Submit(worker.Job{
+						BatchNum:     batchNum,
+						Rows:         batch.Text,
+						Values:       batch.Values,
+						Lines:        batch.Lines,
+						Offset:       reader.Offset(),
+						Line:         reader.Line(),
+						SourceOffset: read,
+					})
+				}
 				batchNum++
 			}
 			if err == io.EOF {
 				break
 			}
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "\nCSV read error: %v\n", err)
+				readErr = err
+				log.Error("reading input", "line", reader.Line(), "err", err)
 				break
 			}
 		}
@@ -113,44 +402,262 @@ func importFile(ctx context.Context, db *sql.DB, csvPath, schemaTable string, ba
 
 	// Collect results
 	start := time.Now()
-	var totalInserted int
-	var errorCount int
-	var errors []string
+	var checkpointErr, rejectsErr error
 
 	for result := range pool.Results() {
+		stats.Inserted += result.RowCount
+		if rejectsErr == nil {
+			rejectsErr = rejects.Write(result.Rejects)
+		}
 		if result.Err != nil {
-			errorCount++
-			if len(errors) < 10 {
-				errors = append(errors, result.Err.Error())
+			stats.FailedBatches++
+			if len(stats.Errors) < 10 {
+				stats.Errors = append(stats.Errors, result.Err.Error())
 			}
+			// Rows committed or rejected before the failure must not be
+			// loaded or rejected again on --resume.
+			if cp != nil && checkpointErr == nil && len(result.Settled) > 0 {
+				checkpointErr = cp.CommitPartial(result.BatchNum, result.Settled)
+			}
+		} else if cp != nil && checkpointErr == nil {
+			checkpointErr = cp.Commit(checkpoint.Batch{
+				Num:    result.BatchNum,
+				Offset: result.Offset,
+				Line:   result.Line,
+				Rows:   result.RowCount + len(result.Rejects),
+			})
+		}
+		progress.rows(result.RowCount + len(result.Rejects))
+		progress.reached(result.SourceOffset)
+	}
+	progress.rows(skippedRows)
+	progress.finish()
+
+	if err := rejects.Close(); err != nil && rejectsErr == nil {
+		rejectsErr = err
+	}
+	stats.Rejected = rejects.Count()
+	stats.Skipped = resumedRows + skippedRows
+	if stats.Rejected > 0 {
+		stats.RejectsPath = rejects.path
+	}
+
+	// Apply the mirror only from a complete stage: a rejected row missing
+	// from it would otherwise be deleted from the target.
+	var mirrorErr error
+	var merged int64
+	if mirror && stats.FailedBatches == 0 && readErr == nil {
+		if stats.Rejected > 0 {
+			mirrorErr = fmt.Errorf("mirror not applied: %d row(s) rejected", stats.Rejected)
 		} else {
-			totalInserted += result.RowCount
+			ms, err := dest.MirrorFromStaging(ctx, schemaTable, loadTable, plan.dbColumns, plan.pkColumns, plan.hasIdentity,
+				database.MirrorOptions{Scope: opts.Scope, MaxDeletePct: opts.MaxDeletePct})
+			if err != nil {
+				mirrorErr = fmt.Errorf("mirror not applied: %w", err)
+			} else {
+				merged, stats.Deleted = ms.Merged, ms.Deleted
+			}
 		}
-		bar.Add(result.RowCount) //nolint:errcheck
 	}
-	bar.Finish() //nolint:errcheck
 
 	// Summary
 	elapsed := time.Since(start)
-	rowsPerSec := float64(totalInserted) / elapsed.Seconds()
+	stats.DurationMS = elapsed.Milliseconds()
+	stats.RowsPerSec = float64(stats.Inserted) / elapsed.Seconds()
 
 	fmt.Fprintf(w, "\n--- Import Summary ---\n")
-	fmt.Fprintf(w, "File:                %s\n", filepath.Base(csvPath))
+	fmt.Fprintf(w, "File:                %s\n", csvutil.DisplayName(csvPath))
 	fmt.Fprintf(w, "Table:               %s\n", schemaTable)
-	fmt.Fprintf(w, "Total rows inserted: %d\n", totalInserted)
+	if mirror {
+		fmt.Fprintf(w, "Rows staged:         %d\n", stats.Inserted)
+		if mirrorErr == nil && stats.FailedBatches == 0 {
+			fmt.Fprintf(w, "Rows merged:         %d\n", merged)
+			fmt.Fprintf(w, "Rows deleted:        %d\n", stats.Deleted)
+		}
+	} else {
+		fmt.Fprintf(w, "Rows inserted:       %d\n", stats.Inserted)
+	}
+	fmt.Fprintf(w, "Rows rejected:       %d\n", stats.Rejected)
+	if stats.Skipped > 0 {
+		fmt.Fprintf(w, "Resumed (skipped):   %d\n", stats.Skipped)
+	}
 	fmt.Fprintf(w, "Duration:            %s\n", elapsed.Round(time.Millisecond))
-	fmt.Fprintf(w, "Throughput:          %.0f rows/sec\n", rowsPerSec)
-	fmt.Fprintf(w, "Errors:              %d\n", errorCount)
+	fmt.Fprintf(w, "Throughput:          %.0f rows/sec\n", stats.RowsPerSec)
+	if stats.FailedBatches > 0 {
+		fmt.Fprintf(w, "Failed batches:      %d\n", stats.FailedBatches)
+	}
+	if stats.Rejected > 0 {
+		fmt.Fprintf(w, "Rejects written to:  %s\n", stats.RejectsPath)
+	}
 
-	if len(errors) > 0 {
+	if checkpointErr != nil {
+		fmt.Fprintf(w, "WARNING: checkpoint not updated: %v\n", checkpointErr)
+		log.Warn("checkpoint not updated", "err", checkpointErr)
+	}
+	if rejectsErr != nil {
+		fmt.Fprintf(w, "WARNING: rejects file incomplete: %v\n", rejectsErr)
+		log.Warn("rejects file incomplete", "err", rejectsErr)
+	}
+
+	log.Info("import finished",
+		"inserted", stats.Inserted,
+		"rejected", stats.Rejected,
+		"resumed", stats.Skipped,
+		"deleted", stats.Deleted,
+		"failed_batches", stats.FailedBatches,
+		"duration", elapsed,
+		"rows_per_sec", int(stats.RowsPerSec))
+
+	if len(stats.Errors) > 0 {
 		fmt.Fprintln(w, "\nFirst errors:")
-		for _, e := range errors {
+		for _, e := range stats.Errors {
 			fmt.Fprintf(w, "  - %s\n", e)
 		}
-		return totalInserted, fmt.Errorf("%d batch errors during import", errorCount)
+		if cp != nil && checkpointErr == nil {
+			fmt.Fprintf(w, "\nCheckpoint saved to %s — rerun with --resume to retry only the failed batches.\n", cp.Path())
+		}
+		return stats, fmt.Errorf("%d batch errors during import", stats.FailedBatches)
+	}
+	if readErr != nil {
+		fmt.Fprintf(w, "\nCSV read error: %v\n", readErr)
+		return stats, fmt.Errorf("reading CSV: %w", readErr)
+	}
+	if mirrorErr != nil {
+		log.Error("mirror not applied", "err", mirrorErr)
+		return stats, mirrorErr
+	}
+
+	if cp != nil {
+		if err := cp.Remove(); err != nil {
+			fmt.Fprintf(w, "WARNING: %v\n", err)
+			log.Warn("checkpoint not removed", "err", err)
+		}
+	}
+
+	return stats, nil
+}
+
+// unsettledRows returns batch without the rows starting on the given
+// lines, which an earlier run already committed or rejected.
+func unsettledRows(batch csvutil.Batch, lines map[int]bool) csvutil.Batch {
+	var out csvutil.Batch
+	for i, line := range batch.Lines {
+		if lines[line] {
+			continue
+		}
+		out.Lines = append(out.Lines, line)
+		if batch.Text != nil {
+			out.Text = append(out.Text, batch.Text[i])
+		}
+		if batch.Values != nil {
+			out.Values = append(out.Values, batch.Values[i])
+		}
+	}
+	return out
+}
+
+// openCheckpoint returns the checkpoint to record this import's progress in.
+// With opts.Resume an existing checkpoint is reused if it was written for the
+// same file contents, table, batch size and column mapping; anything else
+// starts a fresh checkpoint, replacing whatever was on disk. The returned bool
+// reports whether an existing checkpoint is being resumed.
+func openCheckpoint(csvPath, schemaTable string, opts importOptions, dialect csvutil.Dialect, mapped []database.ColumnMapping, pkColumns []string, w io.Writer) (*checkpoint.Checkpoint, bool, error) {
+	fileHash, err := checkpoint.HashFile(csvutil.SourcePath(csvPath))
+	if err != nil {
+		return nil, false, fmt.Errorf("fingerprinting CSV: %w", err)
 	}
 
-	return totalInserted, nil
+	parts := make([]string, 0, len(mapped)+1)
+	for _, m := range mapped {
+		parts = append(parts, fmt.Sprintf("%d=%s|%s|%s|%s", m.CSVIndex, m.DBColumn.Name, m.Constant, m.Default, m.Oversize))
+	}
+	parts = append(parts, "pk="+strings.Join(pkColumns, ","), "dialect="+dialect.String())
+
+	key := checkpoint.Key{
+		FileHash:    fileHash,
+		Table:       schemaTable,
+		BatchSize:   opts.BatchSize,
+		MappingHash: checkpoint.HashStrings(parts...),
+	}
+	path := checkpoint.PathFor(opts.StateDir, csvutil.FlatPath(csvPath))
+
+	if opts.Resume {
+		cp, err := checkpoint.Load(path)
+		if err != nil {
+			return nil, false, err
+		}
+		switch {
+		case cp == nil:
+			fmt.Fprintln(w, "No checkpoint found — starting from the beginning")
+		case cp.Key != key:
+			fmt.Fprintln(w, "Checkpoint is stale (file, table, batch size, mapping or dialect changed) — starting from the beginning")
+		default:
+			return cp, true, nil
+		}
+	}
+
+	cp := checkpoint.New(path, csvPath, key)
+	if err := cp.Save(); err != nil {
+		return nil, false, err
+	}
+	return cp, false, nil
+}
+
+// importProgress shows the progress of an import: rows loaded out of the
+// file's line count, or for compressed input, whose lines can't be counted
+// without decompressing it twice, the compressed bytes loaded so far.
+type importProgress struct {
+	bar   *progressbar.ProgressBar
+	bytes bool
+	pos   int64
+}
+
+func newImportProgress(reader csvutil.RowReader, csvPath string, w io.Writer) *importProgress {
+	opts := []progressbar.Option{
+		progressbar.OptionSetDescription("Importing"),
+		progressbar.OptionSetWriter(w),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionThrottle(200 * time.Millisecond),
+		progressbar.OptionOnCompletion(func() { fmt.Fprintln(w) }),
+	}
+	if reader.Compressed() {
+		_, size := reader.Progress()
+		opts = append(opts, progressbar.OptionShowBytes(true), progressbar.OptionShowCount())
+		return &importProgress{bar: progressbar.NewOptions64(size, opts...), bytes: true}
+	}
+	opts = append(opts,
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetItsString("rows"),
+	)
+	var total int
+	switch r := reader.(type) {
+	case interface{ NumRows() int64 }:
+		total = int(r.NumRows())
+	default:
+		total = countCSVRows(csvPath)
+	}
+	return &importProgress{bar: progressbar.NewOptions(total, opts...)}
+}
+
+// rows records n more rows loaded.
+func (p *importProgress) rows(n int) {
+	if !p.bytes {
+		p.bar.Add(n) //nolint:errcheck
+	}
+}
+
+// reached records that loading has got to offset bytes into the file on
+// disk. Batches finish out of order, so only the furthest offset counts.
+func (p *importProgress) reached(offset int64) {
+	if p.bytes && offset > p.pos {
+		p.pos = offset
+		p.bar.Set64(offset) //nolint:errcheck
+	}
+}
+
+func (p *importProgress) finish() {
+	p.bar.Finish() //nolint:errcheck
 }
 
 // countCSVRows does a quick line count of the file (minus the header).
@@ -179,3 +686,74 @@ func countCSVRows(path string) int {
 	}
 	return count
 }
+
+// resolveMapping loads the mapping file at explicitPath, or else the one
+// stored for schemaTable in mappingsDir. It returns nil when there is none.
+func resolveMapping(schemaTable, explicitPath string) (*database.MappingFile, string, error) {
+	var mf *database.MappingFile
+	path := explicitPath
+	var err error
+	if explicitPath != "" {
+		mf, err = database.LoadMappingFile(explicitPath)
+	} else {
+		mf, path, err = database.FindMappingFile(mappingsDir, schemaTable)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if mf != nil && mf.Table != "" && !strings.EqualFold(mf.Table, schemaTable) {
+		return nil, "", fmt.Errorf("mapping file %s is for table %s, not %s", path, mf.Table, schemaTable)
+	}
+	return mf, path, nil
+}
+
+// storeMapping saves the mapping file at path as the stored mapping for
+// schemaTable so later imports and process runs pick it up.
+func storeMapping(path, schemaTable string, w io.Writer) error {
+	dest := database.MappingFilePath(mappingsDir, schemaTable)
+	if abs, err := filepath.Abs(path); err == nil {
+		if absDest, err := filepath.Abs(dest); err == nil && abs == absDest {
+			return nil
+		}
+	}
+
+	mf, err := database.LoadMappingFile(path)
+	if err != nil {
+		return err
+	}
+	mf.Table = schemaTable
+	if err := mf.Save(dest); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Mapping stored as %s\n", dest)
+	return nil
+}
+
+// printMapping lists the resolved column mapping.
+func printMapping(w io.Writer, mr *database.MapResult) {
+	width := 0
+	for _, m := range mr.Mapped {
+		if len(m.CSVName) > width {
+			width = len(m.CSVName)
+		}
+	}
+	if width < len("(constant)") {
+		width = len("(constant)")
+	}
+
+	for _, m := range mr.Mapped {
+		switch {
+		case m.CSVIndex < 0 && m.Default != "":
+			fmt.Fprintf(w, "  %-*s → %s = %s\n", width, "(default)", m.DBColumn.Name, m.Default)
+		case m.CSVIndex < 0:
+			fmt.Fprintf(w, "  %-*s → %s = %q\n", width, "(constant)", m.DBColumn.Name, m.Constant)
+		case m.Default != "":
+			fmt.Fprintf(w, "  %-*s → %s (default %s)\n", width, m.CSVName, m.DBColumn.Name, m.Default)
+		default:
+			fmt.Fprintf(w, "  %-*s → %s\n", width, m.CSVName, m.DBColumn.Name)
+		}
+	}
+	if len(mr.Ignored) > 0 {
+		fmt.Fprintf(w, "Ignored %d CSV columns: %s\n", len(mr.Ignored), strings.Join(mr.Ignored, ", "))
+	}
+}
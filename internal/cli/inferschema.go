@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+	"github.com/walkerscm/scaleSyncGo/internal/worker"
+)
+
+var inferSchemaCmd = &cobra.Command{
+	Use:   "infer-schema",
+	Short: "Print a CREATE TABLE statement for an input file",
+	Long: `Sample the rows of an input file, infer a column type for each of its
+columns and print the CREATE TABLE statement that would hold them. Nothing
+is written; see import --create-table.`,
+	RunE: runInferSchema,
+}
+
+func init() {
+	inferSchemaCmd.Flags().String("file", "", "path to input file")
+	inferSchemaCmd.Flags().String("sheet", "", "Excel: sheet to read from --file, by name or 1-based position")
+	inferSchemaCmd.Flags().String("table", "", "table name for the statement as schema.name (default: the file's name)")
+	inferSchemaCmd.Flags().String("driver", database.DriverSQLServer, "database to write the statement for: sqlserver or postgres")
+	addSchemaFlags(inferSchemaCmd)
+	addInputFlags(inferSchemaCmd)
+	rootCmd.AddCommand(inferSchemaCmd)
+}
+
+// addSchemaFlags adds the flags controlling schema inference.
+func addSchemaFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("primary-key", nil, "columns of the new table's primary key")
+	cmd.Flags().Int("sample-rows", 10000, "rows to sample when inferring column types (0 for all)")
+}
+
+// schemaResult is the machine-readable output of infer-schema.
+type schemaResult struct {
+	File    string         `json:"file"`
+	Table   string         `json:"table"`
+	Sampled int            `json:"rows_sampled"`
+	Columns []schemaColumn `json:"columns"`
+	DDL     string         `json:"ddl"`
+}
+
+// schemaColumn is one inferred column.
+type schemaColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+func runInferSchema(cmd *cobra.Command, args []string) error {
+	filePath, _ := cmd.Flags().GetString("file")
+	sheet, _ := cmd.Flags().GetString("sheet")
+	tableName, _ := cmd.Flags().GetString("table")
+	driver, _ := cmd.Flags().GetString("driver")
+	pkColumns, _ := cmd.Flags().GetStringSlice("primary-key")
+	sampleRows, _ := cmd.Flags().GetInt("sample-rows")
+
+	if filePath == "" {
+		return fmt.Errorf("--file is required")
+	}
+	if _, err := os.Stat(csvutil.SourcePath(filePath)); err != nil {
+		return fmt.Errorf("input file not found: %s", filePath)
+	}
+	defaultSchema := "dbo"
+	switch driver {
+	case database.DriverSQLServer:
+	case database.DriverPostgres:
+		defaultSchema = "public"
+	default:
+		return fmt.Errorf("unknown --driver %q (valid: %s, %s)", driver, database.DriverSQLServer, database.DriverPostgres)
+	}
+	dialect, err := dialectFlags(cmd)
+	if err != nil {
+		return err
+	}
+	opts := importOptions{Dialect: dialect}
+	opts.KeepJSON, _ = cmd.Flags().GetStringSlice("keep-json")
+	opts.HeaderRow, _ = cmd.Flags().GetInt("header-row")
+
+	path := filePath
+	if csvutil.IsWorkbook(filePath) {
+		if path, err = selectSheet(filePath, sheet, true); err != nil {
+			return err
+		}
+	} else if sheet != "" {
+		return fmt.Errorf("--sheet needs --file naming an .xlsx workbook")
+	}
+	if tableName == "" {
+		name := csvutil.LogicalName(path)
+		tableName = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	schemaTable := qualifyTable(tableName, defaultSchema)
+
+	cols, sampled, err := inferColumns(path, schemaTable, opts, sampleRows, pkColumns)
+	if err != nil {
+		return err
+	}
+	ddl := database.CreateTableSQL(driver, schemaTable, cols, pkColumns)
+
+	if jsonOutput() {
+		res := schemaResult{File: path, Table: schemaTable, Sampled: sampled, DDL: ddl}
+		for _, c := range cols {
+			res.Columns = append(res.Columns, schemaColumn{Name: c.Name, Type: database.ColumnType(driver, c), Nullable: c.IsNullable})
+		}
+		return printJSON(res)
+	}
+	fmt.Fprintf(os.Stderr, "Inferred from %d sampled rows of %s\n", sampled, csvutil.DisplayName(path))
+	fmt.Println(ddl)
+	return nil
+}
+
+// qualifyTable prefixes schema to a table name that has none.
+func qualifyTable(name, schema string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+	return schema + "." + name
+}
+
+// inferColumns reads up to sampleRows rows of the input file at path (all
+// of them if sampleRows is 0) and returns the columns inferred from them,
+// along with the number of rows read. pkColumns must name columns of the
+// file; they come out NOT NULL, and are respelled in place as the file's
+// headers.
+func inferColumns(path, schemaTable string, opts importOptions, sampleRows int, pkColumns []string) ([]database.TableColumn, int, error) {
+	reader, err := csvutil.Open(path, csvutil.Options{
+		Dialect:   dialectFor(schemaTable, opts.Dialect),
+		KeepJSON:  opts.KeepJSON,
+		HeaderRow: opts.HeaderRow,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("opening %s: %w", csvutil.DisplayName(path), err)
+	}
+	defer reader.Close()
+
+	headers := reader.Headers()
+	seen := make(map[string]string, len(headers))
+	for i, h := range headers {
+		if strings.TrimSpace(h) == "" {
+			return nil, 0, fmt.Errorf("column %d of %s has no name", i+1, csvutil.DisplayName(path))
+		}
+		if _, dup := seen[strings.ToUpper(h)]; dup {
+			return nil, 0, fmt.Errorf("%s has more than one column named %q", csvutil.DisplayName(path), h)
+		}
+		seen[strings.ToUpper(h)] = h
+	}
+	for i, pk := range pkColumns {
+		h, ok := seen[strings.ToUpper(pk)]
+		if !ok {
+			return nil, 0, fmt.Errorf("--primary-key: %s has no column %q", csvutil.DisplayName(path), pk)
+		}
+		pkColumns[i] = h
+	}
+
+	inf := worker.NewSchemaInferrer(headers)
+	sampled := 0
+	for sampleRows == 0 || sampled < sampleRows {
+		n := 1000
+		if sampleRows > 0 {
+			n = min(n, sampleRows-sampled)
+		}
+		batch, err := reader.ReadRows(n)
+		if batch.Values != nil {
+			inf.AddValues(batch.Values)
+		} else {
+			inf.Add(batch.Text)
+		}
+		sampled += batch.Len()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return inf.Columns(pkColumns), sampled, nil
+}
+
+// createTable creates schemaTable for the input file at path, with types
+// inferred from its first opts.SampleRows rows, unless the table already
+// exists. The statement is printed on w and, unless autoConfirm, confirmed
+// at a prompt. It returns false if the user declined.
+func createTable(ctx context.Context, dest database.Destination, path, schemaTable string, opts importOptions, pkColumns []string, autoConfirm bool, w io.Writer) (bool, error) {
+	existing, err := dest.GetTableColumns(ctx, schemaTable)
+	if err != nil {
+		return false, fmt.Errorf("getting table columns: %w", err)
+	}
+	if len(existing) > 0 {
+		fmt.Fprintf(w, "Table %s already exists; importing into it\n", schemaTable)
+		return true, nil
+	}
+
+	cols, sampled, err := inferColumns(path, schemaTable, opts, opts.SampleRows, pkColumns)
+	if err != nil {
+		return false, err
+	}
+	ddl := database.CreateTableSQL(dest.Driver(), schemaTable, cols, pkColumns)
+	fmt.Fprintf(w, "Table %s does not exist. Inferred from %d sampled rows:\n\n%s\n\n", schemaTable, sampled, ddl)
+
+	if !autoConfirm {
+		confirmPrompt := promptui.Prompt{
+			Label:     fmt.Sprintf("Create %s", schemaTable),
+			IsConfirm: true,
+			Stdout:    promptOut(),
+		}
+		if _, err := confirmPrompt.Run(); err != nil {
+			return false, nil
+		}
+	}
+
+	logger.Info("creating table", "table", schemaTable, "sql", ddl)
+	if _, err := dest.DB().ExecContext(ctx, ddl); err != nil {
+		return false, fmt.Errorf("creating %s: %w", schemaTable, err)
+	}
+	fmt.Fprintf(w, "Created %s\n", schemaTable)
+	return true, nil
+}
+
+// evolveSchema adds a nullable column to schemaTable for each header of the
+// input file at path in skipped, typed from the first opts.SampleRows rows.
+// The statements are printed on w, and with opts.DryRun not run. It reports
+// whether columns were added.
+func evolveSchema(ctx context.Context, dest database.Destination, path, schemaTable string, skipped []string, opts importOptions, w io.Writer) (bool, error) {
+	cols, _, err := inferColumns(path, schemaTable, opts, opts.SampleRows, nil)
+	if err != nil {
+		return false, err
+	}
+	want := make(map[string]bool, len(skipped))
+	for _, h := range skipped {
+		want[strings.ToUpper(h)] = true
+	}
+	var add []database.TableColumn
+	var names []string
+	for _, c := range cols {
+		if want[strings.ToUpper(c.Name)] {
+			add = append(add, c)
+			names = append(names, c.Name)
+		}
+	}
+	if len(add) == 0 {
+		return false, nil
+	}
+
+	stmts := database.AddColumnsSQL(dest.Driver(), schemaTable, add)
+	if opts.DryRun {
+		fmt.Fprintf(w, "Would add %d column(s) to %s for new headers:\n", len(add), schemaTable)
+	} else {
+		fmt.Fprintf(w, "Adding %d column(s) to %s for new headers:\n", len(add), schemaTable)
+	}
+	for _, stmt := range stmts {
+		fmt.Fprintf(w, "  %s\n", stmt)
+	}
+	if opts.DryRun {
+		return false, nil
+	}
+
+	tx, err := dest.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return false, fmt.Errorf("adding columns to %s: %w", schemaTable, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	logger.Info("columns added", "table", schemaTable, "columns", names)
+	return true, nil
+}
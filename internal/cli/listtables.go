@@ -41,16 +41,18 @@ func runListTables(cmd *cobra.Command, args []string) error {
 			fmt.Sprintf("tables-report-%s.md", time.Now().Format("20060102-150405")))
 	}
 
+	out := humanOut()
+
 	dbCfg, err := config.LoadDatabaseConfig(envPath, target)
 	if err != nil {
 		return err
 	}
 
-	db, err := database.NewConnection(dbCfg.ConnectionString())
+	dest, err := database.Open(dbCfg.Driver, dbCfg.ConnectionString(), logger)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
+	defer dest.Close()
 
 	ctx := context.Background()
 
@@ -60,7 +62,7 @@ func runListTables(cmd *cobra.Command, args []string) error {
 	}
 
 	if showCounts {
-		results, err := database.ListTablesWithCounts(ctx, db)
+		results, err := dest.ListTablesWithCounts(ctx)
 		if err != nil {
 			return err
 		}
@@ -74,8 +76,18 @@ func runListTables(cmd *cobra.Command, args []string) error {
 		}
 
 		// Print to stdout
-		for _, r := range results {
-			fmt.Printf("%-*s  %d\n", maxLen, r.Name, r.RowCount)
+		if jsonOutput() {
+			entries := make([]tableEntry, len(results))
+			for i, r := range results {
+				entries[i] = tableEntry{Name: r.Name, RowCount: &results[i].RowCount}
+			}
+			if err := printJSON(tableList{Tables: entries}); err != nil {
+				return err
+			}
+		} else {
+			for _, r := range results {
+				fmt.Printf("%-*s  %d\n", maxLen, r.Name, r.RowCount)
+			}
 		}
 
 		// Write markdown if requested
@@ -83,21 +95,39 @@ func runListTables(cmd *cobra.Command, args []string) error {
 			if err := writeMarkdown(mdPath, dbCfg, results); err != nil {
 				return err
 			}
-			fmt.Printf("\nMarkdown written to %s\n", mdPath)
+			fmt.Fprintf(out, "\nMarkdown written to %s\n", mdPath)
 		}
 		return nil
 	}
 
-	tables, err := database.ListTables(ctx, db)
+	tables, err := dest.ListTables(ctx)
 	if err != nil {
 		return err
 	}
+	if jsonOutput() {
+		entries := make([]tableEntry, len(tables))
+		for i, t := range tables {
+			entries[i] = tableEntry{Name: t}
+		}
+		return printJSON(tableList{Tables: entries})
+	}
 	for _, t := range tables {
 		fmt.Println(t)
 	}
 	return nil
 }
 
+// tableList is the machine-readable output of list-tables.
+type tableList struct {
+	Tables []tableEntry `json:"tables"`
+}
+
+// tableEntry is one table; RowCount is only present with --counts.
+type tableEntry struct {
+	Name     string `json:"name"`
+	RowCount *int64 `json:"row_count,omitempty"`
+}
+
 func writeMarkdown(path string, dbCfg *config.DatabaseConfig, results []database.TableRowCount) error {
 	if dir := filepath.Dir(path); dir != "." {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
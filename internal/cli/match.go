@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/walkerscm/scaleSyncGo/internal/config"
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+	"github.com/walkerscm/scaleSyncGo/internal/filematch"
+)
+
+// csvMatch pairs a CSV file path with its resolved database table name.
+type csvMatch struct {
+	Path        string
+	TableName   string // e.g. "dbo.SHIPPING_CONTAINER"
+	BaseName    string // original filename
+	ExtractedID string // table portion extracted from filename
+	Rule        string // match rule that resolved the table
+	Timestamp   string // timestamp from the filename; orders files for one table
+}
+
+// tableMatcher resolves files in csv_input/ to destination tables with the
+// configured match rules.
+type tableMatcher struct {
+	dest   database.Destination
+	rules  *filematch.Matcher
+	lookup map[string]string // lowercase name → name, e.g. "dbo.orders" → "dbo.ORDERS"
+}
+
+// compileMatchRules compiles the match_rules from config.yaml.
+func compileMatchRules() (*filematch.Matcher, error) {
+	var rules []config.MatchRule
+	if cfg != nil {
+		rules = cfg.MatchRules
+	}
+	compiled, err := filematch.Compile(rules)
+	if err != nil {
+		return nil, fmt.Errorf("match_rules: %w", err)
+	}
+	return compiled, nil
+}
+
+func newTableMatcher(ctx context.Context, dest database.Destination, rules *filematch.Matcher) (*tableMatcher, error) {
+	t := &tableMatcher{dest: dest, rules: rules}
+	if err := t.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// refresh reloads the destination's table list.
+func (t *tableMatcher) refresh(ctx context.Context) error {
+	tables, err := t.dest.ListTables(ctx)
+	if err != nil {
+		return fmt.Errorf("listing tables: %w", err)
+	}
+	t.lookup = make(map[string]string, len(tables))
+	for _, name := range tables {
+		t.lookup[strings.ToLower(name)] = name
+	}
+	return nil
+}
+
+// scanInput lists the files in csv_input/ with list (csvutil.ScanDirectory
+// or csvutil.ListFiles), including its subdirectories when a rule takes the
+// schema from the directory name.
+func scanInput(rules *filematch.Matcher, list func(dir string) ([]string, error)) ([]string, error) {
+	dirs := []string{inputDir}
+	if rules.UsesDirectories() {
+		entries, err := os.ReadDir(inputDir)
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", inputDir, err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, filepath.Join(inputDir, e.Name()))
+			}
+		}
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		found, err := list(dir)
+		if err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", dir, err)
+		}
+		files = append(files, found...)
+	}
+	return files, nil
+}
+
+// match resolves the table csvPath loads into. If there is none it returns
+// a description of the unmatched file instead; the returned csvMatch still
+// carries the rule and table name tried, if any.
+func (t *tableMatcher) match(csvPath string) (csvMatch, string) {
+	rel := relInput(csvPath)
+	m := csvMatch{Path: csvPath, BaseName: csvutil.DisplayName(csvPath)}
+	// Rules see the name of the CSV itself, not of its archive or
+	// compressed file.
+	name := filepath.Join(filepath.Dir(relInput(csvutil.SourcePath(csvPath))), csvutil.LogicalName(csvPath))
+	res, ok := t.rules.Match(name)
+	if !ok {
+		return m, rel + " (no rule matched)"
+	}
+	m.Rule = res.Rule
+	m.ExtractedID = res.Table
+	m.Timestamp = res.Timestamp
+
+	qualified := res.QualifiedName(t.dest.DefaultSchema())
+	fullName, ok := t.lookup[strings.ToLower(qualified)]
+	if !ok {
+		m.TableName = qualified
+		return m, rel + " (no table: " + qualified + ")"
+	}
+	m.TableName = fullName
+	return m, ""
+}
+
+// relInput returns path relative to csv_input/.
+func relInput(path string) string {
+	rel, err := filepath.Rel(inputDir, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+	return rel
+}
+
+// explainEntry is the machine-readable outcome of matching one file.
+type explainEntry struct {
+	File      string `json:"file"`
+	Rule      string `json:"rule,omitempty"`
+	Table     string `json:"table,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Matched   bool   `json:"matched"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// explainMatches prints which rule matched each file and the table it
+// resolved to, without importing anything.
+func explainMatches(t *tableMatcher, files []string, out io.Writer) error {
+	entries := make([]explainEntry, 0, len(files))
+	for _, path := range files {
+		m, reason := t.match(path)
+		e := explainEntry{File: relInput(path), Rule: m.Rule, Table: m.TableName, Timestamp: m.Timestamp, Matched: reason == ""}
+		switch {
+		case e.Matched:
+			fmt.Fprintf(out, "  %s\n      rule %q → %s\n", e.File, e.Rule, e.Table)
+		case m.Rule == "":
+			e.Reason = "no rule matched"
+			fmt.Fprintf(out, "  %s\n      no rule matched\n", e.File)
+		default:
+			e.Reason = "table not found"
+			fmt.Fprintf(out, "  %s\n      rule %q → %s (table not found)\n", e.File, e.Rule, e.Table)
+		}
+		entries = append(entries, e)
+	}
+	if jsonOutput() {
+		return printJSON(struct {
+			Files []explainEntry `json:"files"`
+		}{entries})
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Output formats accepted by --output.
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// Exit codes set by Execute. Any other error exits with exitFailure.
+const (
+	exitFailure = 1 // nothing (or nothing usable) was done
+	exitPartial = 2 // the command ran but some rows or files failed
+)
+
+// exitError carries a specific exit code out of a command's RunE.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// partialFailure marks err as a partial failure, exiting with exitPartial.
+func partialFailure(err error) error {
+	return &exitError{code: exitPartial, err: err}
+}
+
+// jsonOutput reports whether --output json is in effect.
+func jsonOutput() bool {
+	return cfg != nil && cfg.Output == outputJSON
+}
+
+// humanOut returns where progress, prompts and prose summaries go: stdout
+// for text output, stderr for JSON output so stdout carries only the JSON
+// document.
+func humanOut() io.Writer {
+	if jsonOutput() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// promptOut returns the writer for interactive prompts; nil means the
+// prompt's default (stdout).
+func promptOut() io.WriteCloser {
+	if jsonOutput() {
+		return os.Stderr
+	}
+	return nil
+}
+
+// printJSON writes v to stdout as a single indented JSON document.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("writing JSON output: %w", err)
+	}
+	return nil
+}
+
+// fileResult is the machine-readable outcome of importing one file.
+type fileResult struct {
+	importStats
+	// Status is "ok", "partial" (some rows or batches failed), "failed", or
+	// "skipped" (process left it alone because an earlier file for the same
+	// table failed).
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// MovedTo is where process moved the file, if it did.
+	MovedTo string `json:"moved_to,omitempty"`
+}
+
+// newFileResult classifies the outcome of importFile.
+func newFileResult(stats importStats, err error) fileResult {
+	r := fileResult{importStats: stats, Status: "ok"}
+	switch {
+	case err != nil && (stats.Inserted == 0 || stats.Mode == modeMirror):
+		// A mirror that errors changes nothing in the target.
+		r.Status = "failed"
+	case err != nil, stats.Rejected > 0:
+		r.Status = "partial"
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// exitErr returns the error the command should fail with for this result,
+// given the error importFile returned: nil when ok, a partial failure when
+// some rows loaded, else err itself.
+func (r fileResult) exitErr(err error) error {
+	switch r.Status {
+	case "failed":
+		return err
+	case "partial":
+		if err == nil {
+			err = fmt.Errorf("%d row(s) rejected, see %s", r.Rejected, r.RejectsPath)
+		}
+		return partialFailure(err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewFileResultStatus(t *testing.T) {
+	batchErr := errors.New("2 batch errors during import")
+
+	tests := []struct {
+		name  string
+		stats importStats
+		err   error
+		want  string
+		code  int
+	}{
+		{"clean", importStats{Mode: modeUpsert, Inserted: 10}, nil, "ok", 0},
+		{"rejects", importStats{Mode: modeUpsert, Inserted: 9, Rejected: 1}, nil, "partial", exitPartial},
+		{"some batches failed", importStats{Mode: modeUpsert, Inserted: 5}, batchErr, "partial", exitPartial},
+		{"nothing loaded", importStats{Mode: modeUpsert}, batchErr, "failed", exitFailure},
+		{"mirror not applied", importStats{Mode: modeMirror, Inserted: 10}, batchErr, "failed", exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newFileResult(tt.stats, tt.err)
+			if r.Status != tt.want {
+				t.Fatalf("status = %q, want %q", r.Status, tt.want)
+			}
+
+			err := r.exitErr(tt.err)
+			code := 0
+			if err != nil {
+				code = exitFailure
+				var ee *exitError
+				if errors.As(err, &ee) {
+					code = ee.code
+				}
+			}
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+		})
+	}
+}
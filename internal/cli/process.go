@@ -1,11 +1,15 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
@@ -21,9 +25,11 @@ const (
 
 var processCmd = &cobra.Command{
 	Use:   "process",
-	Short: "Batch-process all CSV files in csv_input/",
-	Long:  `Scan csv_input/ for CSV files, match each to a database table by filename, import them sequentially, and move completed files to csv_processed/.`,
-	RunE:  runProcess,
+	Short: "Batch-process all input files in csv_input/",
+	Long: `Scan csv_input/ for CSV, Parquet, JSON Lines and Excel files (each sheet of a workbook counts as a file), match each to a database table by filename, import them sequentially, and move completed files to csv_processed/.
+
+With --watch, keep running and import each new file once it is complete: when its size has not changed for --settle, or as soon as a <file>.done marker appears. SIGINT or SIGTERM stops watching after the current file finishes; a second signal aborts it.`,
+	RunE: runProcess,
 }
 
 func init() {
@@ -31,59 +37,136 @@ func init() {
 	processCmd.Flags().Int("batch-size", 1000, "rows per batch")
 	processCmd.Flags().Int("workers", 4, "parallel worker count")
 	processCmd.Flags().BoolP("yes", "y", false, "skip confirmation prompt")
+	processCmd.Flags().Bool("resume", false, "skip batches committed by an earlier interrupted run of each file")
+	processCmd.Flags().String("state-dir", "", "directory for import checkpoints (default: next to each CSV)")
+	processCmd.Flags().Bool("dry-run", false, "validate every matched file against its table without writing or moving anything")
+	processCmd.Flags().String("mode", modeUpsert, "upsert, or mirror to also delete rows missing from the file")
+	processCmd.Flags().String("scope", "", "with --mode mirror, only delete rows matching this SQL predicate")
+	processCmd.Flags().Float64("max-delete-pct", 10, "with --mode mirror, abort if more than this % of rows in scope would be deleted")
+	processCmd.Flags().String("oversize", database.OversizeError, "values too long or precise for their column: error (reject the row), truncate or null")
+	processCmd.Flags().Bool("evolve-schema", false, "add a nullable column to the table for each file header it lacks, typed from the data")
+	processCmd.Flags().Bool("fail-on-skipped", false, "fail a file that has headers with no table column instead of skipping them")
+	processCmd.Flags().Int("sample-rows", 10000, "rows to sample when inferring column types (0 for all)")
+	processCmd.Flags().Bool("explain", false, "show which match rule and table each file resolves to, then exit")
+	processCmd.Flags().Int("parallel-files", 1, "import up to this many files at once; files for the same table still run one at a time")
+	processCmd.Flags().Bool("watch", false, "keep running and import files as they arrive in csv_input/")
+	processCmd.Flags().Duration("settle", 10*time.Second, "with --watch, import a file once its size has not changed for this long (0: only when a <file>.done marker appears)")
+	addInputFlags(processCmd)
 	rootCmd.AddCommand(processCmd)
 }
 
-// csvMatch pairs a CSV file path with its resolved database table name.
-type csvMatch struct {
-	Path        string
-	TableName   string // e.g. "dbo.SHIPPING_CONTAINER"
-	BaseName    string // original filename
-	ExtractedID string // table portion extracted from filename
-}
-
 func runProcess(cmd *cobra.Command, args []string) error {
 	envPath, _ := cmd.Flags().GetString("env")
 	target, _ := cmd.Flags().GetString("target")
 	batchSize, _ := cmd.Flags().GetInt("batch-size")
 	workers, _ := cmd.Flags().GetInt("workers")
 	autoConfirm, _ := cmd.Flags().GetBool("yes")
+	resume, _ := cmd.Flags().GetBool("resume")
+	stateDir, _ := cmd.Flags().GetString("state-dir")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	mode, _ := cmd.Flags().GetString("mode")
+	scope, _ := cmd.Flags().GetString("scope")
+	maxDeletePct, _ := cmd.Flags().GetFloat64("max-delete-pct")
+	oversize, _ := cmd.Flags().GetString("oversize")
+	evolve, _ := cmd.Flags().GetBool("evolve-schema")
+	failOnSkipped, _ := cmd.Flags().GetBool("fail-on-skipped")
+	watch, _ := cmd.Flags().GetBool("watch")
+	settle, _ := cmd.Flags().GetDuration("settle")
+	parallelFiles, _ := cmd.Flags().GetInt("parallel-files")
+	explain, _ := cmd.Flags().GetBool("explain")
 
-	// 1. Scan csv_input/
-	csvFiles, err := csvutil.ScanDirectory(inputDir)
+	opts := importOptions{
+		BatchSize:    batchSize,
+		Workers:      workers,
+		Resume:       resume,
+		StateDir:     stateDir,
+		Mode:         mode,
+		Scope:        scope,
+		MaxDeletePct: maxDeletePct,
+		Oversize:     oversize,
+	}
+	opts.EvolveSchema, opts.FailOnSkipped = evolve, failOnSkipped
+	opts.SampleRows, _ = cmd.Flags().GetInt("sample-rows")
+	if err := checkMode(opts); err != nil {
+		return err
+	}
+	if evolve && failOnSkipped {
+		return fmt.Errorf("--evolve-schema and --fail-on-skipped cannot be combined")
+	}
+	if err := database.CheckOversize(oversize); err != nil {
+		return fmt.Errorf("--oversize: %w", err)
+	}
+	dialect, err := dialectFlags(cmd)
 	if err != nil {
-		return fmt.Errorf("scanning %s: %w", inputDir, err)
+		return err
 	}
-	if len(csvFiles) == 0 {
-		fmt.Printf("No CSV files found in %s/\n", inputDir)
-		return nil
+	opts.Dialect = dialect
+	opts.KeepJSON, _ = cmd.Flags().GetStringSlice("keep-json")
+	opts.HeaderRow, _ = cmd.Flags().GetInt("header-row")
+	if parallelFiles < 1 {
+		return fmt.Errorf("--parallel-files must be at least 1")
+	}
+	rules, err := compileMatchRules()
+	if err != nil {
+		return err
 	}
-	fmt.Printf("Found %d CSV file(s) in %s/\n", len(csvFiles), inputDir)
+	out := humanOut()
 
-	// 2. Connect to database
-	dbCfg, err := config.LoadDatabaseConfig(envPath, target)
+	if watch {
+		if dryRun {
+			return fmt.Errorf("--watch cannot be combined with --dry-run")
+		}
+		if parallelFiles > 1 {
+			return fmt.Errorf("--watch cannot be combined with --parallel-files")
+		}
+		if settle < 0 {
+			return fmt.Errorf("--settle must not be negative")
+		}
+		dest, err := connectTarget(envPath, target, out)
+		if err != nil {
+			return err
+		}
+		defer dest.Close()
+		return watchInput(dest, rules, opts, settle, out)
+	}
+
+	report := processReport{Files: []fileResult{}, Unmatched: []string{}}
+	finish := func(err error) error {
+		if jsonOutput() {
+			if jerr := printJSON(report); jerr != nil {
+				return jerr
+			}
+		}
+		return err
+	}
+
+	// 1. Scan csv_input/
+	csvFiles, err := scanInput(rules, csvutil.ScanDirectory)
 	if err != nil {
-		return fmt.Errorf("loading database config: %w", err)
+		return err
+	}
+	if len(csvFiles) == 0 {
+		fmt.Fprintf(out, "No CSV files found in %s/\n", inputDir)
+		return finish(nil)
 	}
+	fmt.Fprintf(out, "Found %d CSV file(s) in %s/\n", len(csvFiles), inputDir)
 
-	fmt.Printf("Connecting to %s/%s...\n", dbCfg.Server, dbCfg.Database)
-	db, err := database.NewConnection(dbCfg.ConnectionString())
+	// 2. Connect to database
+	dest, err := connectTarget(envPath, target, out)
 	if err != nil {
-		return fmt.Errorf("connecting to database: %w", err)
+		return err
 	}
-	defer db.Close()
-	fmt.Println("Connected.")
+	defer dest.Close()
 
 	ctx := context.Background()
-	tables, err := database.ListTables(ctx, db)
+	tables, err := newTableMatcher(ctx, dest, rules)
 	if err != nil {
-		return fmt.Errorf("listing tables: %w", err)
+		return err
 	}
 
-	// Build lowercase lookup: "dbo.shipping_container" → "dbo.SHIPPING_CONTAINER"
-	tableLookup := make(map[string]string, len(tables))
-	for _, t := range tables {
-		tableLookup[strings.ToLower(t)] = t
+	if explain {
+		fmt.Fprintln(out)
+		return explainMatches(tables, csvFiles, out)
 	}
 
 	// 3. Match each CSV file to a table
@@ -91,44 +174,37 @@ func runProcess(cmd *cobra.Command, args []string) error {
 	var unmatched []string
 
 	for _, csvPath := range csvFiles {
-		baseName := filepath.Base(csvPath)
-		tablePart := extractTableName(baseName)
-		if tablePart == "" {
-			unmatched = append(unmatched, baseName)
+		m, reason := tables.match(csvPath)
+		if reason != "" {
+			unmatched = append(unmatched, reason)
 			continue
 		}
-
-		// Look up as dbo.<TABLE_NAME>
-		key := strings.ToLower("dbo." + tablePart)
-		if fullName, ok := tableLookup[key]; ok {
-			matched = append(matched, csvMatch{
-				Path:        csvPath,
-				TableName:   fullName,
-				BaseName:    baseName,
-				ExtractedID: tablePart,
-			})
-		} else {
-			unmatched = append(unmatched, baseName+" (no table: dbo."+tablePart+")")
-		}
+		matched = append(matched, m)
 	}
 
+	report.Unmatched = append(report.Unmatched, unmatched...)
+
 	// 4. Print summary
-	fmt.Println()
-	fmt.Printf("Matched:   %d file(s)\n", len(matched))
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "Matched:   %d file(s)\n", len(matched))
 	for _, m := range matched {
-		fmt.Printf("  %s → %s\n", m.BaseName, m.TableName)
+		fmt.Fprintf(out, "  %s → %s\n", m.BaseName, m.TableName)
 	}
 	if len(unmatched) > 0 {
-		fmt.Printf("Unmatched: %d file(s)\n", len(unmatched))
+		fmt.Fprintf(out, "Unmatched: %d file(s)\n", len(unmatched))
 		for _, u := range unmatched {
-			fmt.Printf("  %s\n", u)
+			fmt.Fprintf(out, "  %s\n", u)
 		}
 	}
-	fmt.Println()
+	fmt.Fprintln(out)
 
 	if len(matched) == 0 {
-		fmt.Println("No files matched any database table. Nothing to do.")
-		return nil
+		fmt.Fprintln(out, "No files matched any database table. Nothing to do.")
+		return finish(nil)
+	}
+
+	if dryRun {
+		return validateMatches(ctx, dest, matched, opts, out)
 	}
 
 	// 5. Confirm
@@ -136,10 +212,11 @@ func runProcess(cmd *cobra.Command, args []string) error {
 		confirmPrompt := promptui.Prompt{
 			Label:     fmt.Sprintf("Process %d file(s)", len(matched)),
 			IsConfirm: true,
+			Stdout:    promptOut(),
 		}
 		if _, err := confirmPrompt.Run(); err != nil {
-			fmt.Println("Processing cancelled.")
-			return nil
+			fmt.Fprintln(out, "Processing cancelled.")
+			return finish(nil)
 		}
 	}
 
@@ -148,52 +225,305 @@ func runProcess(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("creating %s directory: %w", processedDir, err)
 	}
 
-	// 6. Process each matched file sequentially
-	var succeeded, failed, totalRows int
+	// 6. Import the matched files, each table's files in timestamp order
+	partial := importMatches(ctx, dest, matched, opts, parallelFiles, &report, out)
+	moveArchives(csvFiles, report.Files, out)
 
-	for i, m := range matched {
-		fmt.Printf("\n[%d/%d] Processing %s → %s...\n", i+1, len(matched), m.BaseName, m.TableName)
+	// 7. Final summary
+	fmt.Fprintln(out, "\n=== Process Summary ===")
+	fmt.Fprintf(out, "Files processed: %d\n", len(matched))
+	fmt.Fprintf(out, "Succeeded:       %d\n", report.Succeeded)
+	fmt.Fprintf(out, "Failed:          %d\n", report.Failed)
+	fmt.Fprintf(out, "Total rows:      %d\n", report.TotalRows)
+	fmt.Fprintf(out, "Rejected rows:   %d\n", report.RejectedRows)
+	logger.Info("process finished", "files", len(matched), "succeeded", report.Succeeded,
+		"failed", report.Failed, "rows", report.TotalRows, "rejected", report.RejectedRows)
 
-		rows, err := importFile(ctx, db, m.Path, m.TableName, batchSize, workers, os.Stdout)
-		if err != nil {
-			fmt.Printf("ERROR: %s: %v\n", m.BaseName, err)
-			failed++
+	switch {
+	case report.Failed == len(matched):
+		return finish(fmt.Errorf("all %d file(s) failed", report.Failed))
+	case report.Failed > 0 || partial > 0:
+		return finish(partialFailure(fmt.Errorf("%d of %d file(s) failed, %d row(s) rejected",
+			report.Failed, len(matched), report.RejectedRows)))
+	}
+	return finish(nil)
+}
+
+// connectTarget opens the destination configured in envPath for target.
+func connectTarget(envPath, target string, out io.Writer) (database.Destination, error) {
+	dbCfg, err := config.LoadDatabaseConfig(envPath, target)
+	if err != nil {
+		return nil, fmt.Errorf("loading database config: %w", err)
+	}
+
+	fmt.Fprintf(out, "Connecting to %s/%s...\n", dbCfg.Server, dbCfg.Database)
+	dest, err := database.Open(dbCfg.Driver, dbCfg.ConnectionString(), logger)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to database: %w", err)
+	}
+	fmt.Fprintln(out, "Connected.")
+	return dest, nil
+}
+
+// importMatch imports one matched file and, unless the import returned an
+// error, moves it and its rejects file (if any) to csv_processed/. Members of
+// zip archives and sheets of workbooks are left for moveArchives.
+func importMatch(ctx context.Context, dest database.Destination, m csvMatch, opts importOptions, out io.Writer) (fileResult, error) {
+	stats, err := importFile(ctx, dest, m.Path, m.TableName, opts, out)
+	res := newFileResult(stats, err)
+	if err != nil {
+		fmt.Fprintf(out, "ERROR: %s: %v\n", m.BaseName, err)
+		return res, err
+	}
+	if _, _, ok := csvutil.SplitMember(m.Path); ok {
+		return res, nil
+	}
+
+	if destPath, ok := moveProcessed(m.Path, out); ok {
+		res.MovedTo = destPath
+	}
+	if stats.RejectsPath != "" {
+		moveProcessed(stats.RejectsPath, out)
+	}
+	return res, nil
+}
+
+// moveArchives moves each zip archive or workbook that files were expanded
+// from to csv_processed/ once every one of its CSV members or sheets imported
+// without error, together with their rejects files. An archive with an
+// unmatched or failed member stays in csv_input/ so it can be retried as a
+// whole.
+func moveArchives(files []string, results []fileResult, out io.Writer) {
+	var archives []string
+	members := make(map[string][]string)
+	for _, f := range files {
+		archive, _, ok := csvutil.SplitMember(f)
+		if !ok {
 			continue
 		}
+		if _, seen := members[archive]; !seen {
+			archives = append(archives, archive)
+		}
+		members[archive] = append(members[archive], f)
+	}
 
-		totalRows += rows
-		succeeded++
+	index := make(map[string]int, len(results))
+	for i, r := range results {
+		index[r.File] = i
+	}
+	for _, archive := range archives {
+		complete := true
+		for _, f := range members[archive] {
+			if i, ok := index[f]; !ok || results[i].Error != "" {
+				complete = false
+				break
+			}
+		}
+		if !complete {
+			fmt.Fprintf(out, "Leaving %s in %s/: not every file in it was imported\n", filepath.Base(archive), inputDir)
+			logger.Warn("archive not moved", "file", filepath.Base(archive))
+			continue
+		}
 
-		// Move file to csv_processed/
-		destPath := filepath.Join(processedDir, m.BaseName)
-		if err := os.Rename(m.Path, destPath); err != nil {
-			fmt.Printf("WARNING: imported OK but failed to move %s: %v\n", m.BaseName, err)
-		} else {
-			fmt.Printf("Moved %s → %s/\n", m.BaseName, processedDir)
+		destPath, moved := moveProcessed(archive, out)
+		for _, f := range members[archive] {
+			r := &results[index[f]]
+			if moved {
+				r.MovedTo = destPath
+			}
+			if r.RejectsPath != "" {
+				moveProcessed(r.RejectsPath, out)
+			}
 		}
 	}
+}
 
-	// 7. Final summary
-	fmt.Println("\n=== Process Summary ===")
-	fmt.Printf("Files processed: %d\n", len(matched))
-	fmt.Printf("Succeeded:       %d\n", succeeded)
-	fmt.Printf("Failed:          %d\n", failed)
-	fmt.Printf("Total rows:      %d\n", totalRows)
+// moveProcessed moves a file from csv_input/ to the same place under
+// csv_processed/, reporting a failure as a warning on out.
+func moveProcessed(path string, out io.Writer) (string, bool) {
+	name := filepath.Base(path)
+	destPath := filepath.Join(processedDir, relInput(path))
+	err := os.MkdirAll(filepath.Dir(destPath), 0o755)
+	if err == nil {
+		err = os.Rename(path, destPath)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "WARNING: failed to move %s: %v\n", name, err)
+		logger.Warn("file not moved", "file", name, "err", err)
+		return "", false
+	}
+	logger.Debug("file moved", "file", name, "to", destPath)
+	fmt.Fprintf(out, "Moved %s → %s/\n", name, filepath.Dir(destPath))
+	return destPath, true
+}
 
-	return nil
+// importMatches imports matched files, up to parallel at a time, adding each
+// outcome to report. Files for the same table run one after another, oldest
+// filename timestamp first; if one fails, the table's later files are left
+// in csv_input/ so a retry can't apply the older file over them. With
+// parallel > 1 each file's output is buffered and written in one piece when
+// it finishes. It returns the number of partially imported files.
+func importMatches(ctx context.Context, dest database.Destination, matched []csvMatch, opts importOptions, parallel int, report *processReport, out io.Writer) int {
+	var (
+		mu      sync.Mutex
+		started int
+		partial int
+	)
+	record := func(res fileResult, err error) {
+		report.TotalRows += res.Inserted
+		report.RejectedRows += res.Rejected
+		report.Files = append(report.Files, res)
+		if err != nil {
+			report.Failed++
+			return
+		}
+		report.Succeeded++
+		if res.Status == "partial" {
+			partial++
+		}
+	}
+
+	run := func(m csvMatch) error {
+		if parallel == 1 {
+			started++
+			fmt.Fprintf(out, "\n[%d/%d] Processing %s → %s...\n", started, len(matched), m.BaseName, m.TableName)
+			res, err := importMatch(ctx, dest, m, opts, out)
+			record(res, err)
+			return err
+		}
+
+		var buf bytes.Buffer
+		mu.Lock()
+		started++
+		fmt.Fprintf(out, "[%d/%d] Started %s → %s\n", started, len(matched), m.BaseName, m.TableName)
+		fmt.Fprintf(&buf, "\n[%d/%d] %s → %s:\n", started, len(matched), m.BaseName, m.TableName)
+		mu.Unlock()
+
+		fileOpts := opts
+		fileOpts.NoProgress = true
+		res, err := importMatch(ctx, dest, m, fileOpts, &buf)
+
+		mu.Lock()
+		defer mu.Unlock()
+		buf.WriteTo(out) //nolint:errcheck
+		record(res, err)
+		return err
+	}
+
+	groups := make(chan []csvMatch)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range groups {
+				for j, m := range group {
+					if run(m) == nil {
+						continue
+					}
+					mu.Lock()
+					for _, later := range group[j+1:] {
+						err := fmt.Errorf("not imported: earlier file %s for %s failed", m.BaseName, m.TableName)
+						fmt.Fprintf(out, "SKIPPED: %s: %v\n", later.BaseName, err)
+						logger.Warn("file skipped", "file", later.BaseName, "table", later.TableName, "after", m.BaseName)
+						res := fileResult{
+							importStats: importStats{
+								File:           later.Path,
+								Table:          later.TableName,
+								Mode:           opts.Mode,
+								SkippedColumns: []string{},
+								Errors:         []string{},
+							},
+							Status: "skipped",
+							Error:  err.Error(),
+						}
+						record(res, err)
+					}
+					mu.Unlock()
+					break
+				}
+			}
+		}()
+	}
+	for _, g := range groupByTable(matched) {
+		groups <- g
+	}
+	close(groups)
+	wg.Wait()
+	return partial
 }
 
-// extractTableName gets the table name from a filename like "TABLE_NAME_inserts_20260211_170255.csv".
-// It returns everything before "_inserts_", or empty string if the pattern is not found.
-func extractTableName(filename string) string {
-	// Remove extension
-	name := strings.TrimSuffix(filename, filepath.Ext(filename))
+// groupByTable splits matched into one group per table, in order of each
+// table's first file, with each group sorted by the timestamp its match
+// rule extracted from the filename.
+func groupByTable(matched []csvMatch) [][]csvMatch {
+	index := make(map[string]int)
+	var groups [][]csvMatch
+	for _, m := range matched {
+		i, ok := index[m.TableName]
+		if !ok {
+			i = len(groups)
+			index[m.TableName] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g, func(a, b int) bool {
+			return g[a].Timestamp < g[b].Timestamp
+		})
+	}
+	return groups
+}
 
-	// Find "_inserts_" (case-insensitive)
-	lower := strings.ToLower(name)
-	idx := strings.Index(lower, "_inserts_")
-	if idx < 0 {
-		return ""
+// processReport is the machine-readable outcome of a process run.
+type processReport struct {
+	Files        []fileResult `json:"files"`
+	Unmatched    []string     `json:"unmatched"`
+	Succeeded    int          `json:"succeeded"`
+	Failed       int          `json:"failed"`
+	TotalRows    int          `json:"total_rows"`
+	RejectedRows int          `json:"rejected_rows"`
+}
+
+// dryRunReport is the machine-readable outcome of process --dry-run.
+type dryRunReport struct {
+	Files  []validationResult `json:"files"`
+	Passed int                `json:"passed"`
+	Failed int                `json:"failed"`
+}
+
+// validateMatches dry-runs every matched file and returns an error if any of
+// them would fail to load. Files are left where they are.
+func validateMatches(ctx context.Context, dest database.Destination, matched []csvMatch, opts importOptions, out io.Writer) error {
+	var failed int
+	results := make([]validationResult, 0, len(matched))
+	for i, m := range matched {
+		fmt.Fprintf(out, "\n[%d/%d] Validating %s → %s...\n", i+1, len(matched), m.BaseName, m.TableName)
+		res, err := validateFile(ctx, dest, m.Path, m.TableName, opts, out)
+		if err != nil {
+			fmt.Fprintf(out, "ERROR: %s: %v\n", m.BaseName, err)
+			failed++
+		}
+		results = append(results, res)
+	}
+	if jsonOutput() {
+		if err := printJSON(dryRunReport{Files: results, Passed: len(matched) - failed, Failed: failed}); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out, "\n=== Dry Run Summary ===")
+	fmt.Fprintf(out, "Files checked: %d\n", len(matched))
+	fmt.Fprintf(out, "Passed:        %d\n", len(matched)-failed)
+	fmt.Fprintf(out, "Failed:        %d\n", failed)
+
+	if failed > 0 {
+		err := fmt.Errorf("dry run: %d of %d file(s) would fail", failed, len(matched))
+		if failed < len(matched) {
+			return partialFailure(err)
+		}
+		return err
 	}
-	return name[:idx]
+	return nil
 }
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupByTable(t *testing.T) {
+	matched := []csvMatch{
+		{BaseName: "ORDERS_inserts_20260211_170255.csv", TableName: "dbo.ORDERS", Timestamp: "20260211_170255"},
+		{BaseName: "Customers_inserts_20260212_080000.csv", TableName: "dbo.CUSTOMERS", Timestamp: "20260212_080000"},
+		{BaseName: "orders_inserts_20260210_090000.csv", TableName: "dbo.ORDERS", Timestamp: "20260210_090000"},
+		{BaseName: "CUSTOMERS_inserts_20260211_120000.csv", TableName: "dbo.CUSTOMERS", Timestamp: "20260211_120000"},
+	}
+
+	var got [][]string
+	for _, g := range groupByTable(matched) {
+		var names []string
+		for _, m := range g {
+			names = append(names, m.BaseName)
+		}
+		got = append(got, names)
+	}
+
+	want := [][]string{
+		{"orders_inserts_20260210_090000.csv", "ORDERS_inserts_20260211_170255.csv"},
+		{"CUSTOMERS_inserts_20260211_120000.csv", "Customers_inserts_20260212_080000.csv"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("groupByTable = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/worker"
+)
+
+// rejectsPath returns where rows refused while importing csvPath are written:
+// "orders.csv" → "orders.rejects.csv". Rejects from compressed files and zip
+// members are written uncompressed next to them: "orders.csv.gz" →
+// "orders.rejects.csv", "batch.zip!orders.csv" → "batch.zip!orders.rejects.csv".
+// Sheets keep their whole name: "book.xlsx!Q1.2026" →
+// "book.xlsx!Q1.2026.rejects.csv".
+func rejectsPath(csvPath string) string {
+	p := csvutil.TrimCompression(csvutil.FlatPath(csvPath))
+	if csvutil.IsSheet(csvPath) {
+		return p + csvutil.RejectsSuffix
+	}
+	return strings.TrimSuffix(p, filepath.Ext(p)) + csvutil.RejectsSuffix
+}
+
+// rejectWriter writes rejected rows to a CSV holding the original columns
+// plus the source line, offending column and database error. The file is
+// only created once the first reject arrives.
+type rejectWriter struct {
+	path    string
+	headers []string
+	append  bool
+	file    *os.File
+	w       *csv.Writer
+	count   int
+}
+
+func newRejectWriter(path string, headers []string, appendExisting bool) *rejectWriter {
+	return &rejectWriter{path: path, headers: headers, append: appendExisting}
+}
+
+// Write appends rejects to the file, creating it on first use.
+func (rw *rejectWriter) Write(rejects []worker.Reject) error {
+	if len(rejects) == 0 {
+		return nil
+	}
+	if rw.w == nil {
+		if err := rw.open(); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range rejects {
+		record := make([]string, 0, len(r.Row)+3)
+		record = append(record, r.Row...)
+		record = append(record, strconv.Itoa(r.Line), r.Column, r.Err.Error())
+		if err := rw.w.Write(record); err != nil {
+			return fmt.Errorf("writing rejects: %w", err)
+		}
+	}
+	rw.count += len(rejects)
+	rw.w.Flush()
+	return rw.w.Error()
+}
+
+func (rw *rejectWriter) open() error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	writeHeader := true
+	if rw.append {
+		if info, err := os.Stat(rw.path); err == nil && info.Size() > 0 {
+			flags = os.O_WRONLY | os.O_APPEND
+			writeHeader = false
+		}
+	}
+
+	if dir := filepath.Dir(rw.path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+	f, err := os.OpenFile(rw.path, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("creating rejects file: %w", err)
+	}
+	rw.file = f
+	rw.w = csv.NewWriter(f)
+
+	if writeHeader {
+		header := append(append([]string{}, rw.headers...), "source_line", "error_column", "error_message")
+		if err := rw.w.Write(header); err != nil {
+			return fmt.Errorf("writing rejects header: %w", err)
+		}
+	}
+	return nil
+}
+
+// Count returns the number of rejects written by this writer.
+func (rw *rejectWriter) Count() int {
+	return rw.count
+}
+
+// Close flushes and closes the file if it was created.
+func (rw *rejectWriter) Close() error {
+	if rw.file == nil {
+		return nil
+	}
+	rw.w.Flush()
+	if err := rw.w.Error(); err != nil {
+		rw.file.Close()
+		return fmt.Errorf("flushing rejects: %w", err)
+	}
+	return rw.file.Close()
+}
@@ -1,15 +1,22 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/walkerscm/scaleSyncGo/internal/config"
+	"github.com/walkerscm/scaleSyncGo/internal/logging"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var cfg *config.Config
 
+// logger is the structured logger configured by --log-level, --log-format
+// and --log-file. It discards everything until the root command has run.
+var logger = logging.Discard()
+
 var rootCmd = &cobra.Command{
 	Use:   "scalesync",
 	Short: "ScaleSync - A CLI tool for scaling and synchronization",
@@ -19,18 +26,40 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
+		if cfg.Output != outputText && cfg.Output != outputJSON {
+			return fmt.Errorf("unknown --output %q (want %s or %s)", cfg.Output, outputText, outputJSON)
+		}
+		logger, err = logging.New(cfg.LogLevel, cfg.LogFormat, cfg.LogFile)
+		if err != nil {
+			return fmt.Errorf("setting up logging: %w", err)
+		}
+		logger.Debug("starting", "command", cmd.CommandPath(), "version", Version)
 		return nil
 	},
 }
 
+// Execute runs the root command. It exits with exitPartial when a command
+// reports a partial failure and exitFailure on any other error.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		var ee *exitError
+		if errors.As(err, &ee) {
+			os.Exit(ee.code)
+		}
+		os.Exit(exitFailure)
 	}
 }
 
 func init() {
 	rootCmd.PersistentFlags().String("log-level", "info", "log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().String("log-format", "text", "log format (text, json)")
+	rootCmd.PersistentFlags().String("log-file", "", "append logs to this file instead of stderr")
 	rootCmd.PersistentFlags().StringP("output", "o", "text", "output format (text, json)")
 	rootCmd.PersistentFlags().StringP("target", "t", "prod", `target database: "prod" or "test"`)
+
+	// Flags take precedence over SCALESYNC_* env vars and config.yaml.
+	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))   //nolint:errcheck
+	viper.BindPFlag("log_format", rootCmd.PersistentFlags().Lookup("log-format")) //nolint:errcheck
+	viper.BindPFlag("log_file", rootCmd.PersistentFlags().Lookup("log-file"))     //nolint:errcheck
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))         //nolint:errcheck
 }
@@ -15,8 +15,16 @@ var (
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if jsonOutput() {
+			return printJSON(map[string]string{
+				"version":    Version,
+				"commit":     CommitSHA,
+				"build_date": BuildDate,
+			})
+		}
 		fmt.Printf("scalesync %s (commit: %s, built: %s)\n", Version, CommitSHA, BuildDate)
+		return nil
 	},
 }
 
@@ -0,0 +1,346 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/walkerscm/scaleSyncGo/internal/csvutil"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+	"github.com/walkerscm/scaleSyncGo/internal/filematch"
+)
+
+// watchTick is how often watch mode checks whether pending files are complete.
+const watchTick = time.Second
+
+// Watch mode retries a file whose import failed after retryBackoff, doubling
+// the wait after each further failure up to maxRetryBackoff.
+const (
+	retryBackoff    = 30 * time.Second
+	maxRetryBackoff = 30 * time.Minute
+)
+
+// retryDelay returns how long to wait before retrying a file that has failed
+// to import failures times.
+func retryDelay(failures int) time.Duration {
+	d := retryBackoff
+	for i := 1; i < failures && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
+
+// pendingFile is what settleTracker last saw of a file being written.
+type pendingFile struct {
+	size  int64
+	mod   time.Time
+	since time.Time // when size or mod last changed
+	// retryAt holds back a file whose import failed until then.
+	retryAt time.Time
+}
+
+// settleTracker decides when files arriving in a watched directory are
+// completely written. A file is complete once a <file>.done marker exists
+// or, if settle is non-zero, once its size and modification time have not
+// changed for settle.
+type settleTracker struct {
+	settle  time.Duration
+	pending map[string]pendingFile
+}
+
+func newSettleTracker(settle time.Duration) *settleTracker {
+	return &settleTracker{settle: settle, pending: make(map[string]pendingFile)}
+}
+
+// add starts (or restarts) tracking path.
+func (t *settleTracker) add(path string) {
+	t.pending[path] = pendingFile{size: -1}
+}
+
+// addNew starts tracking path unless it is already tracked.
+func (t *settleTracker) addNew(path string) {
+	if _, ok := t.pending[path]; !ok {
+		t.add(path)
+	}
+}
+
+// retry tracks path again after its import failed; it isn't ready before at.
+func (t *settleTracker) retry(path string, at time.Time) {
+	t.pending[path] = pendingFile{size: -1, retryAt: at}
+}
+
+// ready returns, in name order, the tracked files that are complete as of
+// now and stops tracking them. Files that have disappeared are dropped.
+func (t *settleTracker) ready(now time.Time) []string {
+	var paths []string
+	for path, p := range t.pending {
+		info, err := os.Stat(path)
+		if err != nil {
+			delete(t.pending, path)
+			continue
+		}
+		changed := info.Size() != p.size || !info.ModTime().Equal(p.mod)
+		if changed {
+			t.pending[path] = pendingFile{size: info.Size(), mod: info.ModTime(), since: now, retryAt: p.retryAt}
+		}
+		if now.Before(p.retryAt) {
+			continue
+		}
+		if _, err := os.Stat(path + csvutil.DoneSuffix); err == nil {
+			paths = append(paths, path)
+			delete(t.pending, path)
+			continue
+		}
+		if changed {
+			continue
+		}
+		if t.settle > 0 && now.Sub(p.since) >= t.settle {
+			paths = append(paths, path)
+			delete(t.pending, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// watchInput imports files from csv_input/ as they arrive until SIGINT or
+// SIGTERM, reusing dest's connection pool for every file. Files already
+// present when it starts are picked up too, and so are files that fail to
+// import, again after a growing delay (see retryDelay), resuming from their
+// checkpoint. The first signal stops it once the current import finishes; a
+// second cancels that import, leaving its checkpoint for --resume.
+func watchInput(dest database.Destination, rules *filematch.Matcher, opts importOptions, settle time.Duration, out io.Writer) error {
+	for _, dir := range []string{inputDir, processedDir} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating %s directory: %w", dir, err)
+		}
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("starting file watcher: %w", err)
+	}
+	defer w.Close()
+	if err := w.Add(inputDir); err != nil {
+		return fmt.Errorf("watching %s: %w", inputDir, err)
+	}
+	if rules.UsesDirectories() {
+		entries, err := os.ReadDir(inputDir)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", inputDir, err)
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				if err := w.Add(filepath.Join(inputDir, e.Name())); err != nil {
+					return fmt.Errorf("watching %s: %w", e.Name(), err)
+				}
+			}
+		}
+	}
+
+	ctx, abort := context.WithCancel(context.Background())
+	defer abort()
+	stopping := make(chan struct{})
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go func() {
+		select {
+		case <-sigs:
+		case <-ctx.Done():
+			return
+		}
+		fmt.Fprintln(out, "\nStopping after the current file (signal again to abort it)...")
+		logger.Info("shutdown requested")
+		close(stopping)
+		select {
+		case <-sigs:
+		case <-ctx.Done():
+			return
+		}
+		logger.Warn("aborting current import")
+		abort()
+	}()
+
+	tables, err := newTableMatcher(ctx, dest, rules)
+	if err != nil {
+		return err
+	}
+
+	tracker := newSettleTracker(settle)
+	existing, err := scanInput(rules, csvutil.ListFiles)
+	if err != nil {
+		return err
+	}
+	for _, path := range existing {
+		tracker.add(path)
+	}
+
+	fmt.Fprintf(out, "Watching %s/ for CSV files (Ctrl+C to stop)...\n", inputDir)
+	logger.Info("watching", "dir", inputDir, "settle", settle, "existing", len(existing))
+
+	var report processReport
+	failures := make(map[string]int)
+	importOne := func(path string, opts importOptions) (fileResult, bool, error) {
+		m, reason := tables.match(path)
+		if reason != "" && m.Rule != "" {
+			// The table may have been created since we last looked.
+			if err := tables.refresh(ctx); err == nil {
+				m, reason = tables.match(path)
+			}
+		}
+		if reason != "" {
+			fmt.Fprintf(out, "Skipping unmatched file: %s\n", reason)
+			logger.Warn("file not matched", "file", reason)
+			return fileResult{}, false, nil
+		}
+
+		fmt.Fprintf(out, "\nProcessing %s → %s...\n", m.BaseName, m.TableName)
+		res, err := importMatch(ctx, dest, m, opts, out)
+		report.TotalRows += res.Inserted
+		report.RejectedRows += res.Rejected
+		if err != nil {
+			report.Failed++
+		} else {
+			report.Succeeded++
+		}
+		return res, true, err
+	}
+	// handle imports the file at path, reporting whether an import failed.
+	handle := func(path string) bool {
+		files, err := csvutil.Expand(path)
+		if err != nil {
+			fmt.Fprintf(out, "ERROR: %v\n", err)
+			logger.Warn("file skipped", "file", filepath.Base(path), "err", err)
+			return false
+		}
+		// A retry picks up where the failed import stopped.
+		fileOpts := opts
+		if failures[path] > 0 {
+			fileOpts.Resume = true
+		}
+		var results []fileResult
+		failed := false
+		for _, f := range files {
+			res, ok, err := importOne(f, fileOpts)
+			if ok {
+				results = append(results, res)
+			}
+			if err != nil {
+				failed = true
+			}
+		}
+		if len(files) == 0 {
+			fmt.Fprintf(out, "Skipping %s: nothing to import in it\n", filepath.Base(path))
+			logger.Warn("file skipped", "file", filepath.Base(path), "err", "no CSV files or visible sheets")
+			return false
+		}
+		moveArchives(files, results, out)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if rerr := os.Remove(path + csvutil.DoneSuffix); rerr != nil && !os.IsNotExist(rerr) {
+				logger.Warn("done marker not removed", "file", path+csvutil.DoneSuffix, "err", rerr)
+			}
+		}
+		if jsonOutput() {
+			for _, res := range results {
+				if jerr := printJSON(res); jerr != nil {
+					logger.Warn("writing JSON output failed", "err", jerr)
+				}
+			}
+		}
+		return failed
+	}
+
+	ticker := time.NewTicker(watchTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopping:
+			fmt.Fprintln(out, "\n=== Watch Summary ===")
+			fmt.Fprintf(out, "Succeeded:       %d\n", report.Succeeded)
+			fmt.Fprintf(out, "Failed:          %d\n", report.Failed)
+			fmt.Fprintf(out, "Total rows:      %d\n", report.TotalRows)
+			fmt.Fprintf(out, "Rejected rows:   %d\n", report.RejectedRows)
+			logger.Info("watch stopped", "succeeded", report.Succeeded, "failed", report.Failed,
+				"rows", report.TotalRows, "rejected", report.RejectedRows)
+			return nil
+
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if ev.Op == fsnotify.Chmod {
+				continue
+			}
+			if ev.Has(fsnotify.Create) && rules.UsesDirectories() && filepath.Dir(ev.Name) == filepath.Clean(inputDir) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := w.Add(ev.Name); err != nil {
+						logger.Warn("cannot watch directory", "dir", ev.Name, "err", err)
+					}
+					nested, _ := csvutil.ListFiles(ev.Name)
+					for _, path := range nested {
+						tracker.add(path)
+					}
+					continue
+				}
+			}
+			// A marker being created readies the file it names.
+			path := strings.TrimSuffix(ev.Name, csvutil.DoneSuffix)
+			if csvutil.IsInputFile(filepath.Base(path)) {
+				tracker.add(path)
+			}
+
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				logger.Warn("file watcher error", "err", err)
+				continue
+			}
+			// Events were lost, likely during a long import; look for
+			// files that arrived unseen.
+			files, err := scanInput(rules, csvutil.ListFiles)
+			if err != nil {
+				logger.Warn("rescanning after file watcher overflow", "err", err)
+				continue
+			}
+			for _, path := range files {
+				tracker.addNew(path)
+			}
+			logger.Warn("file watcher overflowed; rescanned input", "dir", inputDir, "files", len(files))
+
+		case <-ticker.C:
+			for _, path := range tracker.ready(time.Now()) {
+				select {
+				case <-stopping:
+					// Leave the rest for the next run.
+				default:
+					if !handle(path) {
+						delete(failures, path)
+						continue
+					}
+					if _, err := os.Stat(path); err != nil {
+						delete(failures, path)
+						continue
+					}
+					failures[path]++
+					delay := retryDelay(failures[path])
+					tracker.retry(path, time.Now().Add(delay))
+					fmt.Fprintf(out, "Will retry %s in %s\n", filepath.Base(path), delay)
+					logger.Info("file will be retried", "file", filepath.Base(path),
+						"failures", failures[path], "delay", delay)
+				}
+			}
+		}
+	}
+}
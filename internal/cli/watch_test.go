@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSettleTracker(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "A_inserts_1.csv")
+	b := filepath.Join(dir, "B_inserts_1.csv")
+	gone := filepath.Join(dir, "C_inserts_1.csv")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("id\n1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tr := newSettleTracker(5 * time.Second)
+	tr.add(a)
+	tr.add(b)
+	tr.add(gone)
+
+	t0 := time.Now()
+	if got := tr.ready(t0); len(got) != 0 {
+		t.Fatalf("first look: ready = %v, want none", got)
+	}
+	if _, ok := tr.pending[gone]; ok {
+		t.Fatal("missing file still tracked")
+	}
+
+	// b grows; a's marker appears.
+	if err := os.WriteFile(b, []byte("id\n1\n2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(a+".done", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := tr.ready(t0.Add(4 * time.Second)); !reflect.DeepEqual(got, []string{a}) {
+		t.Fatalf("after marker: ready = %v, want [%s]", got, a)
+	}
+
+	// b's settle clock restarted when it grew.
+	if got := tr.ready(t0.Add(8 * time.Second)); len(got) != 0 {
+		t.Fatalf("before settle: ready = %v, want none", got)
+	}
+	if got := tr.ready(t0.Add(9 * time.Second)); !reflect.DeepEqual(got, []string{b}) {
+		t.Fatalf("after settle: ready = %v, want [%s]", got, b)
+	}
+	if len(tr.pending) != 0 {
+		t.Fatalf("pending = %v, want empty", tr.pending)
+	}
+}
+
+func TestSettleTrackerRetry(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "A_inserts_1.csv")
+	if err := os.WriteFile(a, []byte("id\n1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := newSettleTracker(5 * time.Second)
+	t0 := time.Now()
+	tr.retry(a, t0.Add(time.Minute))
+	tr.addNew(a) // an overflow rescan must not cut the backoff short
+
+	for _, at := range []time.Duration{0, 10 * time.Second, 59 * time.Second} {
+		if got := tr.ready(t0.Add(at)); len(got) != 0 {
+			t.Fatalf("at %s: ready = %v, want none", at, got)
+		}
+	}
+	if got := tr.ready(t0.Add(time.Minute)); !reflect.DeepEqual(got, []string{a}) {
+		t.Fatalf("after backoff: ready = %v, want [%s]", got, a)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		6:  16 * time.Minute,
+		7:  30 * time.Minute,
+		50: 30 * time.Minute,
+	} {
+		if got := retryDelay(failures); got != want {
+			t.Errorf("retryDelay(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
@@ -7,8 +7,59 @@ import (
 )
 
 type Config struct {
-	LogLevel string `mapstructure:"log_level"`
-	Output   string `mapstructure:"output"`
+	LogLevel  string `mapstructure:"log_level"`
+	LogFormat string `mapstructure:"log_format"`
+	LogFile   string `mapstructure:"log_file"`
+	Output    string `mapstructure:"output"`
+	// MatchRules map csv_input/ filenames to tables for process, tried in
+	// order before the built-in <TABLE>_inserts_<timestamp>.csv rule.
+	MatchRules []MatchRule `mapstructure:"match_rules"`
+	// Dialects describe the CSV format of the files loaded into particular
+	// tables, overridden by the command-line flags.
+	Dialects []Dialect `mapstructure:"dialects"`
+	// Extracts are the tables export --incremental exports the new and
+	// changed rows of.
+	Extracts []Extract `mapstructure:"extracts"`
+}
+
+// Extract is one entry of extracts in config.yaml.
+type Extract struct {
+	// Table is the schema.table to export.
+	Table string `mapstructure:"table"`
+	// Watermark is a column whose value grows with every insert and update:
+	// a rowversion, a LastModified datetime or an ever-increasing integer.
+	Watermark string `mapstructure:"watermark"`
+	// Where further filters the rows (SQL, without WHERE).
+	Where string `mapstructure:"where"`
+}
+
+// Dialect is one entry of dialects in config.yaml. Empty fields keep the
+// defaults (comma-delimited, double-quoted, UTF-8).
+type Dialect struct {
+	// Table is the schema.table the entry applies to, or "*" for all tables
+	// without an entry of their own.
+	Table     string `mapstructure:"table"`
+	Delimiter string `mapstructure:"delimiter"`
+	Quote     string `mapstructure:"quote"`
+	Comment   string `mapstructure:"comment"`
+	Encoding  string `mapstructure:"encoding"`
+}
+
+// MatchRule is one entry of match_rules in config.yaml.
+type MatchRule struct {
+	Name string `mapstructure:"name"`
+	// File matches one filename exactly (case-insensitively).
+	File string `mapstructure:"file"`
+	// Pattern is a regular expression over the filename. Its named groups
+	// schema, table and timestamp supply those parts of the match.
+	Pattern string `mapstructure:"pattern"`
+	// Schema and Table are used when the file or pattern does not supply
+	// them. Table may be schema-qualified.
+	Schema string `mapstructure:"schema"`
+	Table  string `mapstructure:"table"`
+	// SchemaFromDir makes the rule match files in subdirectories of
+	// csv_input/, using the subdirectory name as the schema.
+	SchemaFromDir bool `mapstructure:"schema_from_dir"`
 }
 
 func Load() (*Config, error) {
@@ -18,6 +69,8 @@ func Load() (*Config, error) {
 	viper.AddConfigPath("$HOME/.scalesync")
 
 	viper.SetDefault("log_level", "info")
+	viper.SetDefault("log_format", "text")
+	viper.SetDefault("log_file", "")
 	viper.SetDefault("output", "text")
 
 	viper.SetEnvPrefix("SCALESYNC")
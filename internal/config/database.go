@@ -9,12 +9,16 @@ import (
 	"github.com/joho/godotenv"
 )
 
-// DatabaseConfig holds the connection parameters for an Azure SQL database.
+// DatabaseConfig holds the connection parameters for a target database.
 type DatabaseConfig struct {
+	// Driver is "sqlserver" (Azure SQL, the default) or "postgres".
+	Driver   string
 	Server   string
 	Database string
 	Username string
 	Password string
+	// SSLMode is the Postgres sslmode; defaults to "require".
+	SSLMode string
 }
 
 // targetEnvPrefix maps a --target value to its env var prefix.
@@ -31,6 +35,8 @@ func ValidTargets() []string {
 // LoadDatabaseConfig reads the .env file at envPath and returns the database
 // configuration for the given target ("prod" or "test"). The env vars are
 // read with the prefix {TARGET}_{FIELD}, e.g. PROD_SERVER or TEST_SERVER.
+// {TARGET}_DRIVER selects the database type and {TARGET}_SSLMODE the
+// Postgres sslmode; both are optional.
 func LoadDatabaseConfig(envPath, target string) (*DatabaseConfig, error) {
 	if err := godotenv.Load(envPath); err != nil {
 		return nil, fmt.Errorf("loading %s: %w", envPath, err)
@@ -42,6 +48,8 @@ func LoadDatabaseConfig(envPath, target string) (*DatabaseConfig, error) {
 	}
 
 	cfg := &DatabaseConfig{
+		Driver:   strings.ToLower(os.Getenv(prefix + "_DRIVER")),
+		SSLMode:  os.Getenv(prefix + "_SSLMODE"),
 		Server:   os.Getenv(prefix + "_SERVER"),
 		Database: os.Getenv(prefix + "_DATABASE"),
 		Username: os.Getenv(prefix + "_USERNAME"),
@@ -53,11 +61,23 @@ func LoadDatabaseConfig(envPath, target string) (*DatabaseConfig, error) {
 			prefix, prefix, prefix, prefix)
 	}
 
+	switch cfg.Driver {
+	case "":
+		cfg.Driver = "sqlserver"
+	case "sqlserver", "postgres":
+	default:
+		return nil, fmt.Errorf("%s_DRIVER must be sqlserver or postgres, got %q", prefix, cfg.Driver)
+	}
+
 	return cfg, nil
 }
 
-// ConnectionString builds an ADO-style connection string for go-mssqldb.
+// ConnectionString builds a connection URL for the configured driver.
 func (c *DatabaseConfig) ConnectionString() string {
+	if c.Driver == "postgres" {
+		return c.postgresConnectionString()
+	}
+
 	query := url.Values{}
 	query.Add("database", c.Database)
 	query.Add("encrypt", "true")
@@ -72,3 +92,23 @@ func (c *DatabaseConfig) ConnectionString() string {
 	}
 	return u.String()
 }
+
+// postgresConnectionString builds a connection URL for lib/pq.
+func (c *DatabaseConfig) postgresConnectionString() string {
+	sslMode := c.SSLMode
+	if sslMode == "" {
+		sslMode = "require"
+	}
+	query := url.Values{}
+	query.Add("sslmode", sslMode)
+	query.Add("connect_timeout", "30")
+
+	u := &url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Username, c.Password),
+		Host:     c.Server,
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
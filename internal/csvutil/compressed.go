@@ -0,0 +1,234 @@
+package csvutil
+
+import (
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compressed CSV suffixes that are decompressed transparently while reading.
+const (
+	GzipSuffix  = ".gz"
+	ZstdSuffix  = ".zst"
+	Bzip2Suffix = ".bz2"
+	ZipSuffix   = ".zip"
+)
+
+// memberSep separates a zip archive's path from a member name, or a
+// workbook's path from a sheet name, in the paths ScanDirectory returns for
+// them, e.g. "in/batch.zip!orders.csv" or "in/book.xlsx!Orders".
+const memberSep = "!"
+
+// MemberPath returns the path of member inside the zip archive or workbook
+// at archive.
+func MemberPath(archive, member string) string {
+	return archive + memberSep + member
+}
+
+// SplitMember splits a path made by MemberPath into the archive and member.
+func SplitMember(p string) (archive, member string, ok bool) {
+	lower := strings.ToLower(p)
+	end := -1
+	for _, suffix := range []string{ZipSuffix, XLSXSuffix} {
+		if i := strings.Index(lower, suffix+memberSep); i >= 0 && (end < 0 || i+len(suffix) < end) {
+			end = i + len(suffix)
+		}
+	}
+	if end < 0 {
+		return "", "", false
+	}
+	return p[:end], p[end+len(memberSep):], true
+}
+
+// SourcePath returns the file on disk holding p: the archive for a zip
+// member or the workbook for a sheet, else p itself.
+func SourcePath(p string) string {
+	if archive, _, ok := SplitMember(p); ok {
+		return archive
+	}
+	return p
+}
+
+// compressionSuffix returns the compression suffix of a .csv.gz, .csv.zst or
+// .csv.bz2 name, or "".
+func compressionSuffix(name string) string {
+	lower := strings.ToLower(name)
+	for _, s := range []string{GzipSuffix, ZstdSuffix, Bzip2Suffix} {
+		if strings.HasSuffix(lower, ".csv"+s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// TrimCompression removes a .gz, .zst or .bz2 suffix from a compressed CSV
+// name ("orders.csv.gz" → "orders.csv").
+func TrimCompression(name string) string {
+	return name[:len(name)-len(compressionSuffix(name))]
+}
+
+// DisplayName returns p's file name for messages: "orders.csv.gz", or
+// "batch.zip!orders.csv" for a zip member.
+func DisplayName(p string) string {
+	if archive, member, ok := SplitMember(p); ok {
+		return MemberPath(filepath.Base(archive), member)
+	}
+	return filepath.Base(p)
+}
+
+// LogicalName returns the file name p stands for, which is what files are
+// matched to tables by: the base name of a zip member, the workbook's name
+// joined to a sheet's ("book.xlsx!Orders" → "book_Orders.xlsx"), or the base
+// name without its compression suffix ("orders.csv.gz" → "orders.csv").
+func LogicalName(p string) string {
+	if archive, member, ok := SplitMember(p); ok {
+		if IsWorkbook(archive) {
+			base := filepath.Base(archive)
+			return strings.TrimSuffix(base, filepath.Ext(base)) + "_" + member + XLSXSuffix
+		}
+		return path.Base(member)
+	}
+	return TrimCompression(filepath.Base(p))
+}
+
+// FlatPath returns p as a single path on disk, for naming the rejects and
+// checkpoint files that go next to it: a zip member's directories are
+// flattened into its name ("in/b.zip!x/o.csv" → "in/b.zip!x_o.csv").
+func FlatPath(p string) string {
+	if archive, member, ok := SplitMember(p); ok {
+		return MemberPath(archive, strings.ReplaceAll(member, "/", "_"))
+	}
+	return p
+}
+
+// ArchiveMembers returns the MemberPath of every CSV in the zip archive.
+func ArchiveMembers(archive string) ([]string, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("opening archive %s: %w", archive, err)
+	}
+	defer zr.Close()
+
+	var members []string
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".csv") {
+			continue
+		}
+		if strings.HasSuffix(strings.ToLower(f.Name), RejectsSuffix) {
+			continue
+		}
+		members = append(members, MemberPath(archive, f.Name))
+	}
+	return members, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// source is an open CSV byte stream, decompressed if need be.
+type source struct {
+	io.Reader
+	file    *os.File // set for plain CSV files, which can seek
+	counter *countingReader
+	size    int64
+	closers []func() error
+}
+
+// openSource opens p (a plain or compressed CSV file or a zip member) for
+// reading its CSV bytes. For compressed input, counter and size measure the
+// compressed bytes on disk; for zip members, the member's uncompressed bytes.
+func openSource(p string) (*source, error) {
+	if archive, member, ok := SplitMember(p); ok {
+		return openMember(archive, member)
+	}
+
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &source{counter: &countingReader{r: f}, size: info.Size(), closers: []func() error{f.Close}}
+
+	switch compressionSuffix(p) {
+	case "":
+		s.file = f
+		s.Reader = s.counter
+	case GzipSuffix:
+		gz, err := gzip.NewReader(s.counter)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("reading gzip header: %w", err)
+		}
+		s.Reader = gz
+		s.closers = append(s.closers, gz.Close)
+	case ZstdSuffix:
+		zr, err := zstd.NewReader(s.counter)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("starting zstd decoder: %w", err)
+		}
+		s.Reader = zr
+		s.closers = append(s.closers, func() error { zr.Close(); return nil })
+	case Bzip2Suffix:
+		s.Reader = bzip2.NewReader(s.counter)
+	}
+	return s, nil
+}
+
+func openMember(archive, member string) (*source, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != member {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			zr.Close()
+			return nil, fmt.Errorf("opening %s in archive: %w", member, err)
+		}
+		s := &source{
+			counter: &countingReader{r: rc},
+			size:    int64(f.UncompressedSize64),
+			closers: []func() error{zr.Close, rc.Close},
+		}
+		s.Reader = s.counter
+		return s, nil
+	}
+	zr.Close()
+	return nil, fmt.Errorf("%s not found in archive %s", member, archive)
+}
+
+// Close closes the decompressors and file, innermost first.
+func (s *source) Close() error {
+	var first error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i](); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
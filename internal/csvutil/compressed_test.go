@@ -0,0 +1,114 @@
+package csvutil
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const sample = "ID,NOTE\n1,a\n2,\"two\nlines\"\n3,c\n"
+
+func writeCompressed(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if filepath.Ext(name) == ZipSuffix {
+		zw := zip.NewWriter(f)
+		for _, member := range []string{"a/ORDERS_inserts_1.csv", "ITEMS_inserts_1.csv", "readme.txt"} {
+			mw, err := zw.Create(member)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(mw, sample) //nolint:errcheck
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var w io.WriteCloser
+	if filepath.Ext(name) == ZstdSuffix {
+		if w, err = zstd.NewWriter(f); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		w = gzip.NewWriter(f)
+	}
+	io.WriteString(w, sample) //nolint:errcheck
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCompressedInput(t *testing.T) {
+	dir := t.TempDir()
+	writeCompressed(t, dir, "ORDERS_inserts_1.csv.gz")
+	writeCompressed(t, dir, "ORDERS_inserts_2.csv.zst")
+	archive := writeCompressed(t, dir, "batch.zip")
+	if err := os.WriteFile(filepath.Join(dir, "ORDERS_inserts_1.rejects.csv"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ScanDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, "ORDERS_inserts_1.csv.gz"),
+		filepath.Join(dir, "ORDERS_inserts_2.csv.zst"),
+		MemberPath(archive, "a/ORDERS_inserts_1.csv"),
+		MemberPath(archive, "ITEMS_inserts_1.csv"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("ScanDirectory = %v, want %v", files, want)
+	}
+	if got := LogicalName(files[0]); got != "ORDERS_inserts_1.csv" {
+		t.Errorf("LogicalName(%s) = %s", files[0], got)
+	}
+	if got := LogicalName(files[2]); got != "ORDERS_inserts_1.csv" {
+		t.Errorf("LogicalName(%s) = %s", files[2], got)
+	}
+
+	for _, path := range files {
+		r, err := NewReader(path, Dialect{})
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if !r.Compressed() {
+			t.Errorf("%s: Compressed() = false", path)
+		}
+		if _, err := r.ReadBatch(2); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		offset, line := r.Offset(), r.Line()
+
+		// Resuming reopens and skips to the offset.
+		if err := r.SeekTo(offset, line); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		rows, lines, err := r.ReadBatchLines(10)
+		if err != io.EOF {
+			t.Fatalf("%s: expected io.EOF, got %v", path, err)
+		}
+		if len(rows) != 1 || rows[0][0] != "3" || lines[0] != 5 {
+			t.Errorf("%s: after SeekTo got rows %v lines %v", path, rows, lines)
+		}
+		if done, total := r.Progress(); done == 0 || total == 0 {
+			t.Errorf("%s: Progress() = %d, %d", path, done, total)
+		}
+		r.Close()
+	}
+}
@@ -0,0 +1,319 @@
+package csvutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// AutoDetect as a Dialect's Delimiter or Encoding sniffs it from the start of
+// the file.
+const AutoDetect = "auto"
+
+// QuoteNone as a Dialect's Quote turns quoting off: quote characters are
+// read as ordinary data.
+const QuoteNone = "none"
+
+// sniffSize is how much of a file is looked at to detect its dialect.
+const sniffSize = 64 * 1024
+
+// Dialect describes how a CSV file is written. Empty fields mean the
+// defaults: comma-delimited, double-quoted, UTF-8 and no comment lines. A
+// byte order mark is always honoured and removed, whatever the Encoding.
+type Dialect struct {
+	// Delimiter separates fields: a single character, "tab", or AutoDetect.
+	Delimiter string
+	// Quote is the quote character, or QuoteNone.
+	Quote string
+	// Comment starts lines that are skipped.
+	Comment string
+	// Encoding is a character set name such as "utf-16le" or
+	// "windows-1252", or AutoDetect.
+	Encoding string
+}
+
+// Merge returns d with the fields that are set in over replacing its own.
+func (d Dialect) Merge(over Dialect) Dialect {
+	if over.Delimiter != "" {
+		d.Delimiter = over.Delimiter
+	}
+	if over.Quote != "" {
+		d.Quote = over.Quote
+	}
+	if over.Comment != "" {
+		d.Comment = over.Comment
+	}
+	if over.Encoding != "" {
+		d.Encoding = over.Encoding
+	}
+	return d
+}
+
+// String describes d for messages, e.g. `delimiter ";", encoding windows-1252`.
+func (d Dialect) String() string {
+	delim, _ := d.delimiter()
+	parts := []string{fmt.Sprintf("delimiter %q", delim), "encoding " + d.encodingName()}
+	switch d.Quote {
+	case "", `"`:
+	case QuoteNone:
+		parts = append(parts, "no quoting")
+	default:
+		parts = append(parts, fmt.Sprintf("quote %s", d.Quote))
+	}
+	if d.Comment != "" {
+		parts = append(parts, fmt.Sprintf("comment %s", d.Comment))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Validate checks that every field of d is usable.
+func (d Dialect) Validate() error {
+	if d.Delimiter != AutoDetect {
+		if _, err := d.delimiter(); err != nil {
+			return err
+		}
+	}
+	if _, _, err := d.quote(); err != nil {
+		return err
+	}
+	if _, err := singleChar("comment", d.Comment); err != nil {
+		return err
+	}
+	if d.Encoding != "" && d.Encoding != AutoDetect {
+		if _, err := lookupEncoding(d.Encoding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d Dialect) delimiter() (rune, error) {
+	switch strings.ToLower(d.Delimiter) {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r, err := singleChar("delimiter", d.Delimiter)
+	if err == nil && (r == '"' || r == '\r' || r == '\n') {
+		err = fmt.Errorf("invalid delimiter %q", d.Delimiter)
+	}
+	return r, err
+}
+
+// quote returns the byte to swap with '"' before parsing; swap is false for
+// standard double quotes, which need no swap.
+func (d Dialect) quote() (q byte, swap bool, err error) {
+	switch d.Quote {
+	case "", `"`:
+		return 0, false, nil
+	case QuoteNone:
+		// Swap '"' with a byte that doesn't occur in text files, so the
+		// parser never sees a quote.
+		return 0x00, true, nil
+	}
+	if len(d.Quote) != 1 || d.Quote[0] >= utf8.RuneSelf {
+		return 0, false, fmt.Errorf("quote must be a single ASCII character or %q, got %q", QuoteNone, d.Quote)
+	}
+	return d.Quote[0], true, nil
+}
+
+func (d Dialect) encodingName() string {
+	if d.Encoding == "" {
+		return "utf-8"
+	}
+	return strings.ToLower(d.Encoding)
+}
+
+func singleChar(what, s string) (rune, error) {
+	if s == "" {
+		return 0, nil
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == utf8.RuneError {
+		return 0, fmt.Errorf("%s must be a single character, got %q", what, s)
+	}
+	return r, nil
+}
+
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(name) {
+	case "utf-8", "utf8":
+		return unicode.UTF8, nil
+	case "utf-16", "utf-16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case "utf-16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+	return enc, nil
+}
+
+// byte order marks
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// decode turns raw, the bytes of a CSV file, into UTF-8 text without a byte
+// order mark and with d's quote character swapped for '"' (see swapQuotes),
+// detecting the encoding and delimiter if d asks for it. It
+// returns the text, the dialect with anything detected filled in, and the
+// length of the byte order mark skipped.
+func decode(raw io.Reader, d Dialect) (io.Reader, Dialect, int, error) {
+	br := bufio.NewReaderSize(raw, sniffSize)
+	head, _ := br.Peek(sniffSize)
+
+	var bomLen int
+	switch {
+	case bytes.HasPrefix(head, bomUTF8):
+		d.Encoding, bomLen = "utf-8", len(bomUTF8)
+	case bytes.HasPrefix(head, bomUTF16LE):
+		d.Encoding, bomLen = "utf-16le", len(bomUTF16LE)
+	case bytes.HasPrefix(head, bomUTF16BE):
+		d.Encoding, bomLen = "utf-16be", len(bomUTF16BE)
+	case d.Encoding == AutoDetect:
+		d.Encoding = sniffEncoding(head)
+	}
+	if _, err := br.Discard(bomLen); err != nil {
+		return nil, d, 0, err
+	}
+
+	var text io.Reader = br
+	if name := d.encodingName(); name != "utf-8" && name != "utf8" {
+		enc, err := lookupEncoding(name)
+		if err != nil {
+			return nil, d, 0, err
+		}
+		text = enc.NewDecoder().Reader(br)
+	}
+
+	if q, swap, _ := d.quote(); swap {
+		text = quoteSwapReader{r: text, q: q}
+	}
+
+	if d.Delimiter == AutoDetect {
+		tb := bufio.NewReaderSize(text, sniffSize)
+		sample, _ := tb.Peek(sniffSize)
+		d.Delimiter = sniffDelimiter(sample, d)
+		text = tb
+	}
+
+	return text, d, bomLen, nil
+}
+
+// sniffEncoding guesses the encoding of a file without a byte order mark
+// from its first bytes: UTF-16 if every other byte is zero, UTF-8 if the
+// sample is valid UTF-8, else Windows-1252.
+func sniffEncoding(head []byte) string {
+	if len(head) >= 2 {
+		var evenZero, oddZero int
+		for i, b := range head {
+			if b == 0 {
+				if i%2 == 0 {
+					evenZero++
+				} else {
+					oddZero++
+				}
+			}
+		}
+		half := len(head) / 2
+		switch {
+		case oddZero > half*9/10:
+			return "utf-16le"
+		case evenZero > half*9/10:
+			return "utf-16be"
+		}
+	}
+	// A full sample may end part-way through a character.
+	if len(head) == sniffSize {
+		i := len(head) - 1
+		for i > 0 && len(head)-i < utf8.UTFMax && !utf8.RuneStart(head[i]) {
+			i--
+		}
+		if !utf8.FullRune(head[i:]) {
+			head = head[:i]
+		}
+	}
+	if utf8.Valid(head) {
+		return "utf-8"
+	}
+	return "windows-1252"
+}
+
+// sniffDelimiter picks the candidate delimiter that splits the sample's
+// complete lines into the most fields, the same number on every line.
+// It falls back to a comma.
+func sniffDelimiter(sample []byte, d Dialect) string {
+	if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+		sample = sample[:i+1]
+	}
+	best, bestFields := ",", 1
+	for _, cand := range []rune{',', ';', '\t', '|'} {
+		r := csv.NewReader(bytes.NewReader(sample))
+		r.Comma = cand
+		r.LazyQuotes = true
+		if c, _ := singleChar("comment", d.Comment); c != 0 {
+			r.Comment = c
+		}
+		records, err := r.ReadAll()
+		if err != nil || len(records) == 0 {
+			continue
+		}
+		if n := len(records[0]); n > bestFields {
+			best, bestFields = string(cand), n
+		}
+	}
+	if best == "\t" {
+		return "tab"
+	}
+	return best
+}
+
+// swapQuotes exchanges '"' and q in p, so that encoding/csv, which only
+// understands double quotes, parses fields quoted with q.
+func swapQuotes(p []byte, q byte) {
+	for i, b := range p {
+		switch b {
+		case '"':
+			p[i] = q
+		case q:
+			p[i] = '"'
+		}
+	}
+}
+
+// quoteSwapReader applies swapQuotes to everything read through it.
+type quoteSwapReader struct {
+	r io.Reader
+	q byte
+}
+
+func (s quoteSwapReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	swapQuotes(p[:n], s.q)
+	return n, err
+}
+
+// unswapFields undoes swapQuotes in the parsed fields of a record.
+func unswapFields(record []string, q byte) {
+	for i, f := range record {
+		if strings.IndexByte(f, '"') >= 0 || strings.IndexByte(f, q) >= 0 {
+			b := []byte(f)
+			swapQuotes(b, q)
+			record[i] = string(b)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package csvutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDialects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		dialect Dialect
+		want    [][]string
+	}{
+		{
+			name:    "utf-8 bom",
+			content: []byte("\xEF\xBB\xBFID,NAME\n1,a\n"),
+			want:    [][]string{{"ID", "NAME"}, {"1", "a"}},
+		},
+		{
+			name:    "sniffed semicolons",
+			content: []byte("ID;NAME;NOTE\n1;a,b;x\n2;c;y\n"),
+			dialect: Dialect{Delimiter: AutoDetect},
+			want:    [][]string{{"ID", "NAME", "NOTE"}, {"1", "a,b", "x"}, {"2", "c", "y"}},
+		},
+		{
+			name:    "utf-16le bom",
+			content: []byte("\xFF\xFEI\x00D\x00\t\x00N\x00\n\x001\x00\t\x00\xE9\x00\n\x00"),
+			dialect: Dialect{Delimiter: "tab"},
+			want:    [][]string{{"ID", "N"}, {"1", "é"}},
+		},
+		{
+			name:    "windows-1252",
+			content: []byte("ID,NAME\n1,caf\xE9\n"),
+			dialect: Dialect{Encoding: AutoDetect},
+			want:    [][]string{{"ID", "NAME"}, {"1", "café"}},
+		},
+		{
+			name:    "single quotes",
+			content: []byte("ID,NOTE\n1,'a, \"b\"'\n2,'it''s'\n"),
+			dialect: Dialect{Quote: "'"},
+			want:    [][]string{{"ID", "NOTE"}, {"1", `a, "b"`}, {"2", "it's"}},
+		},
+		{
+			name:    "quoting off",
+			content: []byte("A,B,C\n\"x,y\",2\n"),
+			dialect: Dialect{Quote: QuoteNone},
+			want:    [][]string{{"A", "B", "C"}, {`"x`, `y"`, "2"}},
+		},
+		{
+			name:    "comments",
+			content: []byte("ID,NAME\n# skipped\n1,a\n"),
+			dialect: Dialect{Comment: "#"},
+			want:    [][]string{{"ID", "NAME"}, {"1", "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemp(t, "data.csv", string(tt.content))
+			r, err := NewReader(path, tt.dialect)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			rows, _ := r.ReadBatch(10)
+			got := append([][]string{r.Headers()}, rows...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeekToAfterBOM(t *testing.T) {
+	for _, d := range []Dialect{{}, {Encoding: "windows-1252"}} {
+		path := writeTemp(t, "data.csv", "\xEF\xBB\xBFID,NAME\n1,a\n2,b\n")
+		r, err := NewReader(path, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.ReadBatch(1); err != nil {
+			t.Fatal(err)
+		}
+		offset, line := r.Offset(), r.Line()
+		if err := r.SeekTo(offset, line); err != nil {
+			t.Fatal(err)
+		}
+		rows, _ := r.ReadBatch(10)
+		r.Close()
+		if len(rows) != 1 || rows[0][0] != "2" {
+			t.Fatalf("%v: expected row 2 after seek, got %v", d, rows)
+		}
+	}
+}
+
+func TestDialectValidate(t *testing.T) {
+	for _, d := range []Dialect{{Delimiter: "ab"}, {Delimiter: `"`}, {Quote: "«"}, {Encoding: "klingon"}} {
+		if err := d.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", d)
+		}
+	}
+}
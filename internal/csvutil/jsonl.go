@@ -0,0 +1,253 @@
+package csvutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// JSON Lines file extensions.
+const (
+	JSONLSuffix  = ".jsonl"
+	NDJSONSuffix = ".ndjson"
+)
+
+// KeepAllJSON in a JSONLReader's keep list keeps every nested object as JSON.
+const KeepAllJSON = "*"
+
+// IsJSONLines reports whether p names a JSON Lines file.
+func IsJSONLines(p string) bool {
+	ext := filepath.Ext(p)
+	return strings.EqualFold(ext, JSONLSuffix) || strings.EqualFold(ext, NDJSONSuffix)
+}
+
+// JSONLReader reads a JSON Lines (NDJSON) file, one object per line, as text
+// rows that are converted exactly like CSV fields. Nested objects are
+// flattened into dotted paths ("address.city") unless kept; arrays and kept
+// objects are read as compact JSON text. The headers are the union of the
+// paths in the file in the order they first appear, which takes a pass over
+// the whole file when it is opened.
+//
+// A field's text is a string's value, a number as written, "true" or
+// "false", or empty for null and for paths a line doesn't have.
+type JSONLReader struct {
+	file    *os.File
+	br      *bufio.Reader
+	keep    []string
+	headers []string
+	index   map[string]int
+	rows    int64
+	size    int64
+	offset  int64
+	line    int
+}
+
+// OpenJSONLines opens the JSON Lines file at path and collects its headers.
+// keep lists the nested objects, by dotted path, to read whole as JSON text
+// instead of flattening; KeepAllJSON keeps them all.
+func OpenJSONLines(path string, keep []string) (*JSONLReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening json lines: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening json lines: %w", err)
+	}
+
+	r := &JSONLReader{file: f, br: bufio.NewReader(f), keep: keep, index: map[string]int{}, size: info.Size()}
+	for {
+		raw, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = r.flatten(raw, "", func(path, _ string) {
+				if _, ok := r.index[path]; !ok {
+					r.index[path] = len(r.headers)
+					r.headers = append(r.headers, path)
+				}
+			})
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		r.rows++
+	}
+	if r.rows == 0 {
+		f.Close()
+		return nil, fmt.Errorf("no JSON objects in %s", filepath.Base(path))
+	}
+
+	if err := r.SeekTo(0, 0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// next returns the next non-blank line, or io.EOF.
+func (r *JSONLReader) next() ([]byte, error) {
+	for {
+		line, err := r.br.ReadBytes('\n')
+		if len(line) > 0 {
+			r.offset += int64(len(line))
+			r.line++
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				return line, nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// flatten calls set with the path and text of every field of the JSON
+// object raw, prefixing paths with prefix.
+func (r *JSONLReader) flatten(raw []byte, prefix string, set func(path, text string)) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('{') {
+		return errors.New("not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		path := tok.(string)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		var val json.RawMessage
+		if err := dec.Decode(&val); err != nil {
+			return err
+		}
+		if val[0] == '{' && !r.keeps(path) {
+			if err := r.flatten(val, path, set); err != nil {
+				return err
+			}
+			continue
+		}
+		text, err := jsonText(val)
+		if err != nil {
+			return err
+		}
+		set(path, text)
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("more than one JSON value on the line")
+	}
+	return nil
+}
+
+func (r *JSONLReader) keeps(path string) bool {
+	for _, k := range r.keep {
+		if k == KeepAllJSON || strings.EqualFold(k, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonText returns the field text of a JSON value.
+func jsonText(val json.RawMessage) (string, error) {
+	switch val[0] {
+	case '"':
+		var s string
+		err := json.Unmarshal(val, &s)
+		return s, err
+	case 'n':
+		return "", nil
+	case '{', '[':
+		var b bytes.Buffer
+		err := json.Compact(&b, val)
+		return b.String(), err
+	}
+	// A number, true or false, as written.
+	return string(val), nil
+}
+
+// Headers returns the dotted paths of the fields found in the file.
+func (r *JSONLReader) Headers() []string {
+	return r.headers
+}
+
+// NumRows returns the number of objects in the file.
+func (r *JSONLReader) NumRows() int64 {
+	return r.rows
+}
+
+// ReadRows reads up to n objects as rows of field text.
+func (r *JSONLReader) ReadRows(n int) (Batch, error) {
+	var b Batch
+	for b.Len() < n {
+		raw, err := r.next()
+		if err == io.EOF {
+			return b, io.EOF
+		}
+		if err != nil {
+			return b, fmt.Errorf("reading json lines: %w", err)
+		}
+		row := make([]string, len(r.headers))
+		err = r.flatten(raw, "", func(path, text string) {
+			if i, ok := r.index[path]; ok {
+				row[i] = text
+			}
+		})
+		if err != nil {
+			return b, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		b.Text = append(b.Text, row)
+		b.Lines = append(b.Lines, r.line)
+	}
+	return b, nil
+}
+
+// Offset returns the byte offset just past the last line read.
+func (r *JSONLReader) Offset() int64 {
+	return r.offset
+}
+
+// Line returns the number of the last line read.
+func (r *JSONLReader) Line() int {
+	return r.line
+}
+
+// SeekTo repositions the reader at offset, the start of line+1.
+func (r *JSONLReader) SeekTo(offset int64, line int) error {
+	if _, err := r.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("seeking json lines to offset %d: %w", offset, err)
+	}
+	r.br.Reset(r.file)
+	r.offset, r.line = offset, line
+	return nil
+}
+
+// Compressed returns false: the objects are counted when the file is opened.
+func (r *JSONLReader) Compressed() bool {
+	return false
+}
+
+// Progress returns the bytes read and the file's size.
+func (r *JSONLReader) Progress() (done, total int64) {
+	return r.offset, r.size
+}
+
+// Close closes the file.
+func (r *JSONLReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,79 @@
+package csvutil
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestJSONLReader(t *testing.T) {
+	path := writeTemp(t, "events_inserts_1.jsonl", `{"id": 1, "name": "Ann", "address": {"city": "Oslo", "geo": {"lat": 59.9}}, "tags": ["a", "b"]}
+
+{"id": 2, "active": true, "address": {"city": null}, "note": "tab\there"}
+{"id": 3, "address": {"zip": "0150"}}
+`)
+
+	r, err := OpenJSONLines(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	wantHeaders := []string{"id", "name", "address.city", "address.geo.lat", "tags", "active", "note", "address.zip"}
+	if !reflect.DeepEqual(r.Headers(), wantHeaders) {
+		t.Fatalf("headers = %v, want %v", r.Headers(), wantHeaders)
+	}
+	if r.NumRows() != 3 {
+		t.Fatalf("NumRows = %d, want 3", r.NumRows())
+	}
+
+	b, err := r.ReadRows(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"1", "Ann", "Oslo", "59.9", `["a","b"]`, "", "", ""},
+		{"2", "", "", "", "", "true", "tab\there", ""},
+	}
+	if !reflect.DeepEqual(b.Text, want) || !reflect.DeepEqual(b.Lines, []int{1, 3}) {
+		t.Fatalf("read %q at %v, want %q at [1 3]", b.Text, b.Lines, want)
+	}
+
+	offset, line := r.Offset(), r.Line()
+	if err := r.SeekTo(offset, line); err != nil {
+		t.Fatal(err)
+	}
+	b, err = r.ReadRows(10)
+	if err != io.EOF || b.Len() != 1 || b.Text[0][7] != "0150" || b.Lines[0] != 4 {
+		t.Fatalf("after seek got %q at %v, %v", b.Text, b.Lines, err)
+	}
+}
+
+func TestJSONLKeepJSON(t *testing.T) {
+	path := writeTemp(t, "events.ndjson", `{"id": 1, "address": {"city": "Oslo", "geo": {"lat": 59.9}}}`)
+
+	r, err := OpenJSONLines(path, []string{"address.geo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, _ := r.ReadRows(1)
+	got := map[string]string{}
+	for i, h := range r.Headers() {
+		got[h] = b.Text[0][i]
+	}
+	want := map[string]string{"id": "1", "address.city": "Oslo", "address.geo": `{"lat":59.9}`}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestJSONLInvalid(t *testing.T) {
+	for _, content := range []string{"", "[1, 2]\n", "{\"id\": 1}\n{\"id\": \n", "{\"id\": 1} {\"id\": 2}\n"} {
+		path := writeTemp(t, "bad.jsonl", content)
+		if r, err := OpenJSONLines(path, nil); err == nil {
+			r.Close()
+			t.Errorf("%q: expected an error", content)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package csvutil
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+	"github.com/shopspring/decimal"
+)
+
+// ParquetSuffix is the extension of Parquet input files.
+const ParquetSuffix = ".parquet"
+
+// julianUnixEpoch is the Julian day number of 1970-01-01, for decoding
+// legacy INT96 timestamps.
+const julianUnixEpoch = 2440588
+
+// ParquetReader reads the rows of a Parquet file as typed values, so they
+// reach the database without a round trip through text. Only flat schemas
+// are supported; nested groups, lists and maps are refused by OpenParquet.
+//
+// A value is nil for NULL, else:
+//   - bool for BOOLEAN
+//   - int64 for INT32, INT64 and the integer logical types
+//   - float64 for FLOAT and DOUBLE
+//   - decimal.Decimal for DECIMAL
+//   - time.Time in UTC for DATE, TIME, TIMESTAMP and legacy INT96 timestamps
+//   - string for STRING, ENUM, JSON and UUID
+//   - []byte for any other binary column
+//
+// Positions are row counts: Offset and Line are the number of rows read so
+// far, and a row's line is its 1-based row number.
+type ParquetReader struct {
+	file    *os.File
+	reader  *parquet.Reader
+	headers []string
+	convert []func(parquet.Value) interface{} // per column
+	buf     []parquet.Row
+	rows    int64
+}
+
+// OpenParquet opens the Parquet file at path and reads its schema.
+func OpenParquet(path string) (*ParquetReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening parquet: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening parquet: %w", err)
+	}
+	pf, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading parquet footer: %w", err)
+	}
+
+	r := &ParquetReader{file: f}
+	for _, field := range pf.Schema().Fields() {
+		if !field.Leaf() || field.Repeated() {
+			f.Close()
+			return nil, fmt.Errorf("parquet column %s: nested and repeated columns are not supported", field.Name())
+		}
+		conv, err := parquetConverter(field.Type())
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("parquet column %s: %w", field.Name(), err)
+		}
+		r.headers = append(r.headers, field.Name())
+		r.convert = append(r.convert, conv)
+	}
+	r.reader = parquet.NewReader(pf)
+	return r, nil
+}
+
+// Headers returns the column names.
+func (r *ParquetReader) Headers() []string {
+	return r.headers
+}
+
+// NumRows returns the number of rows in the file.
+func (r *ParquetReader) NumRows() int64 {
+	return r.reader.NumRows()
+}
+
+// ReadRows reads up to n rows as typed values.
+func (r *ParquetReader) ReadRows(n int) (Batch, error) {
+	if cap(r.buf) < n {
+		r.buf = make([]parquet.Row, n)
+	}
+	buf := r.buf[:n]
+	var b Batch
+	for b.Len() < n {
+		got, err := r.reader.ReadRows(buf[b.Len():])
+		for _, row := range buf[b.Len() : b.Len()+got] {
+			values := make([]interface{}, len(r.headers))
+			for _, v := range row {
+				if c := v.Column(); c < len(values) && !v.IsNull() {
+					values[c] = r.convert[c](v)
+				}
+			}
+			r.rows++
+			b.Values = append(b.Values, values)
+			b.Lines = append(b.Lines, int(r.rows))
+		}
+		if err == io.EOF {
+			return b, io.EOF
+		}
+		if err != nil {
+			return b, fmt.Errorf("reading parquet row %d: %w", r.rows+1, err)
+		}
+	}
+	return b, nil
+}
+
+// Offset returns the number of rows read.
+func (r *ParquetReader) Offset() int64 {
+	return r.rows
+}
+
+// Line returns the row number of the last row read.
+func (r *ParquetReader) Line() int {
+	return int(r.rows)
+}
+
+// SeekTo positions the reader after the first offset rows.
+func (r *ParquetReader) SeekTo(offset int64, _ int) error {
+	if err := r.reader.SeekToRow(offset); err != nil {
+		return fmt.Errorf("seeking parquet to row %d: %w", offset, err)
+	}
+	r.rows = offset
+	return nil
+}
+
+// Compressed returns false: the file records its row count.
+func (r *ParquetReader) Compressed() bool {
+	return false
+}
+
+// Progress returns the number of rows read and the number in the file.
+func (r *ParquetReader) Progress() (done, total int64) {
+	return r.rows, r.NumRows()
+}
+
+// Close closes the file.
+func (r *ParquetReader) Close() error {
+	r.reader.Close()
+	return r.file.Close()
+}
+
+// parquetConverter returns the function converting non-null values of a
+// column of type t to the Go values listed on ParquetReader.
+func parquetConverter(t parquet.Type) (func(parquet.Value) interface{}, error) {
+	lt := t.LogicalType()
+	if lt == nil {
+		lt = &format.LogicalType{}
+	}
+	kind := t.Kind()
+
+	switch {
+	case lt.Decimal != nil:
+		exp := -lt.Decimal.Scale
+		if kind == parquet.Int32 || kind == parquet.Int64 {
+			return func(v parquet.Value) interface{} { return decimal.New(v.Int64(), exp) }, nil
+		}
+		return func(v parquet.Value) interface{} {
+			return decimal.NewFromBigInt(twosComplement(v.ByteArray()), exp)
+		}, nil
+
+	case lt.Date != nil:
+		return func(v parquet.Value) interface{} {
+			return time.Unix(int64(v.Int32())*86400, 0).UTC()
+		}, nil
+
+	case lt.Timestamp != nil:
+		u := lt.Timestamp.Unit
+		return func(v parquet.Value) interface{} {
+			switch {
+			case u.Millis != nil:
+				return time.UnixMilli(v.Int64()).UTC()
+			case u.Micros != nil:
+				return time.UnixMicro(v.Int64()).UTC()
+			}
+			return time.Unix(0, v.Int64()).UTC()
+		}, nil
+
+	case lt.Time != nil:
+		unit := timeUnit(lt.Time.Unit)
+		return func(v parquet.Value) interface{} {
+			// The same zero date time.Parse gives a time of day.
+			return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(v.Int64()) * unit)
+		}, nil
+
+	case lt.UUID != nil:
+		return func(v parquet.Value) interface{} {
+			id, err := uuid.FromBytes(v.ByteArray())
+			if err != nil {
+				return copyBytes(v.ByteArray())
+			}
+			return id.String()
+		}, nil
+
+	case lt.UTF8 != nil, lt.Enum != nil, lt.Json != nil:
+		return func(v parquet.Value) interface{} { return string(v.ByteArray()) }, nil
+
+	case lt.Integer != nil && !lt.Integer.IsSigned:
+		if lt.Integer.BitWidth == 64 {
+			return func(v parquet.Value) interface{} {
+				if u := v.Uint64(); u > math.MaxInt64 {
+					return decimal.NewFromUint64(u)
+				}
+				return v.Int64()
+			}, nil
+		}
+		return func(v parquet.Value) interface{} { return int64(v.Uint32()) }, nil
+
+	case lt.Unknown != nil:
+		// Always NULL.
+		return func(parquet.Value) interface{} { return nil }, nil
+
+	case lt.Float16 != nil, lt.Variant != nil, lt.Geometry != nil, lt.Geography != nil:
+		return nil, fmt.Errorf("logical type %s is not supported", lt)
+	}
+
+	switch kind {
+	case parquet.Boolean:
+		return func(v parquet.Value) interface{} { return v.Boolean() }, nil
+	case parquet.Int32, parquet.Int64:
+		return func(v parquet.Value) interface{} { return v.Int64() }, nil
+	case parquet.Int96:
+		return func(v parquet.Value) interface{} {
+			i := v.Int96()
+			days := int64(i[2]) - julianUnixEpoch
+			return time.Unix(days*86400, 0).Add(time.Duration(i.Int64())).UTC()
+		}, nil
+	case parquet.Float:
+		return func(v parquet.Value) interface{} { return float64(v.Float()) }, nil
+	case parquet.Double:
+		return func(v parquet.Value) interface{} { return v.Double() }, nil
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		return func(v parquet.Value) interface{} { return copyBytes(v.ByteArray()) }, nil
+	}
+	return nil, fmt.Errorf("physical type %s is not supported", kind)
+}
+
+// timeUnit returns the duration of one tick of u.
+func timeUnit(u format.TimeUnit) time.Duration {
+	switch {
+	case u.Millis != nil:
+		return time.Millisecond
+	case u.Micros != nil:
+		return time.Microsecond
+	}
+	return time.Nanosecond
+}
+
+// twosComplement decodes a big-endian two's complement integer, the
+// encoding of binary DECIMAL values.
+func twosComplement(b []byte) *big.Int {
+	n := new(big.Int).SetBytes(b)
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b))*8))
+	}
+	return n
+}
+
+// copyBytes copies b, which the Parquet reader reuses between reads.
+func copyBytes(b []byte) []byte {
+	return append([]byte(nil), b...)
+}
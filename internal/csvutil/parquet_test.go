@@ -0,0 +1,108 @@
+package csvutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/shopspring/decimal"
+)
+
+type parquetRow struct {
+	ID     int64     `parquet:"ID"`
+	Amount int64     `parquet:"AMOUNT,decimal(2:10)"`
+	Big    [16]byte  `parquet:"BIG,decimal(2:38)"`
+	Name   *string   `parquet:"NAME,optional"`
+	Active bool      `parquet:"ACTIVE"`
+	Placed time.Time `parquet:"PLACED,timestamp(microsecond)"`
+	Day    int32     `parquet:"DAY,date"`
+	Blob   []byte    `parquet:"BLOB"`
+}
+
+func TestParquetReader(t *testing.T) {
+	name := "Ann"
+	placed := time.Date(2026, 2, 11, 17, 2, 55, 123456000, time.UTC)
+	var minus150 [16]byte // -1.50 at scale 2, big-endian two's complement
+	for i := range minus150 {
+		minus150[i] = 0xFF
+	}
+	minus150[15] = 0x6A
+	rows := []parquetRow{
+		{ID: 1, Amount: 1999, Big: minus150, Name: &name, Active: true, Placed: placed, Day: 20495, Blob: []byte{1, 2}},
+		{ID: 2},
+		{ID: 3},
+	}
+
+	path := filepath.Join(t.TempDir(), "ORDERS_inserts_1.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := parquet.NewGenericWriter[parquetRow](f, parquet.MaxRowsPerRowGroup(2))
+	if _, err := w.Write(rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	files, err := ScanDirectory(filepath.Dir(path))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("ScanDirectory = %v, %v", files, err)
+	}
+
+	r, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	wantHeaders := []string{"ID", "AMOUNT", "BIG", "NAME", "ACTIVE", "PLACED", "DAY", "BLOB"}
+	if !reflect.DeepEqual(r.Headers(), wantHeaders) {
+		t.Fatalf("headers = %v, want %v", r.Headers(), wantHeaders)
+	}
+
+	b, err := r.ReadRows(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := b.Values[0]
+	want := []interface{}{
+		int64(1),
+		decimal.New(1999, -2),
+		decimal.New(-150, -2),
+		"Ann",
+		true,
+		placed,
+		time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC),
+		[]byte{1, 2},
+	}
+	for i := range want {
+		if d, ok := want[i].(decimal.Decimal); ok {
+			if gd, ok := got[i].(decimal.Decimal); !ok || !gd.Equal(d) {
+				t.Errorf("%s = %v, want %v", wantHeaders[i], got[i], d)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("%s = %#v, want %#v", wantHeaders[i], got[i], want[i])
+		}
+	}
+
+	// Resume past the first row group.
+	if err := r.SeekTo(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	b, err = r.ReadRows(10)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if b.Len() != 1 || b.Values[0][0] != int64(3) || b.Values[0][3] != nil || b.Lines[0] != 3 {
+		t.Fatalf("after seek got %v at %v", b.Values, b.Lines)
+	}
+}
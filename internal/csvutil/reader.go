@@ -4,38 +4,86 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"os"
+	"strings"
 )
 
-// Reader wraps a csv.Reader and provides chunked batch reading.
+// Reader wraps a csv.Reader and provides chunked batch reading. It reads
+// plain CSV files, gzip, zstd and bzip2 compressed ones (.csv.gz, .csv.zst,
+// .csv.bz2) and CSV members of zip archives (see MemberPath), decompressing
+// them as it goes, in the Dialect it was opened with.
 type Reader struct {
-	file    *os.File
-	reader  *csv.Reader
-	headers []string
+	path    string
+	dialect Dialect // with anything detected filled in
+	quote   byte    // swapped with '"' in the input when swapQuote is set, see swapQuotes
+	// swapQuote is set unless the input uses standard double quotes.
+	swapQuote bool
+	src       *source
+	// seekable is set for uncompressed UTF-8 files, whose offsets are file
+	// offsets; other input is reopened and skipped through to seek.
+	seekable bool
+	reader   *csv.Reader
+	headers  []string
+	base     int64 // input offset the current csv.Reader started at
+	lines    int   // lines consumed before the current csv.Reader started
+	line     int   // last line of the most recently read record
 }
 
-// NewReader opens the CSV file and reads the header row.
-func NewReader(path string) (*Reader, error) {
-	f, err := os.Open(path)
+// NewReader opens the CSV file, written in dialect d, and reads the header
+// row.
+func NewReader(path string, d Dialect) (*Reader, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	src, err := openSource(path)
 	if err != nil {
 		return nil, fmt.Errorf("opening csv: %w", err)
 	}
+	text, resolved, bomLen, err := decode(src, d)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("decoding csv: %w", err)
+	}
 
-	r := csv.NewReader(f)
-	r.LazyQuotes = true
-	r.ReuseRecord = false
+	r := &Reader{path: path, dialect: resolved, src: src}
+	r.quote, r.swapQuote, _ = resolved.quote()
+	r.seekable = src.file != nil && resolved.encodingName() == "utf-8"
+	if r.seekable {
+		r.base = int64(bomLen)
+	}
+	r.reader = r.newCSVReader(text)
 
-	headers, err := r.Read()
+	headers, err := r.read()
 	if err != nil {
-		f.Close()
+		src.Close()
 		return nil, fmt.Errorf("reading csv headers: %w", err)
 	}
+	r.headers = headers
+	r.line = recordEndLine(r.reader, headers, 0)
+	return r, nil
+}
+
+func (r *Reader) newCSVReader(text io.Reader) *csv.Reader {
+	cr := csv.NewReader(text)
+	cr.LazyQuotes = true
+	cr.ReuseRecord = false
+	cr.Comma, _ = r.dialect.delimiter()
+	cr.Comment, _ = singleChar("comment", r.dialect.Comment)
+	return cr
+}
+
+// read reads one record, undoing the quote swap.
+func (r *Reader) read() ([]string, error) {
+	record, err := r.reader.Read()
+	if err == nil && r.swapQuote {
+		unswapFields(record, r.quote)
+	}
+	return record, err
+}
 
-	return &Reader{
-		file:    f,
-		reader:  r,
-		headers: headers,
-	}, nil
+// Dialect returns the dialect the file is read in, including any delimiter
+// or encoding that was detected.
+func (r *Reader) Dialect() Dialect {
+	return r.dialect
 }
 
 // Headers returns the CSV column headers.
@@ -46,21 +94,110 @@ func (r *Reader) Headers() []string {
 // ReadBatch reads up to n rows from the CSV. Returns the rows read and io.EOF
 // when the file is exhausted. A final partial batch is returned with io.EOF.
 func (r *Reader) ReadBatch(n int) ([][]string, error) {
+	batch, _, err := r.ReadBatchLines(n)
+	return batch, err
+}
+
+// ReadBatchLines is ReadBatch that also returns the 1-based source line each
+// row starts on, for error reporting.
+func (r *Reader) ReadBatchLines(n int) ([][]string, []int, error) {
 	batch := make([][]string, 0, n)
+	lines := make([]int, 0, n)
 	for range n {
-		record, err := r.reader.Read()
+		record, err := r.read()
 		if err == io.EOF {
-			return batch, io.EOF
+			return batch, lines, io.EOF
 		}
 		if err != nil {
-			return batch, fmt.Errorf("reading csv row: %w", err)
+			return batch, lines, fmt.Errorf("reading csv row: %w", err)
 		}
+		start, _ := r.reader.FieldPos(0)
 		batch = append(batch, record)
+		lines = append(lines, r.lines+start)
+		r.line = recordEndLine(r.reader, record, r.lines)
+	}
+	return batch, lines, nil
+}
+
+// ReadRows is ReadBatchLines returning a Batch, for RowReader.
+func (r *Reader) ReadRows(n int) (Batch, error) {
+	rows, lines, err := r.ReadBatchLines(n)
+	return Batch{Text: rows, Lines: lines}, err
+}
+
+// recordEndLine returns the line the record just read ends on, accounting
+// for newlines embedded in its last (quoted) field.
+func recordEndLine(cr *csv.Reader, record []string, base int) int {
+	if len(record) == 0 {
+		return base
+	}
+	last := len(record) - 1
+	line, _ := cr.FieldPos(last)
+	return base + line + strings.Count(record[last], "\n")
+}
+
+// Offset returns the byte offset in the CSV data just past the last row
+// read. For compressed input this is an offset into the decompressed data.
+func (r *Reader) Offset() int64 {
+	return r.base + r.reader.InputOffset()
+}
+
+// Line returns the last source line of the most recently read row.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Compressed reports whether the input is decompressed while reading, in
+// which case its rows can't be counted cheaply up front.
+func (r *Reader) Compressed() bool {
+	return r.src.file == nil
+}
+
+// Progress returns how many bytes of the input have been read and its total
+// size. For compressed files these count the compressed bytes on disk.
+func (r *Reader) Progress() (done, total int64) {
+	return r.src.counter.n, r.src.size
+}
+
+// SeekTo repositions the reader at offset, which must be a row boundary
+// previously reported by Offset, with line the matching value of Line. The
+// header row is kept from the initial read. Input that can't seek is
+// reopened and read through up to offset.
+func (r *Reader) SeekTo(offset int64, line int) error {
+	var text io.Reader
+	if r.seekable {
+		if _, err := r.src.file.Seek(offset, io.SeekStart); err != nil {
+			return fmt.Errorf("seeking csv to offset %d: %w", offset, err)
+		}
+		r.src.counter.n = offset
+		text = r.src
+		if r.swapQuote {
+			text = quoteSwapReader{r: text, q: r.quote}
+		}
+	} else {
+		src, err := openSource(r.path)
+		if err != nil {
+			return fmt.Errorf("reopening csv: %w", err)
+		}
+		text, _, _, err = decode(src, r.dialect)
+		if err == nil {
+			_, err = io.CopyN(io.Discard, text, offset)
+		}
+		if err != nil {
+			src.Close()
+			return fmt.Errorf("skipping to offset %d: %w", offset, err)
+		}
+		r.src.Close()
+		r.src = src
 	}
-	return batch, nil
+	r.reader = r.newCSVReader(text)
+	r.base = offset
+	r.lines = line
+	r.line = line
+	return nil
 }
 
 // Close closes the underlying file.
 func (r *Reader) Close() error {
-	return r.file.Close()
+	return r.src.Close()
 }
@@ -0,0 +1,69 @@
+package csvutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadBatchLines(t *testing.T) {
+	path := writeTemp(t, "data.csv", "ID,NOTE\n1,a\n2,\"two\nlines\"\n3,c\n")
+
+	r, err := NewReader(path, Dialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	rows, lines, err := r.ReadBatchLines(10)
+	if err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	want := []int{2, 3, 5}
+	for i, l := range lines {
+		if l != want[i] {
+			t.Errorf("row %d: expected line %d, got %d", i, want[i], l)
+		}
+	}
+}
+
+func TestSeekTo(t *testing.T) {
+	path := writeTemp(t, "data.csv", "ID,NOTE\n1,a\n2,\"two\nlines\"\n3,c\n")
+
+	r, err := NewReader(path, Dialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadBatch(2); err != nil {
+		t.Fatal(err)
+	}
+	offset, line := r.Offset(), r.Line()
+	r.Close()
+
+	r, err = NewReader(path, Dialect{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.SeekTo(offset, line); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, lines, _ := r.ReadBatchLines(10)
+	if len(rows) != 1 || rows[0][0] != "3" || lines[0] != 5 {
+		t.Fatalf("expected row 3 at line 5 after seek, got %v at %v", rows, lines)
+	}
+}
@@ -0,0 +1,82 @@
+package csvutil
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// RowReader reads the rows of an input file in batches: CSV and JSON Lines
+// files as text (Reader, JSONLReader), Parquet files and Excel sheets as
+// typed values (ParquetReader, XLSXReader).
+type RowReader interface {
+	// Headers returns the column names.
+	Headers() []string
+	// ReadRows reads up to n rows. It returns io.EOF, along with the final
+	// partial batch if there is one, when the input is exhausted.
+	ReadRows(n int) (Batch, error)
+	// Offset and Line report the position just past the last row read, for
+	// SeekTo.
+	Offset() int64
+	Line() int
+	SeekTo(offset int64, line int) error
+	// Compressed reports whether the rows can't be counted cheaply up front,
+	// so progress is measured by Progress instead.
+	Compressed() bool
+	Progress() (done, total int64)
+	Close() error
+}
+
+// Batch is a run of rows read by a RowReader. Text holds the fields of CSV
+// rows; Values holds rows of typed values from formats that carry types.
+// Only one of them is set.
+type Batch struct {
+	Text   [][]string
+	Values [][]interface{}
+	// Lines holds the source line each row starts on, or for formats
+	// without lines, its 1-based row number.
+	Lines []int
+}
+
+// Len returns the number of rows in b.
+func (b Batch) Len() int {
+	return len(b.Lines)
+}
+
+// Options holds the format-specific settings Open passes on to readers.
+type Options struct {
+	// Dialect is how CSV files are written.
+	Dialect Dialect
+	// KeepJSON lists the nested objects of JSON Lines files to read whole as
+	// JSON text; see OpenJSONLines.
+	KeepJSON []string
+	// HeaderRow is the number of the header row of Excel sheets, or 0 to
+	// detect it; see XLSXReader.
+	HeaderRow int
+}
+
+// Open opens the input file at path for reading its rows: a ParquetReader
+// for .parquet files, a JSONLReader for .jsonl and .ndjson files, an
+// XLSXReader for workbooks and their sheets, else a Reader for CSV.
+func Open(path string, opts Options) (RowReader, error) {
+	var r RowReader
+	var err error
+	switch {
+	case IsParquet(path):
+		r, err = OpenParquet(path)
+	case IsJSONLines(path):
+		r, err = OpenJSONLines(path, opts.KeepJSON)
+	case IsWorkbook(path) || IsSheet(path):
+		r, err = OpenSheet(path, opts.HeaderRow)
+	default:
+		r, err = NewReader(path, opts.Dialect)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// IsParquet reports whether p names a Parquet file.
+func IsParquet(p string) bool {
+	return strings.EqualFold(filepath.Ext(p), ParquetSuffix)
+}
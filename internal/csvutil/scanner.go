@@ -7,8 +7,33 @@ import (
 	"strings"
 )
 
-// ScanDirectory returns all .csv file paths found in dir (non-recursive).
-func ScanDirectory(dir string) ([]string, error) {
+// RejectsSuffix is appended (in place of .csv) to name the file holding rows
+// the database refused during an import.
+const RejectsSuffix = ".rejects.csv"
+
+// DoneSuffix names the marker file (e.g. orders.csv.done) a producer can
+// create to signal that a CSV file has been completely written.
+const DoneSuffix = ".done"
+
+// IsInputFile reports whether name is a file to import: a .csv file (not a
+// rejects file), a compressed .csv.gz, .csv.zst or .csv.bz2, a .zip archive
+// of CSV files, a .parquet file, a .jsonl or .ndjson file, or an .xlsx
+// workbook.
+func IsInputFile(name string) bool {
+	if strings.HasSuffix(strings.ToLower(name), RejectsSuffix) {
+		return false
+	}
+	return strings.EqualFold(filepath.Ext(name), ".csv") ||
+		compressionSuffix(name) != "" ||
+		strings.EqualFold(filepath.Ext(name), ZipSuffix) ||
+		IsParquet(name) ||
+		IsJSONLines(name) ||
+		IsWorkbook(name)
+}
+
+// ListFiles returns the input files in dir (non-recursive) as they are on
+// disk, without looking inside zip archives or workbooks.
+func ListFiles(dir string) ([]string, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, fmt.Errorf("reading directory %s: %w", dir, err)
@@ -16,12 +41,42 @@ func ScanDirectory(dir string) ([]string, error) {
 
 	var files []string
 	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		if strings.EqualFold(filepath.Ext(e.Name()), ".csv") {
+		if !e.IsDir() && IsInputFile(e.Name()) {
 			files = append(files, filepath.Join(dir, e.Name()))
 		}
 	}
 	return files, nil
 }
+
+// Expand returns the input files p holds: the members of a zip archive or
+// the visible sheets of a workbook (see MemberPath), or p itself.
+func Expand(p string) ([]string, error) {
+	if strings.EqualFold(filepath.Ext(p), ZipSuffix) {
+		return ArchiveMembers(p)
+	}
+	if IsWorkbook(p) {
+		return WorkbookSheets(p)
+	}
+	return []string{p}, nil
+}
+
+// ScanDirectory returns the CSV, Parquet, JSON Lines and Excel files found in
+// dir (non-recursive). Each CSV member of a zip archive and each sheet of a
+// workbook is listed as its own file. Rejects files written by earlier
+// imports are left out.
+func ScanDirectory(dir string) ([]string, error) {
+	paths, err := ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, p := range paths {
+		expanded, err := Expand(p)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, expanded...)
+	}
+	return files, nil
+}
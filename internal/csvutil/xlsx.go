@@ -0,0 +1,798 @@
+package csvutil
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXSuffix is the extension of Excel workbooks.
+const XLSXSuffix = ".xlsx"
+
+// headerScanRows is how many non-empty rows are examined to find the header
+// row of a sheet.
+const headerScanRows = 20
+
+// IsWorkbook reports whether p names an Excel workbook.
+func IsWorkbook(p string) bool {
+	return strings.EqualFold(filepath.Ext(p), XLSXSuffix)
+}
+
+// IsSheet reports whether p names a sheet of a workbook, e.g.
+// "in/book.xlsx!Orders" (see MemberPath).
+func IsSheet(p string) bool {
+	archive, _, ok := SplitMember(p)
+	return ok && IsWorkbook(archive)
+}
+
+// sheetInfo is a worksheet listed in a workbook.
+type sheetInfo struct {
+	name   string
+	part   string // zip entry holding the sheet, e.g. "xl/worksheets/sheet1.xml"
+	hidden bool
+}
+
+// workbook is an open .xlsx file.
+type workbook struct {
+	zr       *zip.ReadCloser
+	sheets   []sheetInfo
+	date1904 bool
+	strings  []string // shared strings
+	dates    []bool   // per cell style: whether it formats numbers as dates
+}
+
+// openWorkbook opens the workbook at p and lists its sheets.
+func openWorkbook(p string) (*workbook, error) {
+	zr, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, fmt.Errorf("opening workbook %s: %w", filepath.Base(p), err)
+	}
+	wb := &workbook{zr: zr}
+	if err := wb.readSheets(); err != nil {
+		zr.Close()
+		return nil, fmt.Errorf("reading workbook %s: %w", filepath.Base(p), err)
+	}
+	return wb, nil
+}
+
+// file returns the zip entry called name, or nil.
+func (wb *workbook) file(name string) *zip.File {
+	for _, f := range wb.zr.File {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// decodePart unmarshals the XML in the zip entry called name into v. A
+// missing entry is an error unless optional.
+func (wb *workbook) decodePart(name string, v interface{}, optional bool) error {
+	f := wb.file(name)
+	if f == nil {
+		if optional {
+			return nil
+		}
+		return fmt.Errorf("%s is missing", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// readSheets reads the sheet list and date system from xl/workbook.xml.
+func (wb *workbook) readSheets() error {
+	var doc struct {
+		Pr struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name  string     `xml:"name,attr"`
+			State string     `xml:"state,attr"`
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := wb.decodePart("xl/workbook.xml", &doc, false); err != nil {
+		return err
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:"Type,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := wb.decodePart("xl/_rels/workbook.xml.rels", &rels, false); err != nil {
+		return err
+	}
+
+	wb.date1904 = doc.Pr.Date1904
+	for _, s := range doc.Sheets {
+		var id string
+		for _, a := range s.Attrs {
+			// r:id, in whichever namespace the workbook uses.
+			if a.Name.Local == "id" {
+				id = a.Value
+			}
+		}
+		for _, rel := range rels.Rels {
+			if rel.ID != id || !strings.HasSuffix(rel.Type, "/worksheet") {
+				continue
+			}
+			part := strings.TrimPrefix(rel.Target, "/")
+			if !strings.HasPrefix(rel.Target, "/") {
+				part = path.Join("xl", rel.Target)
+			}
+			wb.sheets = append(wb.sheets, sheetInfo{name: s.Name, part: part, hidden: s.State != "" && s.State != "visible"})
+		}
+	}
+	return nil
+}
+
+// readStyles loads the shared strings and the date styles, which the cells
+// of every sheet refer to.
+func (wb *workbook) readStyles() error {
+	if f := wb.file("xl/sharedStrings.xml"); f != nil {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		wb.strings, err = readSharedStrings(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("xl/sharedStrings.xml: %w", err)
+		}
+	}
+
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := wb.decodePart("xl/styles.xml", &styles, true); err != nil {
+		return err
+	}
+	custom := map[int]string{}
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = f.Code
+	}
+	wb.dates = make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		if code, ok := custom[xf.NumFmtID]; ok {
+			wb.dates[i] = isDateFormat(code)
+		} else {
+			wb.dates[i] = isDateFormatID(xf.NumFmtID)
+		}
+	}
+	return nil
+}
+
+// readSharedStrings reads the string table: the text of each <si>, with
+// rich-text runs joined and phonetic hints (<rPh>) left out.
+func readSharedStrings(r io.Reader) ([]string, error) {
+	dec := xml.NewDecoder(r)
+	var table []string
+	var text strings.Builder
+	var inText, inPhonetic bool
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				text.Reset()
+			case "t":
+				inText = !inPhonetic
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				table = append(table, text.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+	}
+}
+
+// isDateFormatID reports whether a built-in number format shows a date or
+// time, counting the locale-specific date formats.
+func isDateFormatID(id int) bool {
+	return id >= 14 && id <= 22 || id >= 27 && id <= 36 || id >= 45 && id <= 47 || id >= 50 && id <= 58
+}
+
+// isDateFormat reports whether a custom number format code shows a date or
+// time: whether it has a date or time token outside quoted text, escaped
+// characters and bracketed sections like colors.
+func isDateFormat(code string) bool {
+	var b strings.Builder
+	for i := 0; i < len(code); i++ {
+		switch c := code[i]; c {
+		case '"':
+			if j := strings.IndexByte(code[i+1:], '"'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(code)
+			}
+		case '\\', '_', '*':
+			i++ // the next character is literal, padding or fill
+		case '[':
+			j := strings.IndexByte(code[i:], ']')
+			if j < 0 {
+				return false
+			}
+			// Elapsed time, e.g. [h]:mm, is still a time.
+			if inner := strings.ToLower(code[i+1 : i+j]); inner != "" && strings.Trim(inner, "hms") == "" {
+				return true
+			}
+			i += j
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return strings.ContainsAny(strings.ToLower(b.String()), "ymdhs")
+}
+
+// WorkbookSheets returns the MemberPath of every visible, non-blank sheet in
+// the workbook at p, in workbook order. Blank sheets, like the spare ones new
+// workbooks come with, are left out.
+func WorkbookSheets(p string) ([]string, error) {
+	wb, err := openWorkbook(p)
+	if err != nil {
+		return nil, err
+	}
+	defer wb.zr.Close()
+
+	var sheets []string
+	for _, s := range wb.sheets {
+		if s.hidden {
+			continue
+		}
+		blank, err := wb.blank(s)
+		if err != nil {
+			return nil, fmt.Errorf("reading workbook %s: sheet %s: %w", filepath.Base(p), s.name, err)
+		}
+		if !blank {
+			sheets = append(sheets, MemberPath(p, s.name))
+		}
+	}
+	return sheets, nil
+}
+
+// blank reports whether sheet s has no cell values, reading only up to its
+// first. Cells that are only formatted have no value.
+func (wb *workbook) blank(s sheetInfo) (bool, error) {
+	f := wb.file(s.part)
+	if f == nil {
+		return false, fmt.Errorf("%s is missing", s.part)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return false, err
+	}
+	defer rc.Close()
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if start, ok := tok.(xml.StartElement); ok && (start.Name.Local == "v" || start.Name.Local == "is") {
+			return false, nil
+		}
+	}
+}
+
+// XLSXReader streams the rows of one sheet of an Excel workbook as typed
+// values, so large sheets are never held in memory. The header row is the
+// first of the leading rows that has only text cells and spans at least half
+// as many columns as the widest of them, which skips title rows above the
+// table; or the row given to OpenSheet. Header cells left empty are named by
+// their column letter.
+//
+// A value is nil for an empty cell, else:
+//   - string for text, formula text and error values such as "#N/A"
+//   - bool for TRUE and FALSE
+//   - int64 for whole numbers, float64 for others
+//   - time.Time in UTC for numbers formatted as dates or times
+//
+// Positions are Excel row numbers: Offset and Line are the number of the
+// last row read, a row's line is its number in the sheet, and empty rows are
+// skipped.
+type XLSXReader struct {
+	wb        *workbook
+	sheet     sheetInfo
+	rc        io.ReadCloser
+	dec       *xml.Decoder
+	headers   []string
+	first     int // column index of the first header
+	headerRow int
+	lastRow   int // from the sheet's dimension, 0 if not recorded
+	row       int
+	pending   []xlsxRow // rows read while finding the header
+}
+
+// xlsxRow is a row of cell values by column index.
+type xlsxRow struct {
+	num   int
+	cells map[int]interface{}
+}
+
+// xlsxCell is a <c> element of a sheet.
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Style  int    `xml:"s,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+// OpenSheet opens the sheet p names ("book.xlsx!Orders") or, given a plain
+// workbook path, its first visible sheet. headerRow is the number of the
+// header row, or 0 to detect it.
+func OpenSheet(p string, headerRow int) (*XLSXReader, error) {
+	file, name, named := SplitMember(p)
+	if !named {
+		file = p
+	}
+	wb, err := openWorkbook(file)
+	if err != nil {
+		return nil, err
+	}
+	r := &XLSXReader{wb: wb}
+	if err := r.open(name, named, headerRow); err != nil {
+		wb.zr.Close()
+		return nil, fmt.Errorf("%s: %w", DisplayName(p), err)
+	}
+	return r, nil
+}
+
+func (r *XLSXReader) open(name string, named bool, headerRow int) error {
+	found := false
+	for _, s := range r.wb.sheets {
+		if named && s.name == name || !named && !s.hidden {
+			r.sheet, found = s, true
+			break
+		}
+	}
+	if !found {
+		if named {
+			return fmt.Errorf("no sheet named %q", name)
+		}
+		return errors.New("workbook has no visible sheets")
+	}
+	if err := r.wb.readStyles(); err != nil {
+		return err
+	}
+	if err := r.restart(); err != nil {
+		return err
+	}
+	return r.findHeader(headerRow)
+}
+
+// restart opens the sheet's XML from the beginning.
+func (r *XLSXReader) restart() error {
+	if r.rc != nil {
+		r.rc.Close()
+	}
+	f := r.wb.file(r.sheet.part)
+	if f == nil {
+		return fmt.Errorf("%s is missing", r.sheet.part)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	r.rc, r.dec, r.row = rc, xml.NewDecoder(rc), 0
+	return nil
+}
+
+// findHeader reads rows up to the header row and sets the headers. Rows read
+// past it are kept in pending.
+func (r *XLSXReader) findHeader(headerRow int) error {
+	var sample []xlsxRow
+	for len(sample) < headerScanRows {
+		row, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if headerRow > 0 && row.num < headerRow {
+			continue
+		}
+		sample = append(sample, row)
+		if headerRow > 0 {
+			break
+		}
+	}
+	if len(sample) == 0 || headerRow > 0 && sample[0].num != headerRow {
+		if headerRow > 0 {
+			return fmt.Errorf("header row %d is empty", headerRow)
+		}
+		return errors.New("sheet is empty")
+	}
+
+	header := 0
+	if headerRow == 0 {
+		widest := 0
+		for _, row := range sample {
+			widest = max(widest, len(row.cells))
+		}
+		for i, row := range sample {
+			if allText(row) && len(row.cells)*2 >= widest {
+				header = i
+				break
+			}
+		}
+	}
+
+	h := sample[header]
+	first, last := math.MaxInt, 0
+	for c := range h.cells {
+		first, last = min(first, c), max(last, c)
+	}
+	r.first, r.headerRow = first, h.num
+	for c := first; c <= last; c++ {
+		name := strings.TrimSpace(cellText(h.cells[c]))
+		if name == "" {
+			name = columnName(c)
+		}
+		r.headers = append(r.headers, name)
+	}
+	r.pending = sample[header+1:]
+	r.row = r.headerRow
+	return nil
+}
+
+// allText reports whether every cell of row holds text.
+func allText(row xlsxRow) bool {
+	for _, v := range row.cells {
+		if _, ok := v.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// cellText formats a cell value as header text.
+func cellText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return v.Format(time.DateOnly)
+	}
+	return fmt.Sprint(v)
+}
+
+// next reads the next row with at least one value.
+func (r *XLSXReader) next() (xlsxRow, error) {
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return xlsxRow{}, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "dimension":
+			for _, a := range start.Attr {
+				if a.Name.Local == "ref" {
+					_, end, _ := strings.Cut(a.Value, ":")
+					_, r.lastRow, _ = splitRef(end)
+				}
+			}
+		case "row":
+			row, err := r.readRow(start)
+			if err != nil {
+				return row, err
+			}
+			if len(row.cells) > 0 {
+				return row, nil
+			}
+		}
+	}
+}
+
+// readRow reads the cells of the <row> element that starts with start.
+func (r *XLSXReader) readRow(start xml.StartElement) (xlsxRow, error) {
+	row := xlsxRow{num: r.row + 1, cells: map[int]interface{}{}}
+	for _, a := range start.Attr {
+		if a.Name.Local == "r" {
+			if n, err := strconv.Atoi(a.Value); err == nil {
+				row.num = n
+			}
+		}
+	}
+	r.row = row.num
+
+	col := -1
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return row, fmt.Errorf("row %d: %w", row.num, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return row, nil
+			}
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			var c xlsxCell
+			if err := r.dec.DecodeElement(&c, &t); err != nil {
+				return row, fmt.Errorf("row %d: %w", row.num, err)
+			}
+			col++
+			if c.Ref != "" {
+				if col, _, err = splitRef(c.Ref); err != nil {
+					return row, fmt.Errorf("row %d: %w", row.num, err)
+				}
+			}
+			v, err := r.value(c)
+			if err != nil {
+				return row, fmt.Errorf("cell %s: %w", columnName(col)+strconv.Itoa(row.num), err)
+			}
+			if v != nil {
+				row.cells[col] = v
+			}
+		}
+	}
+}
+
+// value returns the typed value of c; see XLSXReader.
+func (r *XLSXReader) value(c xlsxCell) (interface{}, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(r.wb.strings) {
+			return nil, fmt.Errorf("bad shared string index %q", c.Value)
+		}
+		return r.wb.strings[i], nil
+	case "inlineStr":
+		text := c.Inline.Text
+		for _, run := range c.Inline.Runs {
+			text += run.Text
+		}
+		return text, nil
+	case "str", "e":
+		return c.Value, nil
+	case "b":
+		return c.Value == "1", nil
+	case "d":
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", time.DateOnly} {
+			if t, err := time.Parse(layout, c.Value); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return nil, fmt.Errorf("bad date %q", c.Value)
+	}
+
+	if c.Value == "" {
+		return nil, nil
+	}
+	if c.Style >= 0 && c.Style < len(r.wb.dates) && r.wb.dates[c.Style] {
+		serial, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", c.Value)
+		}
+		return excelTime(serial, r.wb.date1904), nil
+	}
+	if n, err := strconv.ParseInt(c.Value, 10, 64); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("bad number %q", c.Value)
+	}
+	return f, nil
+}
+
+// excelTime converts an Excel date serial number (days since the epoch,
+// with the time of day as the fraction) to a time, rounded to the
+// millisecond.
+func excelTime(serial float64, date1904 bool) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	switch {
+	case date1904:
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	case serial < 61:
+		// Excel counts a 29 February 1900 that never was.
+		epoch = epoch.AddDate(0, 0, 1)
+	}
+	days := math.Floor(serial)
+	ms := math.Round((serial - days) * 24 * 60 * 60 * 1000)
+	return epoch.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond)
+}
+
+// splitRef splits a cell reference like "AB12" into its 0-based column index
+// and row number.
+func splitRef(ref string) (col, row int, err error) {
+	i := 0
+	col = 0
+	for ; i < len(ref); i++ {
+		c := ref[i] | 0x20 // lower case
+		if c < 'a' || c > 'z' {
+			break
+		}
+		col = col*26 + int(c-'a'+1)
+	}
+	if i == 0 {
+		return 0, 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	if i < len(ref) {
+		if row, err = strconv.Atoi(ref[i:]); err != nil {
+			return 0, 0, fmt.Errorf("bad cell reference %q", ref)
+		}
+	}
+	return col - 1, row, nil
+}
+
+// columnName returns the letters of the 0-based column index c: 0 → "A",
+// 27 → "AB".
+func columnName(c int) string {
+	var b []byte
+	for c++; c > 0; c = (c - 1) / 26 {
+		b = append([]byte{byte('A' + (c-1)%26)}, b...)
+	}
+	return string(b)
+}
+
+// Headers returns the column names from the header row.
+func (r *XLSXReader) Headers() []string {
+	return r.headers
+}
+
+// Sheet returns the name of the sheet being read.
+func (r *XLSXReader) Sheet() string {
+	return r.sheet.name
+}
+
+// HeaderRow returns the row number the headers were taken from.
+func (r *XLSXReader) HeaderRow() int {
+	return r.headerRow
+}
+
+// NumRows returns the number of rows below the header row, counting empty
+// ones, if the sheet records its dimensions; else 0.
+func (r *XLSXReader) NumRows() int64 {
+	return int64(max(r.lastRow-r.headerRow, 0))
+}
+
+// ReadRows reads up to n non-empty rows as typed values.
+func (r *XLSXReader) ReadRows(n int) (Batch, error) {
+	var b Batch
+	for b.Len() < n {
+		var row xlsxRow
+		if len(r.pending) > 0 {
+			row, r.pending = r.pending[0], r.pending[1:]
+		} else {
+			var err error
+			if row, err = r.next(); err == io.EOF {
+				return b, io.EOF
+			} else if err != nil {
+				return b, fmt.Errorf("reading sheet %s: %w", r.sheet.name, err)
+			}
+		}
+		values := make([]interface{}, len(r.headers))
+		empty := true
+		for i := range values {
+			if v, ok := row.cells[r.first+i]; ok {
+				values[i], empty = v, false
+			}
+		}
+		if empty {
+			continue
+		}
+		b.Values = append(b.Values, values)
+		b.Lines = append(b.Lines, row.num)
+	}
+	return b, nil
+}
+
+// Offset returns the number of the last row read.
+func (r *XLSXReader) Offset() int64 {
+	if len(r.pending) > 0 {
+		return int64(r.pending[0].num - 1)
+	}
+	return int64(r.row)
+}
+
+// Line returns the number of the last row read.
+func (r *XLSXReader) Line() int {
+	return int(r.Offset())
+}
+
+// SeekTo positions the reader after row number offset. The sheet is read
+// again from the top, since its XML can't be seeked.
+func (r *XLSXReader) SeekTo(offset int64, _ int) error {
+	offset = max(offset, int64(r.headerRow))
+	r.pending = nil
+	if err := r.restart(); err != nil {
+		return fmt.Errorf("seeking sheet %s: %w", r.sheet.name, err)
+	}
+	for int64(r.row) < offset {
+		row, err := r.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("seeking sheet %s to row %d: %w", r.sheet.name, offset, err)
+		}
+		if int64(row.num) > offset {
+			r.pending = []xlsxRow{row}
+		}
+	}
+	return nil
+}
+
+// Compressed returns false: the sheet records its size, when it does, up
+// front.
+func (r *XLSXReader) Compressed() bool {
+	return false
+}
+
+// Progress returns the rows read and the rows in the sheet, both counted
+// from the header row.
+func (r *XLSXReader) Progress() (done, total int64) {
+	done = max(r.Offset()-int64(r.headerRow), 0)
+	return done, max(r.NumRows(), done)
+}
+
+// Close closes the workbook.
+func (r *XLSXReader) Close() error {
+	if r.rc != nil {
+		r.rc.Close()
+	}
+	return r.wb.zr.Close()
+}
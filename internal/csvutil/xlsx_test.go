@@ -0,0 +1,142 @@
+package csvutil
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeWorkbook writes an .xlsx file of the given parts, adding the
+// workbook, its relationships and the sheets listed in sheets (name → XML).
+func writeWorkbook(t *testing.T, path string, sheets [][2]string, parts map[string]string) {
+	t.Helper()
+	workbook := `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`
+	rels := `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+	all := map[string]string{}
+	for k, v := range parts {
+		all[k] = v
+	}
+	for i, s := range sheets {
+		id := string(rune('1' + i))
+		state := ""
+		if s[0] == "Hidden" {
+			state = ` state="hidden"`
+		}
+		workbook += `<sheet name="` + s[0] + `" sheetId="` + id + `"` + state + ` r:id="rId` + id + `"/>`
+		rels += `<Relationship Id="rId` + id + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + id + `.xml"/>`
+		all["xl/worksheets/sheet"+id+".xml"] = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` + s[1] + `</worksheet>`
+	}
+	all["xl/workbook.xml"] = workbook + `</sheets></workbook>`
+	all["xl/_rels/workbook.xml.rels"] = rels + `</Relationships>`
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range all {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+}
+
+func TestXLSXReader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ORDERS_inserts_1.xlsx")
+	orders := `<dimension ref="A1:E7"/><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c></row>
+<row r="3"><c r="B3" t="s"><v>1</v></c><c r="C3" t="inlineStr"><is><t>Placed</t></is></c><c r="E3" t="s"><v>2</v></c></row>
+<row r="4"><c r="B4"><v>1</v></c><c r="C4" s="1"><v>46064.5</v></c><c r="D4" t="b"><v>1</v></c><c r="E4" t="s"><v>3</v></c></row>
+<row r="5"><c r="B5"><v>2.25</v></c><c r="E5" t="e"><v>#N/A</v></c></row>
+<row r="6"></row>
+<row r="7"><c r="B7"><v>3</v></c><c r="C7" s="2"><v>46064</v></c></row>
+</sheetData>`
+	writeWorkbook(t, path, [][2]string{{"Orders", orders}, {"Hidden", `<sheetData/>`}, {"Empty", `<sheetData/>`}}, map[string]string{
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>Order export</t></si><si><t>ID</t></si><si><r><t>Cust</t></r><r><t>omer</t></r><rPh><t>x</t></rPh></si><si><t>Ann</t></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd hh:mm"/><numFmt numFmtId="165" formatCode="&quot;day&quot; 0.00;[Red]0"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="164"/><xf numFmtId="14"/></cellXfs></styleSheet>`,
+	})
+
+	files, err := ScanDirectory(dir)
+	want := []string{MemberPath(path, "Orders")}
+	if err != nil || !reflect.DeepEqual(files, want) {
+		t.Fatalf("ScanDirectory = %v, %v; want %v", files, err, want)
+	}
+	if got := LogicalName(files[0]); got != "ORDERS_inserts_1_Orders.xlsx" {
+		t.Errorf("LogicalName = %q", got)
+	}
+
+	r, err := Open(files[0], Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	wantHeaders := []string{"ID", "Placed", "D", "Customer"}
+	if !reflect.DeepEqual(r.Headers(), wantHeaders) {
+		t.Fatalf("headers = %v, want %v", r.Headers(), wantHeaders)
+	}
+	b, err := r.ReadRows(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][]interface{}{
+		{int64(1), time.Date(2026, 2, 11, 12, 0, 0, 0, time.UTC), true, "Ann"},
+		{2.25, nil, nil, "#N/A"},
+	}
+	if !reflect.DeepEqual(b.Values, wantRows) || !reflect.DeepEqual(b.Lines, []int{4, 5}) {
+		t.Fatalf("read %v at %v, want %v at [4 5]", b.Values, b.Lines, wantRows)
+	}
+
+	if err := r.SeekTo(r.Offset(), r.Line()); err != nil {
+		t.Fatal(err)
+	}
+	b, err = r.ReadRows(10)
+	if err != io.EOF || b.Len() != 1 || b.Lines[0] != 7 || b.Values[0][1] != time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC) {
+		t.Fatalf("after seek got %v at %v, %v", b.Values, b.Lines, err)
+	}
+
+	if _, err := Open(MemberPath(path, "Empty"), Options{}); err == nil {
+		t.Error("expected an error for an empty sheet")
+	}
+	hr, err := Open(files[0], Options{HeaderRow: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hr.Close()
+	if got := hr.Headers(); !reflect.DeepEqual(got, []string{"1", "2026-02-11", "true", "Ann"}) {
+		t.Errorf("headers from row 4 = %v", got)
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	for code, want := range map[string]bool{
+		"yyyy-mm-dd":       true,
+		"[h]:mm:ss":        true,
+		`d\-mmm`:           true,
+		"#,##0.00":         false,
+		`"days" 0`:         false,
+		"0.00_);[Red](0)":  false,
+		"General":          false,
+		`[$-409]mmmm yyyy`: true,
+	} {
+		if got := isDateFormat(code); got != want {
+			t.Errorf("isDateFormat(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -12,16 +12,21 @@ import (
 
 // InsertBatch performs an upsert of rows into the target table using the
 // temp-table + MERGE pattern. If pkColumns is empty, it falls back to a
-// straight bulk copy (insert-only).
-func InsertBatch(ctx context.Context, db *sql.DB, schemaTable string, columns []string, pkColumns []string, hasIdentity bool, rows [][]interface{}) error {
-	if len(pkColumns) == 0 {
-		return insertBatchDirect(ctx, db, schemaTable, columns, rows)
+// straight bulk copy (insert-only), unless some columns are staged as text
+// (see textStagedColumns), which then go through the temp table too.
+func (s *SQLServer) InsertBatch(ctx context.Context, schemaTable string, columns []string, pkColumns []string, hasIdentity bool, rows [][]interface{}) error {
+	staged, err := s.textStagedColumns(ctx, schemaTable, columns)
+	if err != nil {
+		return err
+	}
+	if len(pkColumns) == 0 && len(staged) == 0 {
+		return s.insertBatchDirect(ctx, schemaTable, columns, rows)
 	}
 
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	tx, err := db.BeginTx(ctx, nil)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
@@ -29,6 +34,9 @@ func InsertBatch(ctx context.Context, db *sql.DB, schemaTable string, columns []
 
 	// 1. Create temp table matching target schema (no constraints).
 	createTemp := fmt.Sprintf("SELECT TOP(0) * INTO #temp FROM %s", schemaTable)
+	if len(staged) > 0 {
+		createTemp = fmt.Sprintf("SELECT TOP(0) %s INTO #temp FROM %s", stagedSelectList(columns, staged), schemaTable)
+	}
 	if _, err := tx.ExecContext(ctx, createTemp); err != nil {
 		return fmt.Errorf("create temp table: %w", err)
 	}
@@ -55,7 +63,16 @@ func InsertBatch(ctx context.Context, db *sql.DB, schemaTable string, columns []
 			return fmt.Errorf("identity insert on: %w", err)
 		}
 	}
-	mergeSQL := buildMergeSQL(schemaTable, columns, pkColumns)
+	var mergeSQL string
+	switch {
+	case len(pkColumns) == 0:
+		mergeSQL = buildInsertSelect(schemaTable, stagedSource("#temp", columns, staged), columns)
+	case len(staged) > 0:
+		mergeSQL = buildMerge(schemaTable+" AS target", stagedSource("#temp", columns, staged), columns, pkColumns, false)
+	default:
+		mergeSQL = buildMergeSQL(schemaTable, columns, pkColumns)
+	}
+	s.logger.Debug("merge", "table", schemaTable, "rows", len(rows), "sql", mergeSQL)
 	if _, err := tx.ExecContext(ctx, mergeSQL); err != nil {
 		return fmt.Errorf("merge: %w", err)
 	}
@@ -79,11 +96,11 @@ func InsertBatch(ctx context.Context, db *sql.DB, schemaTable string, columns []
 }
 
 // insertBatchDirect is the original straight bulk-copy path for tables without a PK.
-func insertBatchDirect(ctx context.Context, db *sql.DB, schemaTable string, columns []string, rows [][]interface{}) error {
+func (s *SQLServer) insertBatchDirect(ctx context.Context, schemaTable string, columns []string, rows [][]interface{}) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	tx, err := db.BeginTx(ctx, nil)
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
@@ -114,6 +131,132 @@ func insertBatchDirect(ctx context.Context, db *sql.DB, schemaTable string, colu
 
 // buildMergeSQL constructs a MERGE statement that upserts from #temp into the target table.
 func buildMergeSQL(schemaTable string, columns, pkColumns []string) string {
+	return buildMerge(schemaTable+" AS target", "#temp", columns, pkColumns, false)
+}
+
+// buildInsertSelect constructs an INSERT of every row of source into the target table.
+func buildInsertSelect(schemaTable, source string, columns []string) string {
+	colList := make([]string, len(columns))
+	for i, col := range columns {
+		colList[i] = fmt.Sprintf("[%s]", col)
+	}
+	cols := strings.Join(colList, ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s AS source;", schemaTable, cols, cols, source)
+}
+
+// textStagedTypes are the column types bulk copy can't load. Their values
+// are staged as nvarchar(max) text and converted by stagedValue on the way
+// into the table.
+var textStagedTypes = map[string]bool{
+	"xml":         true,
+	"geography":   true,
+	"geometry":    true,
+	"hierarchyid": true,
+}
+
+// textStagedColumns returns the data types of the given columns of
+// schemaTable that are staged as text, keyed by upper-cased column name.
+// The types of each table are looked up once.
+func (s *SQLServer) textStagedColumns(ctx context.Context, schemaTable string, columns []string) (map[string]string, error) {
+	all, ok := s.staged.Load(schemaTable)
+	if !ok {
+		schema, table := SplitSchemaTable(schemaTable)
+		query := `SELECT c.name, ty.name FROM sys.columns c
+			JOIN sys.tables t ON c.object_id = t.object_id
+			JOIN sys.schemas s ON t.schema_id = s.schema_id
+			JOIN sys.types ty ON c.user_type_id = ty.user_type_id
+			WHERE s.name = @schema AND t.name = @table
+				AND ty.name IN ('xml', 'geography', 'geometry', 'hierarchyid')`
+
+		qctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+
+		rows, err := s.db.QueryContext(qctx, query, sql.Named("schema", schema), sql.Named("table", table))
+		if err != nil {
+			return nil, fmt.Errorf("querying column types: %w", err)
+		}
+		defer rows.Close()
+
+		types := make(map[string]string)
+		for rows.Next() {
+			var col, dataType string
+			if err := rows.Scan(&col, &dataType); err != nil {
+				return nil, fmt.Errorf("scanning column type: %w", err)
+			}
+			types[strings.ToUpper(col)] = strings.ToLower(dataType)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("querying column types: %w", err)
+		}
+		all, _ = s.staged.LoadOrStore(schemaTable, types)
+	}
+
+	staged := make(map[string]string)
+	for _, col := range columns {
+		if dataType, ok := all.(map[string]string)[strings.ToUpper(col)]; ok && textStagedTypes[dataType] {
+			staged[strings.ToUpper(col)] = dataType
+		}
+	}
+	return staged, nil
+}
+
+// stagedSelectList returns the SELECT list creating a staging table for
+// columns, in which the staged ones are nvarchar(max).
+func stagedSelectList(columns []string, staged map[string]string) string {
+	list := make([]string, len(columns))
+	for i, col := range columns {
+		if _, ok := staged[strings.ToUpper(col)]; ok {
+			list[i] = fmt.Sprintf("CAST(NULL AS nvarchar(max)) AS [%s]", col)
+		} else {
+			list[i] = fmt.Sprintf("[%s]", col)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// stagedSource returns table as a MERGE or INSERT source, wrapped in a
+// derived table converting the staged columns if there are any.
+func stagedSource(table string, columns []string, staged map[string]string) string {
+	if len(staged) == 0 {
+		return table
+	}
+	list := make([]string, len(columns))
+	for i, col := range columns {
+		ref := fmt.Sprintf("[%s]", col)
+		if dataType, ok := staged[strings.ToUpper(col)]; ok {
+			list[i] = fmt.Sprintf("%s AS %s", stagedValue(ref, dataType), ref)
+		} else {
+			list[i] = ref
+		}
+	}
+	return fmt.Sprintf("(SELECT %s FROM %s)", strings.Join(list, ", "), table)
+}
+
+// stagedValue returns the expression converting the staged text in ref to
+// dataType. Spatial values are well-known text, optionally prefixed with
+// "SRID=n;"; without it geography defaults to SRID 4326 and geometry to 0.
+func stagedValue(ref, dataType string) string {
+	switch dataType {
+	case "xml":
+		return fmt.Sprintf("CAST(%s AS xml)", ref)
+	case "hierarchyid":
+		return fmt.Sprintf("hierarchyid::Parse(%s)", ref)
+	case "geography", "geometry":
+		srid := 0
+		if dataType == "geography" {
+			srid = 4326
+		}
+		return fmt.Sprintf("CASE WHEN %[1]s LIKE 'SRID=%%;%%' "+
+			"THEN %[2]s::STGeomFromText(STUFF(%[1]s, 1, CHARINDEX(';', %[1]s), ''), CAST(SUBSTRING(%[1]s, 6, CHARINDEX(';', %[1]s) - 6) AS int)) "+
+			"ELSE %[2]s::STGeomFromText(%[1]s, %[3]d) END", ref, dataType, srid)
+	}
+	return ref
+}
+
+// buildMerge constructs a MERGE from source into target, where target is the
+// full "<table or CTE> AS target" clause. With deleteMissing, target rows with
+// no matching source row are deleted.
+func buildMerge(target, source string, columns, pkColumns []string, deleteMissing bool) string {
 	// Build ON clause from PK columns.
 	onParts := make([]string, len(pkColumns))
 	for i, pk := range pkColumns {
@@ -142,14 +285,18 @@ func buildMergeSQL(schemaTable string, columns, pkColumns []string) string {
 	}
 
 	var b strings.Builder
-	fmt.Fprintf(&b, "MERGE %s AS target ", schemaTable)
-	fmt.Fprintf(&b, "USING #temp AS source ")
+	fmt.Fprintf(&b, "MERGE %s ", target)
+	fmt.Fprintf(&b, "USING %s AS source ", source)
 	fmt.Fprintf(&b, "ON (%s) ", onClause)
 	if len(setParts) > 0 {
 		fmt.Fprintf(&b, "WHEN MATCHED THEN UPDATE SET %s ", strings.Join(setParts, ", "))
 	}
-	fmt.Fprintf(&b, "WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);",
+	fmt.Fprintf(&b, "WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
 		strings.Join(colList, ", "), strings.Join(valList, ", "))
+	if deleteMissing {
+		fmt.Fprintf(&b, " WHEN NOT MATCHED BY SOURCE THEN DELETE")
+	}
+	b.WriteString(";")
 
 	return b.String()
 }
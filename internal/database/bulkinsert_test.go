@@ -0,0 +1,44 @@
+package database
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildMerge(t *testing.T) {
+	cols := []string{"ID", "NAME"}
+	pk := []string{"ID"}
+
+	upsert := buildMergeSQL("dbo.T", cols, pk)
+	if !strings.HasPrefix(upsert, "MERGE dbo.T AS target USING #temp AS source ON (target.[ID] = source.[ID])") {
+		t.Errorf("unexpected upsert: %s", upsert)
+	}
+	if strings.Contains(upsert, "NOT MATCHED BY SOURCE") {
+		t.Errorf("upsert must not delete: %s", upsert)
+	}
+
+	mirror := buildMerge("dbo.T AS target", "dbo.stage", cols, pk, true)
+	if !strings.HasSuffix(mirror, "WHEN NOT MATCHED BY SOURCE THEN DELETE;") {
+		t.Errorf("expected mirror to delete missing rows: %s", mirror)
+	}
+	if !strings.Contains(mirror, "WHEN MATCHED THEN UPDATE SET target.[NAME] = source.[NAME]") {
+		t.Errorf("expected mirror to update non-key columns: %s", mirror)
+	}
+}
+
+func TestStagedSource(t *testing.T) {
+	cols := []string{"ID", "Shape", "Doc"}
+	staged := map[string]string{"SHAPE": "geography", "DOC": "xml"}
+
+	if got := stagedSelectList(cols, staged); got != "[ID], CAST(NULL AS nvarchar(max)) AS [Shape], CAST(NULL AS nvarchar(max)) AS [Doc]" {
+		t.Errorf("unexpected select list: %s", got)
+	}
+	src := stagedSource("#temp", cols, staged)
+	if !strings.HasPrefix(src, "(SELECT [ID], CASE WHEN [Shape] LIKE 'SRID=%;%' THEN geography::STGeomFromText(") ||
+		!strings.HasSuffix(src, "ELSE geography::STGeomFromText([Shape], 4326) END AS [Shape], CAST([Doc] AS xml) AS [Doc] FROM #temp)") {
+		t.Errorf("unexpected source: %s", src)
+	}
+	if got := stagedSource("#temp", cols, nil); got != "#temp" {
+		t.Errorf("expected the bare table without staged columns, got %s", got)
+	}
+}
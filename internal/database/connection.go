@@ -4,14 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/microsoft/go-mssqldb"
 )
 
-// NewConnection opens a connection pool to Azure SQL and verifies it with a ping.
-func NewConnection(connString string) (*sql.DB, error) {
-	db, err := sql.Open("sqlserver", connString)
+// NewConnection opens a connection pool with the named database/sql driver
+// and verifies it with a ping.
+func NewConnection(driverName, connString string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, connString)
 	if err != nil {
 		return nil, fmt.Errorf("opening connection: %w", err)
 	}
@@ -30,3 +34,23 @@ func NewConnection(connString string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// SQLServer is the Destination for Azure SQL and SQL Server.
+type SQLServer struct {
+	db     *sql.DB
+	logger *slog.Logger
+	// staged caches textStagedColumns by table.
+	staged sync.Map
+}
+
+// Driver returns DriverSQLServer.
+func (s *SQLServer) Driver() string { return DriverSQLServer }
+
+// DefaultSchema returns "dbo".
+func (s *SQLServer) DefaultSchema() string { return "dbo" }
+
+// DB returns the connection pool.
+func (s *SQLServer) DB() *sql.DB { return s.db }
+
+// Close closes the connection pool.
+func (s *SQLServer) Close() error { return s.db.Close() }
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Supported values for a target's driver.
+const (
+	DriverSQLServer = "sqlserver"
+	DriverPostgres  = "postgres"
+)
+
+// Destination is a database scalesync loads CSV files into. It covers
+// introspection of the target table, bulk loading with upsert, and the
+// staging needed for mirror mode; everything driver-specific lives behind it.
+type Destination interface {
+	// Driver returns DriverSQLServer or DriverPostgres.
+	Driver() string
+	// DefaultSchema is the schema unqualified table names live in.
+	DefaultSchema() string
+	// DB returns the underlying connection pool, for plain queries.
+	DB() *sql.DB
+	Close() error
+
+	ListTables(ctx context.Context) ([]string, error)
+	ListTablesWithCounts(ctx context.Context) ([]TableRowCount, error)
+	GetTableColumns(ctx context.Context, schemaTable string) ([]TableColumn, error)
+	GetPrimaryKeyColumns(ctx context.Context, schemaTable string) ([]string, error)
+	HasIdentityColumn(ctx context.Context, schemaTable string) (bool, error)
+
+	// InsertBatch upserts rows on pkColumns, or bulk copies them when
+	// pkColumns is empty.
+	InsertBatch(ctx context.Context, schemaTable string, columns, pkColumns []string, hasIdentity bool, rows [][]interface{}) error
+
+	// CreateStagingTable, DropTable and MirrorFromStaging implement mirror
+	// mode; see the SQL Server implementation for details.
+	CreateStagingTable(ctx context.Context, schemaTable string, columns []string) (string, error)
+	DropTable(ctx context.Context, schemaTable string) error
+	MirrorFromStaging(ctx context.Context, schemaTable, stage string, columns, pkColumns []string, hasIdentity bool, opts MirrorOptions) (MirrorStats, error)
+
+	// BuildSelect returns a SELECT of columns from schemaTable with optional
+	// raw WHERE and ORDER BY clauses (without their keywords).
+	BuildSelect(schemaTable string, columns []string, where, orderBy string) string
+}
+
+// Open connects to the database described by connString using driver. The
+// destination logs generated SQL and timings to logger at debug level.
+func Open(driver, connString string, logger *slog.Logger) (Destination, error) {
+	if driver == "" {
+		driver = DriverSQLServer
+	}
+	if driver != DriverSQLServer && driver != DriverPostgres {
+		return nil, fmt.Errorf("unknown driver %q (valid: %s, %s)", driver, DriverSQLServer, DriverPostgres)
+	}
+
+	logger.Debug("connecting", "driver", driver, "dsn", RedactDSN(connString))
+	start := time.Now()
+	db, err := NewConnection(driver, connString)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debug("connected", "driver", driver, "duration", time.Since(start))
+
+	if driver == DriverPostgres {
+		return &Postgres{db: db, logger: logger}, nil
+	}
+	return &SQLServer{db: db, logger: logger}, nil
+}
+
+// RedactDSN returns a connection URL with its password replaced, safe to log.
+func RedactDSN(connString string) string {
+	u, err := url.Parse(connString)
+	if err != nil {
+		return "(unparseable connection string)"
+	}
+	return u.Redacted()
+}
+
+// buildSelect assembles a SELECT from already-quoted table and column names.
+func buildSelect(table string, quoted []string, where, orderBy string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", strings.Join(quoted, ", "), table)
+	if where != "" {
+		fmt.Fprintf(&b, " WHERE %s", where)
+	}
+	if orderBy != "" {
+		fmt.Fprintf(&b, " ORDER BY %s", orderBy)
+	}
+	return b.String()
+}
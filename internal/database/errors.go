@@ -0,0 +1,95 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// ErrorKind says how a loader should react to a failed statement.
+type ErrorKind int
+
+const (
+	// ErrorFatal is anything not known to be caused by the rows sent:
+	// permissions, missing objects, schema drift, lock timeouts, a full log,
+	// lost connections. Retrying the rows one by one won't help.
+	ErrorFatal ErrorKind = iota
+	// ErrorRow is a constraint, conversion, truncation or overflow error,
+	// caused by the value of some row; the rest of the rows can still load.
+	ErrorRow
+	// ErrorRetry is a transient conflict with another session, such as
+	// being chosen as a deadlock victim; the same statement may succeed if
+	// run again.
+	ErrorRetry
+)
+
+// sqlServerRowErrors are the SQL Server error numbers caused by the value
+// of a row.
+var sqlServerRowErrors = map[int32]bool{
+	220:  true, // arithmetic overflow for data type
+	232:  true, // arithmetic overflow for type
+	241:  true, // conversion failed converting date and/or time from string
+	242:  true, // conversion resulted in an out-of-range value
+	245:  true, // conversion failed converting a value to a data type
+	515:  true, // cannot insert NULL into column
+	547:  true, // foreign key or check constraint conflict
+	2601: true, // duplicate key row in unique index
+	2627: true, // violation of primary key or unique constraint
+	2628: true, // string or binary data would be truncated (SQL Server 2019+)
+	4815: true, // bulk load: invalid column length from the bcp client
+	6522: true, // .NET error, e.g. invalid geography or hierarchyid text
+	8114: true, // error converting data type
+	8115: true, // arithmetic overflow converting to data type
+	8152: true, // string or binary data would be truncated
+}
+
+// isXMLParseError reports whether number is one of SQL Server's "XML
+// parsing: line n, character m, ..." errors, raised converting staged text
+// to an xml column.
+func isXMLParseError(number int32) bool {
+	return number >= 9400 && number <= 9459
+}
+
+// sqlServerRetryErrors are the SQL Server error numbers worth retrying.
+var sqlServerRetryErrors = map[int32]bool{
+	1205: true, // chosen as deadlock victim
+}
+
+// ClassifyError reports whether err, returned by a statement loading rows,
+// was caused by the rows' values, is worth retrying, or neither. Errors are
+// classified by their SQL Server error number or Postgres SQLSTATE; errors
+// that carry neither are fatal.
+func ClassifyError(err error) ErrorKind {
+	var msErr mssql.Error
+	if errors.As(err, &msErr) {
+		all := msErr.All
+		if len(all) == 0 {
+			all = []mssql.Error{msErr}
+		}
+		// The last error is usually "The statement has been terminated";
+		// the cause is earlier in the list.
+		for _, e := range all {
+			switch {
+			case sqlServerRetryErrors[e.Number]:
+				return ErrorRetry
+			case sqlServerRowErrors[e.Number], isXMLParseError(e.Number):
+				return ErrorRow
+			}
+		}
+		return ErrorFatal
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40P01", "40001": // deadlock_detected, serialization_failure
+			return ErrorRetry
+		}
+		switch pqErr.Code.Class() {
+		case "22", "23": // data exception, integrity constraint violation
+			return ErrorRow
+		}
+	}
+	return ErrorFatal
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// QueryColumn describes a column in a query result.
+type QueryColumn struct {
+	Name     string
+	DataType string // lower-case SQL type name, e.g. "datetime2"
+}
+
+// RowStream iterates over a query result one row at a time so arbitrarily
+// large tables can be read with a bounded memory footprint.
+type RowStream struct {
+	rows   *sql.Rows
+	cancel context.CancelFunc
+	cols   []QueryColumn
+	vals   []interface{}
+	ptrs   []interface{}
+}
+
+// StreamQuery runs query and returns a stream over its rows. The caller must
+// Close the stream.
+func StreamQuery(ctx context.Context, db *sql.DB, query string, args ...interface{}) (*RowStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("running query: %w", err)
+	}
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		cancel()
+		return nil, fmt.Errorf("reading result columns: %w", err)
+	}
+
+	s := &RowStream{
+		rows:   rows,
+		cancel: cancel,
+		cols:   make([]QueryColumn, len(types)),
+		vals:   make([]interface{}, len(types)),
+		ptrs:   make([]interface{}, len(types)),
+	}
+	for i, t := range types {
+		s.cols[i] = QueryColumn{Name: t.Name(), DataType: strings.ToLower(t.DatabaseTypeName())}
+		s.ptrs[i] = &s.vals[i]
+	}
+	return s, nil
+}
+
+// Columns returns the result's column names and types.
+func (s *RowStream) Columns() []QueryColumn {
+	return s.cols
+}
+
+// Next advances to the next row, returning false at the end or on error.
+func (s *RowStream) Next() bool {
+	return s.rows.Next()
+}
+
+// Values scans the current row. Driver-specific representations are
+// normalised: uniqueidentifiers become their canonical string form, and
+// decimal/money values and any other text a driver returns as bytes become
+// strings. Only binary columns stay []byte. The returned slice is reused by
+// the next call.
+func (s *RowStream) Values() ([]interface{}, error) {
+	if err := s.rows.Scan(s.ptrs...); err != nil {
+		return nil, fmt.Errorf("scanning row: %w", err)
+	}
+	for i, v := range s.vals {
+		b, ok := v.([]byte)
+		if !ok {
+			continue
+		}
+		switch s.cols[i].DataType {
+		case "uniqueidentifier":
+			var id mssql.UniqueIdentifier
+			if err := id.Scan(b); err != nil {
+				return nil, fmt.Errorf("column %s: %w", s.cols[i].Name, err)
+			}
+			s.vals[i] = id.String()
+		case "binary", "varbinary", "image", "timestamp", "rowversion", "bytea":
+		default:
+			s.vals[i] = string(b)
+		}
+	}
+	return s.vals, nil
+}
+
+// Err returns the error, if any, that ended iteration.
+func (s *RowStream) Err() error {
+	return s.rows.Err()
+}
+
+// Close releases the underlying result set.
+func (s *RowStream) Close() error {
+	defer s.cancel()
+	return s.rows.Close()
+}
+
+// BuildSelect returns a SELECT of columns from schemaTable with optional raw
+// WHERE and ORDER BY clauses (without their keywords).
+func (s *SQLServer) BuildSelect(schemaTable string, columns []string, where, orderBy string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = fmt.Sprintf("[%s]", c)
+	}
+	return buildSelect(schemaTable, quoted, where, orderBy)
+}
+
+// QuoteIdent returns a column name quoted for driver.
+func QuoteIdent(driver, name string) string {
+	return identQuoter(driver)(name)
+}
+
+// KeyWhere returns the WHERE clause (without WHERE) matching the rows whose
+// columns equal one of n sets of values, passed as the query's arguments
+// one set after another: "[ID] IN (@p1, @p2)" for a single column, else
+// "([A] = @p1 AND [B] = @p2) OR ([A] = @p3 AND [B] = @p4)".
+func KeyWhere(driver string, columns []string, n int) string {
+	param := func(i int) string {
+		if driver == DriverPostgres {
+			return fmt.Sprintf("$%d", i)
+		}
+		return fmt.Sprintf("@p%d", i)
+	}
+	if len(columns) == 1 {
+		params := make([]string, n)
+		for i := range params {
+			params[i] = param(i + 1)
+		}
+		return fmt.Sprintf("%s IN (%s)", QuoteIdent(driver, columns[0]), strings.Join(params, ", "))
+	}
+	sets := make([]string, n)
+	conds := make([]string, len(columns))
+	for i := range sets {
+		for j, c := range columns {
+			conds[j] = fmt.Sprintf("%s = %s", QuoteIdent(driver, c), param(i*len(columns)+j+1))
+		}
+		sets[i] = "(" + strings.Join(conds, " AND ") + ")"
+	}
+	return strings.Join(sets, " OR ")
+}
+
+// WatermarkWhere returns the WHERE clause (without WHERE) of an incremental
+// export on column: with since, the rows above the watermark passed as the
+// query's first argument, else every row. A rowversion column is also held
+// below MIN_ACTIVE_ROWVERSION(), so the rows of transactions still open are
+// left for the next export instead of being skipped by it.
+func WatermarkWhere(driver, column string, rowversion, since bool) string {
+	quoted := QuoteIdent(driver, column)
+	var conds []string
+	if since {
+		param := "@p1"
+		if driver == DriverPostgres {
+			param = "$1"
+		}
+		conds = append(conds, fmt.Sprintf("%s > %s", quoted, param))
+	}
+	if rowversion {
+		conds = append(conds, quoted+" < MIN_ACTIVE_ROWVERSION()")
+	}
+	return strings.Join(conds, " AND ")
+}
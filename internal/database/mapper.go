@@ -2,31 +2,72 @@ package database
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
 // ColumnMapping describes how CSV columns map to database table columns.
 type ColumnMapping struct {
-	// CSVIndex is the index of the column in the CSV header row.
+	// CSVIndex is the index of the column in the CSV header row, or -1 for a
+	// table column filled from a mapping file constant or default.
 	CSVIndex int
 	// CSVName is the original CSV header name.
 	CSVName string
 	// DBColumn is the matching database column definition.
 	DBColumn TableColumn
+	// Constant is the literal value used for every row when CSVIndex is -1
+	// and no Default is set.
+	Constant string
+	// Default is an expression evaluated when the CSV value is empty or the
+	// column has no CSV header.
+	Default string
+	// Oversize is the policy for values too long or too precise for the
+	// column: OversizeError (the default when empty), OversizeTruncate or
+	// OversizeNull.
+	Oversize string
+}
+
+// Oversize policies.
+const (
+	// OversizeError rejects the row.
+	OversizeError = "error"
+	// OversizeTruncate cuts strings and binary values to the column's length
+	// and rounds numbers to its scale. Numbers with too many integer digits
+	// can't be cut down and are still rejected.
+	OversizeTruncate = "truncate"
+	// OversizeNull loads NULL instead, if the column allows it.
+	OversizeNull = "null"
+)
+
+// CheckOversize returns an error if policy is not an oversize policy.
+func CheckOversize(policy string) error {
+	switch policy {
+	case OversizeError, OversizeTruncate, OversizeNull:
+		return nil
+	}
+	return fmt.Errorf("unknown oversize policy %q (want %s, %s or %s)", policy, OversizeError, OversizeTruncate, OversizeNull)
 }
 
 // MapResult holds the outcome of column mapping.
 type MapResult struct {
 	Mapped    []ColumnMapping
 	Skipped   []string // CSV columns with no table match
+	Ignored   []string // CSV columns dropped by the mapping file
 	Unmatched []string // Non-nullable table columns with no CSV match
 }
 
 // MapColumns performs case-insensitive matching between CSV headers and table columns.
 // CSV columns that don't match any table column are skipped (with a warning).
 // Non-nullable table columns that have no CSV match cause an error.
-func MapColumns(csvHeaders []string, tableCols []TableColumn) (*MapResult, error) {
+//
+// An optional mapping file renames headers before matching, drops ignored
+// headers, supplies constants and defaults for table columns, and sets their
+// oversize policies.
+func MapColumns(csvHeaders []string, tableCols []TableColumn, mf *MappingFile) (*MapResult, error) {
 	result := &MapResult{}
+	if mf == nil {
+		mf = &MappingFile{}
+	}
 
 	// Build lookup: lowercase table column name → TableColumn
 	lookup := make(map[string]TableColumn, len(tableCols))
@@ -34,26 +75,97 @@ func MapColumns(csvHeaders []string, tableCols []TableColumn) (*MapResult, error
 		lookup[strings.ToLower(tc.Name)] = tc
 	}
 
-	matched := make(map[string]bool)
+	renames := make(map[string]string, len(mf.Rename))
+	for from, to := range mf.Rename {
+		if _, ok := lookup[strings.ToLower(strings.TrimSpace(to))]; !ok {
+			return result, fmt.Errorf("mapping renames %q to unknown column %q", from, to)
+		}
+		renames[strings.ToLower(strings.TrimSpace(from))] = strings.TrimSpace(to)
+	}
+	ignored := make(map[string]bool, len(mf.Ignore))
+	for _, h := range mf.Ignore {
+		ignored[strings.ToLower(strings.TrimSpace(h))] = true
+	}
+
+	matched := make(map[string]string) // lowercase column → CSV header it came from
+	defaults := lowerKeys(mf.Defaults)
 
 	for i, h := range csvHeaders {
 		key := strings.ToLower(strings.TrimSpace(h))
-		if tc, ok := lookup[key]; ok {
-			result.Mapped = append(result.Mapped, ColumnMapping{
-				CSVIndex: i,
-				CSVName:  h,
-				DBColumn: tc,
-			})
-			matched[key] = true
-		} else {
+		if ignored[key] {
+			result.Ignored = append(result.Ignored, h)
+			continue
+		}
+		if to, ok := renames[key]; ok {
+			key = strings.ToLower(to)
+		}
+		tc, ok := lookup[key]
+		if !ok {
 			result.Skipped = append(result.Skipped, h)
+			continue
+		}
+		if prev, dup := matched[key]; dup {
+			return result, fmt.Errorf("CSV headers %q and %q both map to column %s", prev, h, tc.Name)
+		}
+		result.Mapped = append(result.Mapped, ColumnMapping{
+			CSVIndex: i,
+			CSVName:  h,
+			DBColumn: tc,
+			Default:  defaults[key],
+		})
+		matched[key] = h
+	}
+
+	// Constants and defaults fill table columns the CSV doesn't supply.
+	// Keys are sorted so the column order is stable from run to run.
+	for _, col := range sortedKeys(mf.Constants) {
+		val := mf.Constants[col]
+		key := strings.ToLower(strings.TrimSpace(col))
+		tc, ok := lookup[key]
+		if !ok {
+			return result, fmt.Errorf("mapping sets constant for unknown column %q", col)
+		}
+		if h, ok := matched[key]; ok {
+			return result, fmt.Errorf("mapping sets constant for column %s, which is already mapped from CSV header %q", tc.Name, h)
+		}
+		result.Mapped = append(result.Mapped, ColumnMapping{CSVIndex: -1, DBColumn: tc, Constant: val})
+		matched[key] = ""
+	}
+	for _, col := range sortedKeys(mf.Defaults) {
+		key := strings.ToLower(strings.TrimSpace(col))
+		expr := mf.Defaults[col]
+		tc, ok := lookup[key]
+		if !ok {
+			return result, fmt.Errorf("mapping sets default for unknown column %q", col)
+		}
+		if _, ok := matched[key]; ok {
+			// Mapped from the CSV (default already attached) or a constant.
+			continue
+		}
+		result.Mapped = append(result.Mapped, ColumnMapping{CSVIndex: -1, DBColumn: tc, Default: expr})
+		matched[key] = ""
+	}
+
+	for _, col := range sortedKeys(mf.Oversize) {
+		policy := mf.Oversize[col]
+		if err := CheckOversize(policy); err != nil {
+			return result, fmt.Errorf("mapping for column %q: %w", col, err)
+		}
+		key := strings.ToLower(strings.TrimSpace(col))
+		if _, ok := lookup[key]; !ok {
+			return result, fmt.Errorf("mapping sets oversize policy for unknown column %q", col)
+		}
+		for i := range result.Mapped {
+			if strings.EqualFold(result.Mapped[i].DBColumn.Name, key) {
+				result.Mapped[i].Oversize = policy
+			}
 		}
 	}
 
 	// Check for non-nullable table columns that have no CSV match
 	for _, tc := range tableCols {
 		key := strings.ToLower(tc.Name)
-		if !matched[key] && !tc.IsNullable {
+		if _, ok := matched[key]; !ok && !tc.IsNullable {
 			result.Unmatched = append(result.Unmatched, tc.Name)
 		}
 	}
@@ -65,3 +177,22 @@ func MapColumns(csvHeaders []string, tableCols []TableColumn) (*MapResult, error
 
 	return result, nil
 }
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lowerKeys returns m with its keys trimmed and lower-cased.
+func lowerKeys(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	return out
+}
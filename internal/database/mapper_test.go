@@ -0,0 +1,102 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var ordersCols = []TableColumn{
+	{Name: "CUSTOMER_NUMBER", DataType: "varchar"},
+	{Name: "ORDER_ID", DataType: "int"},
+	{Name: "SOURCE_SYSTEM", DataType: "varchar"},
+	{Name: "CREATED_AT", DataType: "datetime2"},
+	{Name: "NOTES", DataType: "nvarchar", IsNullable: true},
+}
+
+func TestMapColumnsWithMappingFile(t *testing.T) {
+	mf := &MappingFile{
+		Rename:    map[string]string{"Cust No": "CUSTOMER_NUMBER"},
+		Ignore:    []string{"Internal Notes"},
+		Constants: map[string]string{"SOURCE_SYSTEM": "SAP"},
+		Defaults:  map[string]string{"CREATED_AT": "now()", "NOTES": "n/a"},
+		Oversize:  map[string]string{"notes": OversizeTruncate},
+	}
+	headers := []string{"cust no", "Order_Id", "Internal Notes", "Extra", "Notes"}
+
+	res, err := MapColumns(headers, ordersCols, mf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string]ColumnMapping)
+	for _, m := range res.Mapped {
+		got[m.DBColumn.Name] = m
+	}
+	if m := got["CUSTOMER_NUMBER"]; m.CSVIndex != 0 {
+		t.Errorf("expected Cust No renamed to CUSTOMER_NUMBER, got %+v", m)
+	}
+	if m := got["SOURCE_SYSTEM"]; m.CSVIndex != -1 || m.Constant != "SAP" {
+		t.Errorf("expected SOURCE_SYSTEM constant, got %+v", m)
+	}
+	if m := got["CREATED_AT"]; m.CSVIndex != -1 || m.Default != "now()" {
+		t.Errorf("expected CREATED_AT default, got %+v", m)
+	}
+	if m := got["NOTES"]; m.CSVIndex != 4 || m.Default != "n/a" || m.Oversize != OversizeTruncate {
+		t.Errorf("expected NOTES mapped from CSV with default, got %+v", m)
+	}
+	if len(res.Ignored) != 1 || len(res.Skipped) != 1 || res.Skipped[0] != "Extra" {
+		t.Errorf("unexpected ignored/skipped: %v / %v", res.Ignored, res.Skipped)
+	}
+}
+
+func TestMapColumnsWithoutMappingFile(t *testing.T) {
+	_, err := MapColumns([]string{"Cust No", "ORDER_ID"}, ordersCols, nil)
+	if err == nil || !strings.Contains(err.Error(), "CUSTOMER_NUMBER") {
+		t.Fatalf("expected unmatched CUSTOMER_NUMBER error, got %v", err)
+	}
+}
+
+func TestMapColumnsRejectsBadMapping(t *testing.T) {
+	cases := map[string]*MappingFile{
+		"unknown column":   {Rename: map[string]string{"Cust No": "NOPE"}},
+		"both map":         {Rename: map[string]string{"Cust No": "ORDER_ID"}},
+		"already mapped":   {Constants: map[string]string{"ORDER_ID": "1"}},
+		"unknown constant": {Constants: map[string]string{"NOPE": "1"}},
+		"unknown policy":   validOrders(map[string]string{"NOTES": "cut"}),
+		"unknown oversize": validOrders(map[string]string{"NOPE": OversizeNull}),
+	}
+	for name, mf := range cases {
+		if _, err := MapColumns([]string{"Cust No", "ORDER_ID"}, ordersCols, mf); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// validOrders returns a mapping that maps "Cust No" and "ORDER_ID" onto
+// ordersCols, with the given oversize policies.
+func validOrders(oversize map[string]string) *MappingFile {
+	return &MappingFile{
+		Rename:    map[string]string{"Cust No": "CUSTOMER_NUMBER"},
+		Constants: map[string]string{"SOURCE_SYSTEM": "SAP"},
+		Defaults:  map[string]string{"CREATED_AT": "now()"},
+		Oversize:  oversize,
+	}
+}
+
+func TestLoadMappingFileJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	content := `{"table": "dbo.ORDERS", "rename": {"Cust No": "CUSTOMER_NUMBER"}, "ignore": ["x"]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mf, err := LoadMappingFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mf.Table != "dbo.ORDERS" || mf.Rename["Cust No"] != "CUSTOMER_NUMBER" || len(mf.Ignore) != 1 {
+		t.Errorf("unexpected mapping: %+v", mf)
+	}
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MappingFile declares how a feed's CSV headers map onto a table when they
+// don't match column names exactly. It is read from YAML or JSON.
+//
+//	table: dbo.ORDERS
+//	rename:
+//	  Cust No: CUSTOMER_NUMBER
+//	ignore:
+//	  - Internal Notes
+//	constants:
+//	  SOURCE_SYSTEM: SAP
+//	defaults:
+//	  CREATED_AT: now()
+//	oversize:
+//	  NOTES: truncate
+type MappingFile struct {
+	// Table optionally names the table the mapping was written for; a
+	// mismatch with the import target is an error.
+	Table string `yaml:"table,omitempty" json:"table,omitempty"`
+	// Rename maps CSV headers to table column names.
+	Rename map[string]string `yaml:"rename,omitempty" json:"rename,omitempty"`
+	// Ignore lists CSV headers to drop without a warning.
+	Ignore []string `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+	// Constants gives a literal value for table columns the CSV lacks.
+	Constants map[string]string `yaml:"constants,omitempty" json:"constants,omitempty"`
+	// Defaults gives an expression per table column, used when the CSV value
+	// is empty or the CSV lacks the column.
+	Defaults map[string]string `yaml:"defaults,omitempty" json:"defaults,omitempty"`
+	// Oversize gives the policy per table column for values too long or too
+	// precise for it (see OversizeTruncate), overriding the --oversize flag.
+	Oversize map[string]string `yaml:"oversize,omitempty" json:"oversize,omitempty"`
+}
+
+// LoadMappingFile reads a mapping file. JSON files are accepted since JSON
+// is valid YAML.
+func LoadMappingFile(path string) (*MappingFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mapping file: %w", err)
+	}
+
+	var mf MappingFile
+	if err := yaml.Unmarshal(data, &mf); err != nil {
+		return nil, fmt.Errorf("parsing mapping file %s: %w", path, err)
+	}
+	return &mf, nil
+}
+
+// FindMappingFile returns the stored mapping for schemaTable in dir
+// (dir/<schema>.<table>.yaml), or nil if there is none.
+func FindMappingFile(dir, schemaTable string) (*MappingFile, string, error) {
+	path := MappingFilePath(dir, schemaTable)
+	mf, err := LoadMappingFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return mf, path, nil
+}
+
+// MappingFilePath returns where the mapping for schemaTable is stored in dir.
+func MappingFilePath(dir, schemaTable string) string {
+	schema, table := SplitSchemaTable(schemaTable)
+	return filepath.Join(dir, schema+"."+table+".yaml")
+}
+
+// Save writes the mapping as YAML to path.
+func (mf *MappingFile) Save(path string) error {
+	data, err := yaml.Marshal(mf)
+	if err != nil {
+		return fmt.Errorf("encoding mapping: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("creating directory %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing mapping file: %w", err)
+	}
+	return nil
+}
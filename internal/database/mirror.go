@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// MirrorOptions controls how MirrorFromStaging reconciles the target table.
+type MirrorOptions struct {
+	// Scope is an optional SQL predicate on the target's columns limiting
+	// which rows mirroring may delete, e.g. "REGION = 'EU'".
+	Scope string
+	// MaxDeletePct aborts the mirror when it would delete more than this
+	// percentage of the target rows in scope.
+	MaxDeletePct float64
+}
+
+// MirrorStats reports what MirrorFromStaging did.
+type MirrorStats struct {
+	InScope int64 // target rows in scope before the mirror
+	Deleted int64 // target rows deleted because the file no longer has them
+	Merged  int64 // target rows inserted or updated from the file
+}
+
+// CreateStagingTable creates an empty table next to schemaTable with the
+// given columns, to stage a whole file in before mirroring it. The copy has
+// no constraints and no identity property, so workers can bulk copy into it
+// directly; columns bulk copy can't load are staged as text (see
+// textStagedColumns). It returns the staging table's name. Staging tables
+// left behind by runs that died are dropped first.
+func (s *SQLServer) CreateStagingTable(ctx context.Context, schemaTable string, columns []string) (string, error) {
+	dropStaleStages(ctx, s.db, `SELECT s.name, t.name FROM sys.tables t
+		JOIN sys.schemas s ON t.schema_id = s.schema_id
+		WHERE t.name LIKE '\_\_scalesync\_stage\_%' ESCAPE '\'`, s.DropTable, s.logger)
+
+	schema, _ := SplitSchemaTable(schemaTable)
+	name, err := newStageName(time.Now())
+	if err != nil {
+		return "", err
+	}
+	stage := schema + "." + name
+
+	colList := make([]string, len(columns))
+	for i, c := range columns {
+		colList[i] = fmt.Sprintf("[%s]", c)
+	}
+	cols := strings.Join(colList, ", ")
+	staged, err := s.textStagedColumns(ctx, schemaTable, columns)
+	if err != nil {
+		return "", err
+	}
+	if len(staged) > 0 {
+		cols = stagedSelectList(columns, staged)
+	}
+
+	// The UNION ALL stops SELECT INTO from copying the identity property.
+	query := fmt.Sprintf("SELECT TOP(0) %s INTO %s FROM %s UNION ALL SELECT TOP(0) %s FROM %s",
+		cols, stage, schemaTable, cols, schemaTable)
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	s.logger.Debug("creating staging table", "table", stage, "sql", query)
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return "", fmt.Errorf("creating staging table: %w", err)
+	}
+	return stage, nil
+}
+
+// stagePrefix starts the name of every staging table.
+const stagePrefix = "__scalesync_stage_"
+
+// stageTimeLayout is how a staging table's name records when it was made.
+const stageTimeLayout = "20060102T150405"
+
+// staleStageAge is how old a staging table must be before it is taken to be
+// left behind by a run that died, rather than in use by one still running.
+const staleStageAge = 24 * time.Hour
+
+// newStageName returns a name for a staging table made at now: stagePrefix,
+// the creation time in UTC and a random suffix.
+func newStageName(now time.Time) (string, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("naming staging table: %w", err)
+	}
+	return stagePrefix + now.UTC().Format(stageTimeLayout) + "_" + hex.EncodeToString(suffix), nil
+}
+
+// staleStage reports whether name is a staging table made before cutoff.
+// Staging tables named without a creation time come from earlier versions
+// and are always stale.
+func staleStage(name string, cutoff time.Time) bool {
+	rest, ok := strings.CutPrefix(strings.ToLower(name), stagePrefix)
+	if !ok {
+		return false
+	}
+	stamp, _, ok := strings.Cut(rest, "_")
+	if !ok {
+		return true
+	}
+	made, err := time.Parse(stageTimeLayout, strings.ToUpper(stamp))
+	return err == nil && made.Before(cutoff)
+}
+
+// dropStaleStages drops the stale staging tables (see staleStage) among the
+// schema and table name pairs listed by query, which runs that died left
+// behind. Failures are logged; they don't stop a new mirror.
+func dropStaleStages(ctx context.Context, db *sql.DB, query string, drop func(context.Context, string) error, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		logger.Warn("listing staging tables", "err", err)
+		return
+	}
+	cutoff := time.Now().Add(-staleStageAge)
+	var stale []string
+	for rows.Next() {
+		var schema, name string
+		if err := rows.Scan(&schema, &name); err != nil {
+			logger.Warn("listing staging tables", "err", err)
+			break
+		}
+		if staleStage(name, cutoff) {
+			stale = append(stale, schema+"."+name)
+		}
+	}
+	rows.Close()
+
+	for _, table := range stale {
+		if err := drop(ctx, table); err != nil {
+			logger.Warn("staging table left behind by an earlier run not dropped", "table", table, "err", err)
+			continue
+		}
+		logger.Info("dropped staging table left behind by an earlier run", "table", table)
+	}
+}
+
+// DropTable drops the named table if it exists.
+func (s *SQLServer) DropTable(ctx context.Context, schemaTable string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", schemaTable)); err != nil {
+		return fmt.Errorf("dropping %s: %w", schemaTable, err)
+	}
+	return nil
+}
+
+// MirrorFromStaging makes the target table match the staging table: rows are
+// upserted on pkColumns and target rows in scope that are missing from the
+// staging table are deleted (MERGE ... WHEN NOT MATCHED BY SOURCE). Nothing
+// is changed if the delete would exceed opts.MaxDeletePct.
+func (s *SQLServer) MirrorFromStaging(ctx context.Context, schemaTable, stage string, columns, pkColumns []string, hasIdentity bool, opts MirrorOptions) (MirrorStats, error) {
+	var stats MirrorStats
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// 1. Measure the delete before doing it.
+	scopeWhere := ""
+	if opts.Scope != "" {
+		scopeWhere = fmt.Sprintf(" WHERE (%s)", opts.Scope)
+	}
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT_BIG(*) FROM %s%s", schemaTable, scopeWhere)).Scan(&stats.InScope); err != nil {
+		return stats, fmt.Errorf("counting rows in scope: %w", err)
+	}
+
+	onParts := make([]string, len(pkColumns))
+	for i, pk := range pkColumns {
+		onParts[i] = fmt.Sprintf("target.[%s] = source.[%s]", pk, pk)
+	}
+	missing := fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s AS source WHERE %s)", stage, strings.Join(onParts, " AND "))
+	if opts.Scope != "" {
+		missing = fmt.Sprintf("(%s) AND %s", opts.Scope, missing)
+	}
+	countDeletes := fmt.Sprintf("SELECT COUNT_BIG(*) FROM %s AS target WHERE %s", schemaTable, missing)
+	if err := tx.QueryRowContext(ctx, countDeletes).Scan(&stats.Deleted); err != nil {
+		return stats, fmt.Errorf("counting rows to delete: %w", err)
+	}
+
+	if err := checkDeleteThreshold(stats, opts); err != nil {
+		return stats, err
+	}
+
+	// 2. Upsert the file into the whole table, so a file row whose key lies
+	// outside the scope updates that row instead of failing to insert it.
+	// Without a scope the same MERGE deletes the missing rows; with one they
+	// are deleted separately, inside the scope only.
+	staged, err := s.textStagedColumns(ctx, schemaTable, columns)
+	if err != nil {
+		return stats, err
+	}
+	mergeSQL := buildMerge(schemaTable+" AS target", stagedSource(stage, columns, staged), columns, pkColumns, opts.Scope == "")
+	deleteSQL := ""
+	if opts.Scope != "" {
+		deleteSQL = fmt.Sprintf("DELETE target FROM %s AS target WHERE %s", schemaTable, missing)
+	}
+	s.logger.Debug("mirror", "table", schemaTable, "in_scope", stats.InScope, "deleting", stats.Deleted,
+		"sql", mergeSQL, "delete_sql", deleteSQL)
+
+	if hasIdentity {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET IDENTITY_INSERT %s ON", schemaTable)); err != nil {
+			return stats, fmt.Errorf("identity insert on: %w", err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, mergeSQL)
+	if err != nil {
+		return stats, fmt.Errorf("merge: %w", err)
+	}
+	if hasIdentity {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET IDENTITY_INSERT %s OFF", schemaTable)); err != nil {
+			return stats, fmt.Errorf("identity insert off: %w", err)
+		}
+	}
+	if affected, err := res.RowsAffected(); err == nil {
+		stats.Merged = affected
+		if deleteSQL == "" {
+			stats.Merged -= stats.Deleted
+		}
+	}
+	if deleteSQL != "" {
+		if _, err := tx.ExecContext(ctx, deleteSQL); err != nil {
+			return stats, fmt.Errorf("delete: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("commit: %w", err)
+	}
+	return stats, nil
+}
+
+// checkDeleteThreshold returns an error when the measured delete exceeds
+// opts.MaxDeletePct of the rows in scope.
+func checkDeleteThreshold(stats MirrorStats, opts MirrorOptions) error {
+	if stats.InScope == 0 {
+		return nil
+	}
+	pct := float64(stats.Deleted) * 100 / float64(stats.InScope)
+	if pct > opts.MaxDeletePct {
+		return fmt.Errorf("mirror would delete %d of %d rows (%.1f%%), more than --max-delete-pct %.1f%%; nothing was changed",
+			stats.Deleted, stats.InScope, pct, opts.MaxDeletePct)
+	}
+	return nil
+}
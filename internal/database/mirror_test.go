@@ -0,0 +1,36 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaleStage(t *testing.T) {
+	now := time.Date(2026, 2, 11, 17, 2, 55, 0, time.UTC)
+	name, err := newStageName(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(name, "__scalesync_stage_20260211T170255_") || len(name) > 63 {
+		t.Errorf("unexpected stage name %q", name)
+	}
+
+	cases := []struct {
+		name   string
+		cutoff time.Time
+		want   bool
+	}{
+		{name, now.Add(-time.Hour), false},
+		{name, now.Add(time.Hour), true},
+		{strings.ToUpper(name), now.Add(time.Hour), true},
+		{"__scalesync_stage_9f86d081884c7d65", now, true},
+		{"__scalesync_stage_notatime_9f86d081884c7d65", now, false},
+		{"ORDERS", now, false},
+	}
+	for _, c := range cases {
+		if got := staleStage(c.name, c.cutoff); got != c.want {
+			t.Errorf("staleStage(%q, %s) = %v, want %v", c.name, c.cutoff, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres is the Destination for PostgreSQL. Batches are loaded with COPY
+// and upserted with INSERT ... ON CONFLICT. Column types are reported by
+// their internal names (int4, varchar, timestamptz, ...).
+type Postgres struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+// Driver returns DriverPostgres.
+func (p *Postgres) Driver() string { return DriverPostgres }
+
+// DefaultSchema returns "public".
+func (p *Postgres) DefaultSchema() string { return "public" }
+
+// DB returns the connection pool.
+func (p *Postgres) DB() *sql.DB { return p.db }
+
+// Close closes the connection pool.
+func (p *Postgres) Close() error { return p.db.Close() }
+
+// ListTables returns all user table names from the connected database.
+func (p *Postgres) ListTables(ctx context.Context) ([]string, error) {
+	query := `SELECT table_schema || '.' || table_name
+		FROM information_schema.tables
+		WHERE table_type = 'BASE TABLE'
+			AND table_schema NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY table_schema, table_name`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying information_schema.tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// ListTablesWithCounts returns all user tables with their live row counts
+// from pg_stat_user_tables, which are estimates maintained by the statistics
+// collector rather than exact counts.
+func (p *Postgres) ListTablesWithCounts(ctx context.Context) ([]TableRowCount, error) {
+	query := `SELECT schemaname || '.' || relname, n_live_tup
+		FROM pg_stat_user_tables
+		ORDER BY schemaname, relname`
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("querying table counts: %w", err)
+	}
+	defer rows.Close()
+
+	var results []TableRowCount
+	for rows.Next() {
+		var r TableRowCount
+		if err := rows.Scan(&r.Name, &r.RowCount); err != nil {
+			return nil, fmt.Errorf("scanning table count: %w", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// GetTableColumns returns the column definitions for a given schema.table.
+func (p *Postgres) GetTableColumns(ctx context.Context, schemaTable string) ([]TableColumn, error) {
+	schema, table := splitPgTable(schemaTable)
+
+	query := `SELECT column_name, udt_name, is_nullable, ordinal_position,
+			character_maximum_length, numeric_precision, numeric_scale,
+			datetime_precision, collation_name, is_generated = 'ALWAYS'
+		FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2
+		ORDER BY ordinal_position`
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("querying columns: %w", err)
+	}
+	defer rows.Close()
+
+	var cols []TableColumn
+	for rows.Next() {
+		var c TableColumn
+		var nullable string
+		var maxLen, precision, scale, timePrecision sql.NullInt64
+		var collation sql.NullString
+		if err := rows.Scan(&c.Name, &c.DataType, &nullable, &c.OrdinalPos,
+			&maxLen, &precision, &scale, &timePrecision, &collation, &c.Computed); err != nil {
+			return nil, fmt.Errorf("scanning column: %w", err)
+		}
+		c.IsNullable = nullable == "YES"
+		c.MaxLength = int(maxLen.Int64)
+		c.Precision = int(precision.Int64)
+		c.Scale = int(scale.Int64)
+		c.DateTimePrecision = int(timePrecision.Int64)
+		c.Collation = collation.String
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// HasIdentityColumn returns true if the given table has an identity column.
+// Serial columns are plain defaults and don't count.
+func (p *Postgres) HasIdentityColumn(ctx context.Context, schemaTable string) (bool, error) {
+	schema, table := splitPgTable(schemaTable)
+
+	query := `SELECT 1 FROM information_schema.columns
+		WHERE table_schema = $1 AND table_name = $2 AND is_identity = 'YES'
+		LIMIT 1`
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var one int
+	err := p.db.QueryRowContext(ctx, query, schema, table).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("checking identity column: %w", err)
+	}
+	return true, nil
+}
+
+// GetPrimaryKeyColumns returns the column names that form the primary key for a given schema.table.
+func (p *Postgres) GetPrimaryKeyColumns(ctx context.Context, schemaTable string) ([]string, error) {
+	schema, table := splitPgTable(schemaTable)
+
+	query := `SELECT kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_name = kcu.constraint_name
+			AND tc.table_schema = kcu.table_schema
+			AND tc.table_name = kcu.table_name
+		WHERE tc.constraint_type = 'PRIMARY KEY'
+			AND tc.table_schema = $1
+			AND tc.table_name = $2
+		ORDER BY kcu.ordinal_position`
+
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("querying primary key columns: %w", err)
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, fmt.Errorf("scanning PK column: %w", err)
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+// InsertBatch performs an upsert of rows into the target table by copying
+// them into a temp table and running INSERT ... ON CONFLICT from it. If
+// pkColumns is empty, rows are copied straight into the table.
+func (p *Postgres) InsertBatch(ctx context.Context, schemaTable string, columns []string, pkColumns []string, hasIdentity bool, rows [][]interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if len(pkColumns) == 0 {
+		schema, table := splitPgTable(schemaTable)
+		if err := copyRows(ctx, tx, pq.CopyInSchema(schema, table, columns...), rows); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+		return nil
+	}
+
+	// 1. Create temp table matching target schema, dropped on commit.
+	createTemp := fmt.Sprintf("CREATE TEMP TABLE scalesync_temp (LIKE %s) ON COMMIT DROP", quotePgTable(schemaTable))
+	if _, err := tx.ExecContext(ctx, createTemp); err != nil {
+		return fmt.Errorf("create temp table: %w", err)
+	}
+
+	// 2. COPY rows into the temp table.
+	if err := copyRows(ctx, tx, pq.CopyIn("scalesync_temp", columns...), rows); err != nil {
+		return err
+	}
+
+	// 3. Upsert into target.
+	upsertSQL := buildUpsertSQL(schemaTable, "scalesync_temp", columns, pkColumns, hasIdentity)
+	p.logger.Debug("upsert", "table", schemaTable, "rows", len(rows), "sql", upsertSQL)
+	if _, err := tx.ExecContext(ctx, upsertSQL); err != nil {
+		return fmt.Errorf("upsert: %w", pgError(err))
+	}
+
+	// 4. Commit.
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// copyRows runs the COPY statement copyStmt within tx for rows.
+func copyRows(ctx context.Context, tx *sql.Tx, copyStmt string, rows [][]interface{}) error {
+	stmt, err := tx.PrepareContext(ctx, copyStmt)
+	if err != nil {
+		return fmt.Errorf("prepare copy: %w", err)
+	}
+	defer stmt.Close() //nolint:errcheck
+
+	for i, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("exec row %d: %w", i, pgError(err))
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("flush copy: %w", pgError(err))
+	}
+	return nil
+}
+
+// buildUpsertSQL constructs an INSERT ... SELECT from source (already quoted)
+// into the target table that updates rows whose primary key already exists.
+func buildUpsertSQL(schemaTable, source string, columns, pkColumns []string, hasIdentity bool) string {
+	pkSet := make(map[string]bool, len(pkColumns))
+	pkList := make([]string, len(pkColumns))
+	for i, pk := range pkColumns {
+		pkSet[strings.ToUpper(pk)] = true
+		pkList[i] = quotePgIdent(pk)
+	}
+
+	colList := make([]string, len(columns))
+	var setParts []string
+	for i, col := range columns {
+		colList[i] = quotePgIdent(col)
+		if !pkSet[strings.ToUpper(col)] {
+			setParts = append(setParts, fmt.Sprintf("%s = EXCLUDED.%s", colList[i], colList[i]))
+		}
+	}
+	cols := strings.Join(colList, ", ")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) ", quotePgTable(schemaTable), cols)
+	if hasIdentity {
+		b.WriteString("OVERRIDING SYSTEM VALUE ")
+	}
+	fmt.Fprintf(&b, "SELECT %s FROM %s ", cols, source)
+	fmt.Fprintf(&b, "ON CONFLICT (%s) ", strings.Join(pkList, ", "))
+	if len(setParts) > 0 {
+		fmt.Fprintf(&b, "DO UPDATE SET %s", strings.Join(setParts, ", "))
+	} else {
+		b.WriteString("DO NOTHING")
+	}
+	return b.String()
+}
+
+// CreateStagingTable creates an empty, unlogged table next to schemaTable
+// with the given columns and no constraints or identity, returning its name.
+// Staging tables left behind by runs that died are dropped first.
+func (p *Postgres) CreateStagingTable(ctx context.Context, schemaTable string, columns []string) (string, error) {
+	dropStaleStages(ctx, p.db, `SELECT schemaname, tablename FROM pg_tables
+		WHERE tablename LIKE '\_\_scalesync\_stage\_%'`, p.DropTable, p.logger)
+
+	schema, _ := splitPgTable(schemaTable)
+	name, err := newStageName(time.Now())
+	if err != nil {
+		return "", err
+	}
+	stage := schema + "." + name
+
+	colList := make([]string, len(columns))
+	for i, c := range columns {
+		colList[i] = quotePgIdent(c)
+	}
+	query := fmt.Sprintf("CREATE UNLOGGED TABLE %s AS SELECT %s FROM %s WITH NO DATA",
+		quotePgTable(stage), strings.Join(colList, ", "), quotePgTable(schemaTable))
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	p.logger.Debug("creating staging table", "table", stage, "sql", query)
+	if _, err := p.db.ExecContext(ctx, query); err != nil {
+		return "", fmt.Errorf("creating staging table: %w", err)
+	}
+	return stage, nil
+}
+
+// DropTable drops the named table if it exists.
+func (p *Postgres) DropTable(ctx context.Context, schemaTable string) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if _, err := p.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", quotePgTable(schemaTable))); err != nil {
+		return fmt.Errorf("dropping %s: %w", schemaTable, err)
+	}
+	return nil
+}
+
+// MirrorFromStaging makes the target table match the staging table. Postgres
+// before 17 has no MERGE ... WHEN NOT MATCHED BY SOURCE, so this upserts with
+// INSERT ... ON CONFLICT and deletes the missing rows in the same transaction.
+func (p *Postgres) MirrorFromStaging(ctx context.Context, schemaTable, stage string, columns, pkColumns []string, hasIdentity bool, opts MirrorOptions) (MirrorStats, error) {
+	var stats MirrorStats
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Minute)
+	defer cancel()
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	target := quotePgTable(schemaTable)
+
+	// 1. Measure the delete before doing it.
+	scopeWhere := ""
+	if opts.Scope != "" {
+		scopeWhere = fmt.Sprintf(" WHERE (%s)", opts.Scope)
+	}
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s%s", target, scopeWhere)).Scan(&stats.InScope); err != nil {
+		return stats, fmt.Errorf("counting rows in scope: %w", err)
+	}
+
+	onParts := make([]string, len(pkColumns))
+	for i, pk := range pkColumns {
+		onParts[i] = fmt.Sprintf("target.%s = source.%s", quotePgIdent(pk), quotePgIdent(pk))
+	}
+	missing := fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s AS source WHERE %s)", quotePgTable(stage), strings.Join(onParts, " AND "))
+	if opts.Scope != "" {
+		missing = fmt.Sprintf("(%s) AND %s", opts.Scope, missing)
+	}
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s AS target WHERE %s", target, missing)).Scan(&stats.Deleted); err != nil {
+		return stats, fmt.Errorf("counting rows to delete: %w", err)
+	}
+	if err := checkDeleteThreshold(stats, opts); err != nil {
+		return stats, err
+	}
+
+	// 2. Upsert, then delete.
+	upsertSQL := buildUpsertSQL(schemaTable, quotePgTable(stage), columns, pkColumns, hasIdentity)
+	deleteSQL := fmt.Sprintf("DELETE FROM %s AS target WHERE %s", target, missing)
+	p.logger.Debug("mirror", "table", schemaTable, "in_scope", stats.InScope, "deleting", stats.Deleted,
+		"upsert_sql", upsertSQL, "delete_sql", deleteSQL)
+	res, err := tx.ExecContext(ctx, upsertSQL)
+	if err != nil {
+		return stats, fmt.Errorf("upsert: %w", pgError(err))
+	}
+	if affected, err := res.RowsAffected(); err == nil {
+		stats.Merged = affected
+	}
+	if _, err := tx.ExecContext(ctx, deleteSQL); err != nil {
+		return stats, fmt.Errorf("delete: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return stats, fmt.Errorf("commit: %w", err)
+	}
+	return stats, nil
+}
+
+// BuildSelect returns a SELECT of columns from schemaTable with optional raw
+// WHERE and ORDER BY clauses (without their keywords).
+func (p *Postgres) BuildSelect(schemaTable string, columns []string, where, orderBy string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quotePgIdent(c)
+	}
+	return buildSelect(quotePgTable(schemaTable), quoted, where, orderBy)
+}
+
+// splitPgTable splits "schema.table" into its parts. Defaults to "public" if no dot.
+func splitPgTable(schemaTable string) (string, string) {
+	if i := strings.IndexByte(schemaTable, '.'); i >= 0 {
+		return schemaTable[:i], schemaTable[i+1:]
+	}
+	return "public", schemaTable
+}
+
+// quotePgIdent quotes a Postgres identifier so mixed-case names survive.
+func quotePgIdent(name string) string {
+	return pq.QuoteIdentifier(name)
+}
+
+// quotePgTable quotes both parts of "schema.table".
+func quotePgTable(schemaTable string) string {
+	schema, table := splitPgTable(schemaTable)
+	return quotePgIdent(schema) + "." + quotePgIdent(table)
+}
+
+// copyColumnRe extracts the column from a COPY error context such as
+// `COPY orders, line 3, column qty: "abc"`.
+var copyColumnRe = regexp.MustCompile(`column (\S+?):`)
+
+// pgError adds the offending column to a Postgres error's message when the
+// server reported one, so refused rows can be attributed to a column.
+func pgError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	col := pqErr.Column
+	if col == "" {
+		if m := copyColumnRe.FindStringSubmatch(pqErr.Where); m != nil {
+			col = m[1]
+		}
+	}
+	if col == "" {
+		return err
+	}
+	return fmt.Errorf("%w (column '%s')", err, col)
+}
@@ -0,0 +1,18 @@
+package database
+
+import "testing"
+
+func TestBuildUpsertSQL(t *testing.T) {
+	got := buildUpsertSQL("sales.Orders", "scalesync_temp", []string{"OrderId", "Qty"}, []string{"OrderId"}, true)
+	want := `INSERT INTO "sales"."Orders" ("OrderId", "Qty") OVERRIDING SYSTEM VALUE ` +
+		`SELECT "OrderId", "Qty" FROM scalesync_temp ` +
+		`ON CONFLICT ("OrderId") DO UPDATE SET "Qty" = EXCLUDED."Qty"`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	keyOnly := buildUpsertSQL("orders", `"public"."stage"`, []string{"id"}, []string{"id"}, false)
+	if want := `INSERT INTO "public"."orders" ("id") SELECT "id" FROM "public"."stage" ON CONFLICT ("id") DO NOTHING`; keyOnly != want {
+		t.Errorf("got  %s\nwant %s", keyOnly, want)
+	}
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CreateTableSQL returns the CREATE TABLE statement for schemaTable with
+// cols on driver, with pkColumns as its primary key if there are any. The
+// columns' types are SQL Server's (as inferred by worker.SchemaInferrer);
+// for Postgres they are translated to the nearest equivalent.
+func CreateTableSQL(driver, schemaTable string, cols []TableColumn, pkColumns []string) string {
+	quote, table := identQuoter(driver), quoteTableFor(driver, schemaTable)
+
+	lines := make([]string, 0, len(cols)+1)
+	for _, c := range cols {
+		lines = append(lines, "    "+columnDef(driver, c))
+	}
+	if len(pkColumns) > 0 {
+		keys := make([]string, len(pkColumns))
+		for i, pk := range pkColumns {
+			keys[i] = quote(pk)
+		}
+		constraint := ""
+		if driver != DriverPostgres {
+			_, name := SplitSchemaTable(schemaTable)
+			constraint = fmt.Sprintf("CONSTRAINT %s ", quote("PK_"+name))
+		}
+		lines = append(lines, fmt.Sprintf("    %sPRIMARY KEY (%s)", constraint, strings.Join(keys, ", ")))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", table, strings.Join(lines, ",\n"))
+}
+
+// AddColumnsSQL returns an ALTER TABLE statement adding each of cols to
+// schemaTable on driver, as nullable columns whatever their IsNullable, so
+// rows already in the table stay valid.
+func AddColumnsSQL(driver, schemaTable string, cols []TableColumn) []string {
+	table := quoteTableFor(driver, schemaTable)
+	add := "ADD"
+	if driver == DriverPostgres {
+		add = "ADD COLUMN"
+	}
+	stmts := make([]string, len(cols))
+	for i, c := range cols {
+		c.IsNullable = true
+		stmts[i] = fmt.Sprintf("ALTER TABLE %s %s %s;", table, add, columnDef(driver, c))
+	}
+	return stmts
+}
+
+// identQuoter returns the function quoting identifiers on driver.
+func identQuoter(driver string) func(string) string {
+	if driver == DriverPostgres {
+		return quotePgIdent
+	}
+	return func(name string) string { return fmt.Sprintf("[%s]", name) }
+}
+
+// quoteTableFor returns schemaTable quoted for driver.
+func quoteTableFor(driver, schemaTable string) string {
+	if driver == DriverPostgres {
+		return quotePgTable(schemaTable)
+	}
+	schema, name := SplitSchemaTable(schemaTable)
+	return fmt.Sprintf("[%s].[%s]", schema, name)
+}
+
+// columnDef renders c as a column definition: its quoted name, type and
+// nullability.
+func columnDef(driver string, c TableColumn) string {
+	quote := identQuoter(driver)
+	null := "NULL"
+	if !c.IsNullable {
+		null = "NOT NULL"
+	}
+	return fmt.Sprintf("%s %s %s", quote(c.Name), ColumnType(driver, c), null)
+}
+
+// pgTypes maps the SQL Server types CreateTableSQL handles to Postgres.
+var pgTypes = map[string]string{
+	"bit":            "boolean",
+	"int":            "integer",
+	"bigint":         "bigint",
+	"decimal":        "numeric",
+	"datetime2":      "timestamp",
+	"datetimeoffset": "timestamptz",
+	"nvarchar":       "varchar",
+}
+
+// ColumnType renders c's type on driver, with its length or precision. A
+// MaxLength of -1 is nvarchar(max), or text on Postgres.
+func ColumnType(driver string, c TableColumn) string {
+	dt := c.DataType
+	if driver == DriverPostgres {
+		if pg, ok := pgTypes[dt]; ok {
+			dt = pg
+		}
+	}
+	switch {
+	case c.Precision > 0:
+		return fmt.Sprintf("%s(%d,%d)", dt, c.Precision, c.Scale)
+	case c.MaxLength < 0 && driver == DriverPostgres:
+		return "text"
+	case c.MaxLength < 0:
+		return dt + "(max)"
+	case c.MaxLength > 0:
+		return fmt.Sprintf("%s(%d)", dt, c.MaxLength)
+	}
+	return dt
+}
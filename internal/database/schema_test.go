@@ -0,0 +1,50 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCreateTableSQL(t *testing.T) {
+	cols := []TableColumn{
+		{Name: "ID", DataType: "int"},
+		{Name: "AMOUNT", DataType: "decimal", Precision: 9, Scale: 2, IsNullable: true},
+		{Name: "NOTE", DataType: "nvarchar", MaxLength: -1, IsNullable: true},
+		{Name: "CODE", DataType: "nvarchar", MaxLength: 50, IsNullable: true},
+	}
+
+	want := "CREATE TABLE [dbo].[ORDERS] (\n" +
+		"    [ID] int NOT NULL,\n" +
+		"    [AMOUNT] decimal(9,2) NULL,\n" +
+		"    [NOTE] nvarchar(max) NULL,\n" +
+		"    [CODE] nvarchar(50) NULL,\n" +
+		"    CONSTRAINT [PK_ORDERS] PRIMARY KEY ([ID])\n);"
+	if got := CreateTableSQL(DriverSQLServer, "dbo.ORDERS", cols, []string{"ID"}); got != want {
+		t.Errorf("sqlserver:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = "CREATE TABLE \"public\".\"orders\" (\n" +
+		"    \"ID\" integer NOT NULL,\n" +
+		"    \"AMOUNT\" numeric(9,2) NULL,\n" +
+		"    \"NOTE\" text NULL,\n" +
+		"    \"CODE\" varchar(50) NULL\n);"
+	if got := CreateTableSQL(DriverPostgres, "public.orders", cols, nil); got != want {
+		t.Errorf("postgres:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAddColumnsSQL(t *testing.T) {
+	cols := []TableColumn{{Name: "REGION", DataType: "nvarchar", MaxLength: 50}, {Name: "SCORE", DataType: "int"}}
+
+	got := AddColumnsSQL(DriverSQLServer, "dbo.ORDERS", cols)
+	want := []string{
+		"ALTER TABLE [dbo].[ORDERS] ADD [REGION] nvarchar(50) NULL;",
+		"ALTER TABLE [dbo].[ORDERS] ADD [SCORE] int NULL;",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sqlserver: %q, want %q", got, want)
+	}
+	if got := AddColumnsSQL(DriverPostgres, "public.orders", cols[1:]); !reflect.DeepEqual(got, []string{`ALTER TABLE "public"."orders" ADD COLUMN "SCORE" integer NULL;`}) {
+		t.Errorf("postgres: %q", got)
+	}
+}
@@ -13,10 +13,26 @@ type TableColumn struct {
 	DataType   string
 	IsNullable bool
 	OrdinalPos int
+	// MaxLength is the character (or byte) length limit for string and
+	// binary types, -1 for (max), and 0 when not applicable.
+	MaxLength int
+	// Precision and Scale apply to numeric types; 0 when not applicable.
+	Precision int
+	Scale     int
+	// DateTimePrecision is the number of fractional-second digits kept by
+	// time types; 0 when not applicable or whole seconds.
+	DateTimePrecision int
+	// Collation is the collation of character columns, e.g.
+	// "Latin1_General_100_CI_AS_SC_UTF8"; empty for other types.
+	Collation string
+	// Computed is set for columns whose value the database derives and which
+	// can't be written: SQL Server computed columns and Postgres generated
+	// columns.
+	Computed bool
 }
 
 // ListTables returns all user table names from the connected database.
-func ListTables(ctx context.Context, db *sql.DB) ([]string, error) {
+func (s *SQLServer) ListTables(ctx context.Context) ([]string, error) {
 	query := `SELECT s.name + '.' + t.name
 		FROM sys.tables t
 		JOIN sys.schemas s ON t.schema_id = s.schema_id
@@ -25,7 +41,7 @@ func ListTables(ctx context.Context, db *sql.DB) ([]string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("querying sys.tables: %w", err)
 	}
@@ -50,7 +66,7 @@ type TableRowCount struct {
 
 // ListTablesWithCounts returns all user tables with their row counts using
 // sys.dm_db_partition_stats (heap/clustered index only) for fast results.
-func ListTablesWithCounts(ctx context.Context, db *sql.DB) ([]TableRowCount, error) {
+func (s *SQLServer) ListTablesWithCounts(ctx context.Context) ([]TableRowCount, error) {
 	query := `SELECT s.name + '.' + t.name, SUM(p.row_count)
 		FROM sys.tables t
 		JOIN sys.schemas s ON t.schema_id = s.schema_id
@@ -61,7 +77,7 @@ func ListTablesWithCounts(ctx context.Context, db *sql.DB) ([]TableRowCount, err
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("querying table counts: %w", err)
 	}
@@ -79,18 +95,23 @@ func ListTablesWithCounts(ctx context.Context, db *sql.DB) ([]TableRowCount, err
 }
 
 // GetTableColumns returns the column definitions for a given schema.table.
-func GetTableColumns(ctx context.Context, db *sql.DB, schemaTable string) ([]TableColumn, error) {
-	schema, table := splitSchemaTable(schemaTable)
-
-	query := `SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE, ORDINAL_POSITION
-		FROM INFORMATION_SCHEMA.COLUMNS
-		WHERE TABLE_SCHEMA = @schema AND TABLE_NAME = @table
-		ORDER BY ORDINAL_POSITION`
+func (s *SQLServer) GetTableColumns(ctx context.Context, schemaTable string) ([]TableColumn, error) {
+	schema, table := SplitSchemaTable(schemaTable)
+
+	query := `SELECT c.COLUMN_NAME, c.DATA_TYPE, c.IS_NULLABLE, c.ORDINAL_POSITION,
+			c.CHARACTER_MAXIMUM_LENGTH, c.NUMERIC_PRECISION, c.NUMERIC_SCALE,
+			c.DATETIME_PRECISION, c.COLLATION_NAME, ISNULL(sc.is_computed, 0)
+		FROM INFORMATION_SCHEMA.COLUMNS c
+		LEFT JOIN sys.columns sc
+			ON sc.object_id = OBJECT_ID(QUOTENAME(c.TABLE_SCHEMA) + '.' + QUOTENAME(c.TABLE_NAME))
+			AND sc.name = c.COLUMN_NAME
+		WHERE c.TABLE_SCHEMA = @schema AND c.TABLE_NAME = @table
+		ORDER BY c.ORDINAL_POSITION`
 
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query, sql.Named("schema", schema), sql.Named("table", table))
+	rows, err := s.db.QueryContext(ctx, query, sql.Named("schema", schema), sql.Named("table", table))
 	if err != nil {
 		return nil, fmt.Errorf("querying columns: %w", err)
 	}
@@ -100,18 +121,26 @@ func GetTableColumns(ctx context.Context, db *sql.DB, schemaTable string) ([]Tab
 	for rows.Next() {
 		var c TableColumn
 		var nullable string
-		if err := rows.Scan(&c.Name, &c.DataType, &nullable, &c.OrdinalPos); err != nil {
+		var maxLen, precision, scale, timePrecision sql.NullInt64
+		var collation sql.NullString
+		if err := rows.Scan(&c.Name, &c.DataType, &nullable, &c.OrdinalPos,
+			&maxLen, &precision, &scale, &timePrecision, &collation, &c.Computed); err != nil {
 			return nil, fmt.Errorf("scanning column: %w", err)
 		}
 		c.IsNullable = nullable == "YES"
+		c.MaxLength = int(maxLen.Int64)
+		c.Precision = int(precision.Int64)
+		c.Scale = int(scale.Int64)
+		c.DateTimePrecision = int(timePrecision.Int64)
+		c.Collation = collation.String
 		cols = append(cols, c)
 	}
 	return cols, rows.Err()
 }
 
 // HasIdentityColumn returns true if the given table has an identity column.
-func HasIdentityColumn(ctx context.Context, db *sql.DB, schemaTable string) (bool, error) {
-	schema, table := splitSchemaTable(schemaTable)
+func (s *SQLServer) HasIdentityColumn(ctx context.Context, schemaTable string) (bool, error) {
+	schema, table := SplitSchemaTable(schemaTable)
 
 	query := `SELECT 1 FROM sys.columns c
 		JOIN sys.tables t ON c.object_id = t.object_id
@@ -122,7 +151,7 @@ func HasIdentityColumn(ctx context.Context, db *sql.DB, schemaTable string) (boo
 	defer cancel()
 
 	var one int
-	err := db.QueryRowContext(ctx, query, sql.Named("schema", schema), sql.Named("table", table)).Scan(&one)
+	err := s.db.QueryRowContext(ctx, query, sql.Named("schema", schema), sql.Named("table", table)).Scan(&one)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
@@ -133,8 +162,8 @@ func HasIdentityColumn(ctx context.Context, db *sql.DB, schemaTable string) (boo
 }
 
 // GetPrimaryKeyColumns returns the column names that form the primary key for a given schema.table.
-func GetPrimaryKeyColumns(ctx context.Context, db *sql.DB, schemaTable string) ([]string, error) {
-	schema, table := splitSchemaTable(schemaTable)
+func (s *SQLServer) GetPrimaryKeyColumns(ctx context.Context, schemaTable string) ([]string, error) {
+	schema, table := SplitSchemaTable(schemaTable)
 
 	query := `SELECT kcu.COLUMN_NAME
 		FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
@@ -150,7 +179,7 @@ func GetPrimaryKeyColumns(ctx context.Context, db *sql.DB, schemaTable string) (
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query, sql.Named("schema", schema), sql.Named("table", table))
+	rows, err := s.db.QueryContext(ctx, query, sql.Named("schema", schema), sql.Named("table", table))
 	if err != nil {
 		return nil, fmt.Errorf("querying primary key columns: %w", err)
 	}
@@ -167,8 +196,8 @@ func GetPrimaryKeyColumns(ctx context.Context, db *sql.DB, schemaTable string) (
 	return cols, rows.Err()
 }
 
-// splitSchemaTable splits "schema.table" into its parts. Defaults to "dbo" if no dot.
-func splitSchemaTable(schemaTable string) (string, string) {
+// SplitSchemaTable splits "schema.table" into its parts. Defaults to "dbo" if no dot.
+func SplitSchemaTable(schemaTable string) (string, string) {
 	for i, ch := range schemaTable {
 		if ch == '.' {
 			return schemaTable[:i], schemaTable[i+1:]
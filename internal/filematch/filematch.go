@@ -0,0 +1,151 @@
+// Package filematch resolves the table a CSV file in the input directory
+// loads into, using the match_rules from config.yaml.
+package filematch
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/walkerscm/scaleSyncGo/internal/config"
+)
+
+// DefaultRule is the name of the built-in rule for
+// [<schema>.]<TABLE>_inserts_<timestamp>.csv (or .parquet, .jsonl, .ndjson,
+// .xlsx), which is always tried last.
+const DefaultRule = "default"
+
+var defaultPattern = regexp.MustCompile(`(?i)^(?:(?P<schema>[^.]+)\.)?(?P<table>[^.]+?)_inserts_(?P<timestamp>.*)\.(?:csv|parquet|jsonl|ndjson|xlsx)$`)
+
+// Match is what a rule resolved a file to.
+type Match struct {
+	// Rule is the name of the rule that matched.
+	Rule string
+	// Schema is empty when the destination's default schema applies.
+	Schema    string
+	Table     string
+	Timestamp string
+}
+
+// QualifiedName returns schema.table, using defaultSchema if the match
+// has no schema.
+func (m Match) QualifiedName(defaultSchema string) string {
+	schema := m.Schema
+	if schema == "" {
+		schema = defaultSchema
+	}
+	return schema + "." + m.Table
+}
+
+type rule struct {
+	name    string
+	file    string
+	re      *regexp.Regexp
+	schema  string
+	table   string
+	fromDir bool
+}
+
+// Matcher tries a list of rules in order.
+type Matcher struct {
+	rules []rule
+}
+
+// Compile validates rules and returns a Matcher that tries them in order,
+// followed by the built-in default rule.
+func Compile(rules []config.MatchRule) (*Matcher, error) {
+	m := &Matcher{}
+	for i, r := range rules {
+		name := r.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		if r.File != "" && r.Pattern != "" {
+			return nil, fmt.Errorf("match rule %q: set file or pattern, not both", name)
+		}
+
+		c := rule{name: name, file: r.File, schema: r.Schema, table: r.Table, fromDir: r.SchemaFromDir}
+		if schema, table, ok := strings.Cut(r.Table, "."); ok {
+			c.schema, c.table = schema, table
+		}
+		switch {
+		case r.Pattern != "":
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("match rule %q: %w", name, err)
+			}
+			c.re = re
+		case r.File == "" && r.SchemaFromDir:
+			c.re = defaultPattern
+		case r.File == "":
+			return nil, fmt.Errorf("match rule %q: needs a file, a pattern or schema_from_dir", name)
+		}
+		if c.table == "" && (c.re == nil || c.re.SubexpIndex("table") < 0) {
+			return nil, fmt.Errorf("match rule %q: needs a table, or a pattern with a (?P<table>...) group", name)
+		}
+		m.rules = append(m.rules, c)
+	}
+	m.rules = append(m.rules, rule{name: DefaultRule, re: defaultPattern})
+	return m, nil
+}
+
+// UsesDirectories reports whether any rule matches files in subdirectories.
+func (m *Matcher) UsesDirectories() bool {
+	for _, r := range m.rules {
+		if r.fromDir {
+			return true
+		}
+	}
+	return false
+}
+
+// Match resolves relPath, a file's path relative to the input directory,
+// with the first rule that matches it. Rules with schema_from_dir only match
+// files one directory down; all others only match files at the top level.
+func (m *Matcher) Match(relPath string) (Match, bool) {
+	dir, base := filepath.Split(filepath.ToSlash(relPath))
+	dir = strings.TrimSuffix(dir, "/")
+	if strings.Contains(dir, "/") {
+		return Match{}, false
+	}
+
+	for _, r := range m.rules {
+		if r.fromDir != (dir != "") {
+			continue
+		}
+		res := Match{Rule: r.name, Schema: r.schema, Table: r.table}
+		if r.fromDir {
+			res.Schema = dir
+		}
+		if r.file != "" {
+			if !strings.EqualFold(base, r.file) {
+				continue
+			}
+			return res, true
+		}
+
+		groups := r.re.FindStringSubmatch(base)
+		if groups == nil {
+			continue
+		}
+		for i, g := range r.re.SubexpNames() {
+			if groups[i] == "" {
+				continue
+			}
+			switch g {
+			case "schema":
+				res.Schema = groups[i]
+			case "table":
+				res.Table = groups[i]
+			case "timestamp":
+				res.Timestamp = groups[i]
+			}
+		}
+		if res.Table == "" {
+			continue
+		}
+		return res, true
+	}
+	return Match{}, false
+}
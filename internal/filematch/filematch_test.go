@@ -0,0 +1,61 @@
+package filematch
+
+import (
+	"testing"
+
+	"github.com/walkerscm/scaleSyncGo/internal/config"
+)
+
+func TestMatch(t *testing.T) {
+	m, err := Compile([]config.MatchRule{
+		{Name: "legacy", File: "legacy_dump.csv", Table: "staging.LEGACY"},
+		{Name: "sales", Pattern: `^sales_(?P<table>\w+)_(?P<timestamp>\d{8})\.csv$`, Schema: "sales"},
+		{Name: "qualified", Pattern: `^(?P<schema>\w+)\.(?P<table>\w+)\.csv$`},
+		{Name: "by-dir", SchemaFromDir: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.UsesDirectories() {
+		t.Error("UsesDirectories = false, want true")
+	}
+
+	tests := []struct {
+		path string
+		want Match
+		ok   bool
+	}{
+		{"LEGACY_DUMP.csv", Match{Rule: "legacy", Schema: "staging", Table: "LEGACY"}, true},
+		{"sales_ORDERS_20260211.csv", Match{Rule: "sales", Schema: "sales", Table: "ORDERS", Timestamp: "20260211"}, true},
+		{"staging.ITEMS.csv", Match{Rule: "qualified", Schema: "staging", Table: "ITEMS"}, true},
+		{"staging/ITEMS_inserts_20260211_170255.csv", Match{Rule: "by-dir", Schema: "staging", Table: "ITEMS", Timestamp: "20260211_170255"}, true},
+		{"ITEMS_inserts_20260211_170255.csv", Match{Rule: DefaultRule, Table: "ITEMS", Timestamp: "20260211_170255"}, true},
+		{"ITEMS_inserts_20260211_170255.parquet", Match{Rule: DefaultRule, Table: "ITEMS", Timestamp: "20260211_170255"}, true},
+		{"ITEMS_inserts_20260211_170255.jsonl", Match{Rule: DefaultRule, Table: "ITEMS", Timestamp: "20260211_170255"}, true},
+		{"ITEMS_inserts_20260211_Sheet1.xlsx", Match{Rule: DefaultRule, Table: "ITEMS", Timestamp: "20260211_Sheet1"}, true},
+		{"sales.ORDERS_inserts_20260211_170255.parquet", Match{Rule: DefaultRule, Schema: "sales", Table: "ORDERS", Timestamp: "20260211_170255"}, true},
+		{"staging/legacy_dump.csv", Match{}, false},
+		{"a/b/ITEMS_inserts_1.csv", Match{}, false},
+		{"random.csv", Match{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := m.Match(tt.path)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Match(%q) = %+v, %v; want %+v, %v", tt.path, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []config.MatchRule{
+		{Name: "both", File: "a.csv", Pattern: "a", Table: "T"},
+		{Name: "nothing", Table: "T"},
+		{Name: "no table", Pattern: `^x\.csv$`},
+		{Name: "bad regex", Pattern: `(?P<table>`},
+	}
+	for _, r := range tests {
+		if _, err := Compile([]config.MatchRule{r}); err == nil {
+			t.Errorf("Compile(%s) succeeded, want error", r.Name)
+		}
+	}
+}
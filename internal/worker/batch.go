@@ -1,10 +1,16 @@
 package worker
 
 import (
+	"encoding/hex"
+	"fmt"
+	"math"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"github.com/walkerscm/scaleSyncGo/internal/database"
 )
 
@@ -17,12 +23,15 @@ var dateTimeFormats = []string{
 	"2006-01-02 15:04:05.000",
 	"2006-01-02 15:04:05",
 	"2006-01-02",
+	"15:04:05",
 }
 
 // isDateTimeType returns true if the SQL data type is a date/time variant.
+// Type names cover both SQL Server and Postgres (internal names).
 func isDateTimeType(dt string) bool {
 	switch strings.ToLower(dt) {
-	case "datetime", "datetime2", "smalldatetime", "date", "time":
+	case "datetime", "datetime2", "smalldatetime", "date", "time",
+		"datetimeoffset", "timestamp", "timestamptz", "timetz":
 		return true
 	}
 	return false
@@ -33,7 +42,8 @@ func isNumericType(dt string) bool {
 	switch strings.ToLower(dt) {
 	case "int", "bigint", "smallint", "tinyint",
 		"decimal", "numeric", "money", "smallmoney",
-		"float", "real":
+		"float", "real",
+		"int2", "int4", "int8", "float4", "float8":
 		return true
 	}
 	return false
@@ -42,64 +52,360 @@ func isNumericType(dt string) bool {
 // ConvertBatch converts raw CSV string rows into [][]interface{} suitable for
 // bulk insert, using the column mapping to pick the right CSV indices and
 // convert values to appropriate Go types for each SQL column type.
-func ConvertBatch(rows [][]string, mapping []database.ColumnMapping) [][]interface{} {
-	result := make([][]interface{}, len(rows))
+//
+// Values too long or too precise for their column are handled by the
+// column's oversize policy. errs holds a *ValueError for each row with a
+// value its column's type can't take (see isCheckedType), a *SizeError for
+// each row the oversize policy refuses, and nil for the others.
+func ConvertBatch(rows [][]string, mapping []database.ColumnMapping) (result [][]interface{}, errs []error) {
+	return convertBatch(rows, mapping, false)
+}
+
+// ParseBatch is ConvertBatch for comparing values rather than loading them:
+// errs also holds a *ValueError for a row with a value of an unchecked type,
+// such as a number or date, that doesn't parse, instead of leaving it for
+// the database to refuse.
+func ParseBatch(rows [][]string, mapping []database.ColumnMapping) (result [][]interface{}, errs []error) {
+	return convertBatch(rows, mapping, true)
+}
+
+// convertBatch implements ConvertBatch and, with strict, ParseBatch.
+func convertBatch(rows [][]string, mapping []database.ColumnMapping, strict bool) (result [][]interface{}, errs []error) {
+	result = make([][]interface{}, len(rows))
+	errs = make([]error, len(rows))
+
+	for i, row := range rows {
+		converted := make([]interface{}, len(mapping))
+		var convErr error
+		for j, m := range mapping {
+			val, present := fieldText(row, m)
+			var err error
+			converted[j], err = convertText(val, present, m, strict)
+			if convErr == nil {
+				convErr = err
+			}
+		}
+		result[i], errs[i] = fitRow(converted, mapping)
+		if convErr != nil {
+			errs[i] = convErr
+		}
+	}
+
+	return result, errs
+}
+
+// fitRow applies fitValue to each value of a converted row, returning the
+// first overflow its column's policy refuses.
+func fitRow(row []interface{}, mapping []database.ColumnMapping) ([]interface{}, error) {
+	var err error
+	for j, m := range mapping {
+		v, problem := fitValue(row[j], m)
+		if problem != "" && err == nil {
+			err = &SizeError{Column: m.DBColumn.Name, Problem: problem}
+		}
+		row[j] = v
+	}
+	return row, err
+}
+
+// isDecimalType returns true if the SQL data type holds exact decimals,
+// which are converted to decimal.Decimal rather than float64.
+func isDecimalType(dt string) bool {
+	switch strings.ToLower(dt) {
+	case "decimal", "numeric", "money", "smallmoney":
+		return true
+	}
+	return false
+}
+
+// moneyRanges holds the bounds of the SQL Server money types.
+var moneyRanges = map[string][2]decimal.Decimal{
+	"money":      {decimal.RequireFromString("-922337203685477.5808"), decimal.RequireFromString("922337203685477.5807")},
+	"smallmoney": {decimal.RequireFromString("-214748.3648"), decimal.RequireFromString("214748.3647")},
+}
+
+// convertText converts the text val for mapping m; present is false when
+// the row has no value at all for the column. The error is a *ValueError
+// for a value of a checked type, or with strict of any type, that doesn't
+// parse.
+func convertText(val string, present bool, m database.ColumnMapping, strict bool) (interface{}, error) {
+	var v interface{}
+	var err error
+	switch {
+	case val == "" && m.Default != "":
+		v, err = evalDefault(m.Default, m.DBColumn.DataType)
+	case !present:
+		return nil, nil
+	case val == "":
+		if m.DBColumn.IsNullable {
+			return nil, nil
+		}
+		return val, nil
+	default:
+		v, err = parseValue(val, m.DBColumn.DataType)
+	}
+	if err != nil && (strict || isCheckedType(m.DBColumn.DataType)) {
+		return v, &ValueError{Column: m.DBColumn.Name, Problem: err.Error()}
+	}
+	return v, nil
+}
+
+// Verbatim is a string value, read from another database, that ConvertValues
+// loads into a character column exactly as it is: not trimmed, and an empty
+// string stays empty rather than becoming NULL. Into other columns it is
+// converted like any other string.
+type Verbatim string
+
+// ConvertValues is ConvertBatch for rows of typed values, such as those
+// read from Parquet, where nil is NULL. Strings are converted like CSV text.
+// Other values go to the database as they are, except into character
+// columns, which get their text (see FormatValue).
+func ConvertValues(rows [][]interface{}, mapping []database.ColumnMapping) (result [][]interface{}, errs []error) {
+	return convertValues(rows, mapping, false)
+}
+
+// ParseValues is ConvertValues reporting values as ParseBatch does.
+func ParseValues(rows [][]interface{}, mapping []database.ColumnMapping) (result [][]interface{}, errs []error) {
+	return convertValues(rows, mapping, true)
+}
+
+// convertValues implements ConvertValues and, with strict, ParseValues.
+func convertValues(rows [][]interface{}, mapping []database.ColumnMapping, strict bool) (result [][]interface{}, errs []error) {
+	result = make([][]interface{}, len(rows))
+	errs = make([]error, len(rows))
 
 	for i, row := range rows {
 		converted := make([]interface{}, len(mapping))
+		var convErr error
 		for j, m := range mapping {
-			if m.CSVIndex < len(row) {
-				val := strings.TrimSpace(row[m.CSVIndex])
-				if val == "" {
-					if m.DBColumn.IsNullable {
-						converted[j] = nil
-					} else {
-						converted[j] = val
-					}
+			var v interface{}
+			if m.CSVIndex >= 0 && m.CSVIndex < len(row) {
+				v = row[m.CSVIndex]
+			}
+			var err error
+			switch tv := v.(type) {
+			case string:
+				converted[j], err = convertText(strings.TrimSpace(tv), true, m, strict)
+			case Verbatim:
+				if isStringType(m.DBColumn.DataType) {
+					converted[j] = string(tv)
 				} else {
-					converted[j] = coerceValue(val, m.DBColumn.DataType)
+					converted[j], err = convertText(strings.TrimSpace(string(tv)), true, m, strict)
+				}
+			case nil:
+				if m.CSVIndex < 0 || m.Default != "" {
+					// A constant or default, given as text.
+					val, _ := fieldText(nil, m)
+					converted[j], err = convertText(val, true, m, strict)
 				}
-			} else {
-				converted[j] = nil
+			default:
+				converted[j] = nativeValue(v, m.DBColumn.DataType)
+			}
+			if convErr == nil {
+				convErr = err
 			}
 		}
-		result[i] = converted
+		result[i], errs[i] = fitRow(converted, mapping)
+		if convErr != nil {
+			errs[i] = convErr
+		}
+	}
+
+	return result, errs
+}
+
+// nativeValue returns the typed value v as sent to a column of dataType.
+// Numbers headed for decimal columns become decimal.Decimal, floats by their
+// shortest representation (0.1 stays 0.1).
+func nativeValue(v interface{}, dataType string) interface{} {
+	if isDecimalType(dataType) {
+		switch n := v.(type) {
+		case int64:
+			return decimal.NewFromInt(n)
+		case float64:
+			return decimal.NewFromFloat(n)
+		}
+		return v
+	}
+	if !isStringType(dataType) {
+		return v
+	}
+	if b, ok := v.([]byte); ok && utf8.Valid(b) {
+		return string(b)
+	}
+	return FormatValue(v, dataType)
+}
+
+// TextRow renders a row of typed values as CSV fields, for rejects files.
+func TextRow(values []interface{}) []string {
+	row := make([]string, len(values))
+	for i, v := range values {
+		row[i] = FormatValue(v, "")
+	}
+	return row
+}
+
+// fieldText returns the trimmed text for mapping m in row: the CSV value, or
+// the mapping's constant for columns with no CSV header. present is false
+// when the row has no value at all for the column.
+func fieldText(row []string, m database.ColumnMapping) (val string, present bool) {
+	switch {
+	case m.CSVIndex < 0 && m.Default == "":
+		return strings.TrimSpace(m.Constant), true
+	case m.CSVIndex >= 0 && m.CSVIndex < len(row):
+		return strings.TrimSpace(row[m.CSVIndex]), true
+	}
+	return "", false
+}
+
+// evalDefault evaluates a mapping file default expression for a column of
+// dataType. The supported functions are now()/getdate(), utcnow()/getutcdate(),
+// today() and newid()/uuid(); anything else is a literal value.
+func evalDefault(expr, dataType string) (interface{}, error) {
+	switch strings.ToLower(strings.TrimSpace(expr)) {
+	case "now()", "getdate()", "sysdatetime()":
+		return time.Now(), nil
+	case "utcnow()", "getutcdate()", "sysutcdatetime()":
+		return time.Now().UTC(), nil
+	case "today()":
+		y, m, d := time.Now().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local), nil
+	case "newid()", "uuid()":
+		return parseValue(uuid.NewString(), dataType)
 	}
+	return parseValue(strings.TrimSpace(expr), dataType)
+}
 
-	return result
+// integerRanges holds the bounds of the SQL Server integer types.
+var integerRanges = map[string][2]int64{
+	"tinyint":  {0, math.MaxUint8},
+	"smallint": {math.MinInt16, math.MaxInt16},
+	"int":      {math.MinInt32, math.MaxInt32},
+	"bigint":   {math.MinInt64, math.MaxInt64},
+	"int2":     {math.MinInt16, math.MaxInt16},
+	"int4":     {math.MinInt32, math.MaxInt32},
+	"int8":     {math.MinInt64, math.MaxInt64},
 }
 
-// coerceValue converts a non-empty CSV string to the appropriate Go type.
+// coerceValue converts a non-empty CSV string to the appropriate Go type:
+// time.Time for date/time columns, decimal.Decimal for decimal and money
+// columns, int64 or float64 for other numeric columns, bool for bits,
+// mssql.UniqueIdentifier for GUIDs and []byte for binary columns. XML, JSON,
+// spatial and hierarchyid values stay text once checked. Values that don't
+// parse are passed through as strings to let the driver try; use parseValue
+// to find out about them.
 func coerceValue(val, dataType string) interface{} {
+	v, _ := parseValue(val, dataType)
+	return v
+}
+
+// parseValue converts a non-empty CSV string to the appropriate Go type. It
+// returns the value coerceValue would send along with an error describing
+// why that value is not valid for dataType, if it isn't.
+func parseValue(val, dataType string) (interface{}, error) {
+	switch strings.ToLower(dataType) {
+	case "datetimeoffset", "timestamptz":
+		return parseOffsetTime(val, dataType)
+	case "uniqueidentifier", "uuid":
+		return parseGUID(val, dataType)
+	case "binary", "varbinary", "image", "bytea":
+		return parseBinary(val, dataType)
+	case "xml":
+		return checkXML(val)
+	case "json", "jsonb":
+		return checkJSON(val, dataType)
+	case "geography", "geometry":
+		return parseWKT(val, dataType)
+	case "hierarchyid":
+		return checkHierarchyID(val)
+	}
+
 	if isDateTimeType(dataType) {
 		for _, layout := range dateTimeFormats {
 			if t, err := time.Parse(layout, val); err == nil {
-				return t
+				return t, nil
 			}
 		}
-		// Couldn't parse — pass as string, let the driver try
-		return val
+		return val, fmt.Errorf("not parseable as %s", strings.ToLower(dataType))
+	}
+
+	if isDecimalType(dataType) {
+		// Exact, so 0.1 or a 19-digit amount reaches the column unchanged.
+		d, err := decimal.NewFromString(val)
+		if err != nil {
+			return val, fmt.Errorf("not parseable as %s", strings.ToLower(dataType))
+		}
+		if r, ok := moneyRanges[strings.ToLower(dataType)]; ok && (d.LessThan(r[0]) || d.GreaterThan(r[1])) {
+			return d, fmt.Errorf("out of range for %s", strings.ToLower(dataType))
+		}
+		return d, nil
 	}
 
 	if isNumericType(dataType) {
 		// Try int first, then float
+		intRange, isInteger := integerRanges[strings.ToLower(dataType)]
 		if iv, err := strconv.ParseInt(val, 10, 64); err == nil {
-			return iv
+			if isInteger && (iv < intRange[0] || iv > intRange[1]) {
+				return iv, fmt.Errorf("out of range for %s", strings.ToLower(dataType))
+			}
+			return iv, nil
 		}
 		if fv, err := strconv.ParseFloat(val, 64); err == nil {
-			return fv
+			if isInteger && fv != math.Trunc(fv) {
+				return fv, fmt.Errorf("not a whole number for %s", strings.ToLower(dataType))
+			}
+			return fv, nil
 		}
-		return val
+		return val, fmt.Errorf("not parseable as %s", strings.ToLower(dataType))
 	}
 
-	if strings.EqualFold(dataType, "bit") {
+	if strings.EqualFold(dataType, "bit") || strings.EqualFold(dataType, "bool") {
 		switch strings.ToLower(val) {
 		case "1", "true", "yes", "y":
-			return true
+			return true, nil
 		case "0", "false", "no", "n":
-			return false
+			return false, nil
 		}
+		return val, fmt.Errorf("not parseable as %s", strings.ToLower(dataType))
 	}
 
-	return val
+	return val, nil
+}
+
+// FormatValue renders a value read from the database as CSV text that
+// coerceValue (or, for types it passes through, the driver) parses back to
+// the same value, so exported files can be re-imported losslessly. NULL
+// becomes an empty string.
+func FormatValue(v interface{}, dataType string) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return "0x" + strings.ToUpper(hex.EncodeToString(v))
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case time.Time:
+		switch strings.ToLower(dataType) {
+		case "date":
+			return v.Format("2006-01-02")
+		case "time", "timetz":
+			return v.Format("15:04:05.0000000")
+		case "datetimeoffset", "timestamptz":
+			return v.Format("2006-01-02 15:04:05.9999999Z07:00")
+		default:
+			return v.Format("2006-01-02 15:04:05.0000000")
+		}
+	}
+	return fmt.Sprint(v)
 }
@@ -0,0 +1,304 @@
+package worker
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/shopspring/decimal"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+func TestFormatValueRoundTrip(t *testing.T) {
+	ts := time.Date(2026, 2, 11, 17, 2, 55, 123456700, time.UTC)
+
+	cases := []struct {
+		dataType string
+		value    interface{}
+	}{
+		{"datetime2", ts},
+		{"date", time.Date(2026, 2, 11, 0, 0, 0, 0, time.UTC)},
+		{"int", int64(-42)},
+		{"float", 0.1},
+		{"bit", true},
+		{"bit", false},
+	}
+
+	for _, c := range cases {
+		text := FormatValue(c.value, c.dataType)
+		got := coerceValue(text, c.dataType)
+		if want, ok := c.value.(time.Time); ok {
+			if gt, ok := got.(time.Time); !ok || !gt.Equal(want) {
+				t.Errorf("%s: %v → %q → %v", c.dataType, c.value, text, got)
+			}
+			continue
+		}
+		if got != c.value {
+			t.Errorf("%s: %v → %q → %v (%T)", c.dataType, c.value, text, got, got)
+		}
+	}
+}
+
+func TestFormatValueTime(t *testing.T) {
+	v := time.Date(1, 1, 1, 8, 30, 15, 500000000, time.UTC)
+	text := FormatValue(v, "time")
+	if text != "08:30:15.5000000" {
+		t.Fatalf("unexpected time format %q", text)
+	}
+	got, ok := coerceValue(text, "time").(time.Time)
+	if !ok || got.Hour() != 8 || got.Minute() != 30 || got.Nanosecond() != 500000000 {
+		t.Errorf("time did not round-trip: %v", got)
+	}
+}
+
+func TestFormatValueNull(t *testing.T) {
+	if got := FormatValue(nil, "nvarchar"); got != "" {
+		t.Errorf("expected empty string for NULL, got %q", got)
+	}
+}
+
+func TestConvertValues(t *testing.T) {
+	ts := time.Date(2026, 2, 11, 17, 2, 55, 0, time.UTC)
+	col := func(name, dataType string, nullable bool) database.ColumnMapping {
+		return database.ColumnMapping{DBColumn: database.TableColumn{Name: name, DataType: dataType, IsNullable: nullable}}
+	}
+	mapping := []database.ColumnMapping{
+		col("ID", "bigint", false),
+		col("PLACED", "datetime2", true),
+		col("QTY", "int", true),
+		col("NOTE", "nvarchar", true),
+		col("CODE", "varchar", true),
+		{CSVIndex: -1, DBColumn: database.TableColumn{Name: "SRC", DataType: "varchar"}, Constant: "lake"},
+	}
+	for i := range mapping[:5] {
+		mapping[i].CSVIndex = i
+	}
+
+	got, _ := ConvertValues([][]interface{}{{int64(7), ts, " 12 ", nil, []byte("A1")}}, mapping)
+	want := []interface{}{int64(7), ts, int64(12), nil, "A1", "lake"}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Fatalf("ConvertValues = %#v, want %#v", got[0], want)
+	}
+}
+
+func TestConvertValuesVerbatim(t *testing.T) {
+	mapping := []database.ColumnMapping{
+		{CSVIndex: 0, DBColumn: database.TableColumn{Name: "PADDED", DataType: "nchar", IsNullable: true}},
+		{CSVIndex: 1, DBColumn: database.TableColumn{Name: "BLANK", DataType: "nvarchar", IsNullable: true}},
+		{CSVIndex: 2, DBColumn: database.TableColumn{Name: "QTY", DataType: "int", IsNullable: true}},
+		{CSVIndex: 3, DBColumn: database.TableColumn{Name: "EMPTY", DataType: "int", IsNullable: true}},
+	}
+
+	got, errs := ConvertValues([][]interface{}{{Verbatim("AB  "), Verbatim(""), Verbatim(" 12 "), Verbatim("")}}, mapping)
+	want := []interface{}{"AB  ", "", int64(12), nil}
+	if !reflect.DeepEqual(got[0], want) || errs[0] != nil {
+		t.Fatalf("ConvertValues = %#v, %v; want %#v", got[0], errs[0], want)
+	}
+}
+
+func TestConvertBatchOversize(t *testing.T) {
+	col := func(name, dataType string, policy string) database.ColumnMapping {
+		return database.ColumnMapping{
+			DBColumn: database.TableColumn{Name: name, DataType: dataType, IsNullable: true, MaxLength: 3, Precision: 5, Scale: 2},
+			Oversize: policy,
+		}
+	}
+	mapping := []database.ColumnMapping{
+		col("CODE", "nvarchar", database.OversizeTruncate),
+		col("UTF8", "varchar", database.OversizeTruncate),
+		col("AMOUNT", "decimal", database.OversizeTruncate),
+		col("NOTE", "varchar", database.OversizeNull),
+		col("STRICT", "varchar", ""),
+	}
+	mapping[1].DBColumn.Collation = "Latin1_General_100_CI_AS_SC_UTF8"
+	for i := range mapping {
+		mapping[i].CSVIndex = i
+	}
+
+	got, errs := ConvertBatch([][]string{
+		{"AB😀C", "aéb", "123.456", "long", "ok"},
+		{"ABC", "abc", "999.999", "", "toolong"},
+	}, mapping)
+
+	want := []interface{}{"AB", "aé", nil, nil, "ok"}
+	amount, _ := got[0][2].(decimal.Decimal)
+	got[0][2] = nil
+	if !reflect.DeepEqual(got[0], want) || !amount.Equal(decimal.New(12346, -2)) || errs[0] != nil {
+		t.Fatalf("row 1 = %#v (amount %v), %v; want %#v", got[0], amount, errs[0], want)
+	}
+	var sizeErr *SizeError
+	if !errors.As(errs[1], &sizeErr) || sizeErr.Column != "AMOUNT" {
+		t.Fatalf("row 2 error = %v, want AMOUNT overflow", errs[1])
+	}
+}
+
+func TestConvertDecimal(t *testing.T) {
+	cases := []struct {
+		val      interface{}
+		dataType string
+		scale    int
+		want     string // "" when refused
+	}{
+		{"0.1", "decimal", 2, "0.1"},
+		{"1234567890123456789.12", "numeric", 2, "1234567890123456789.12"},
+		{"-1.5e2", "decimal", 2, "-150"},
+		{"1.005", "decimal", 2, ""},
+		{0.1, "decimal", 2, "0.1"},
+		{int64(42), "decimal", 2, "42"},
+		{"19.99", "money", 4, "19.99"},
+		{"214748.3648", "smallmoney", 4, ""},
+	}
+	for _, c := range cases {
+		m := database.ColumnMapping{DBColumn: database.TableColumn{Name: "AMOUNT", DataType: c.dataType, Precision: 21, Scale: c.scale}}
+		if c.dataType != "decimal" && c.dataType != "numeric" {
+			m.DBColumn.Precision, m.DBColumn.Scale = 0, 0
+		}
+		var got []interface{}
+		var err error
+		if s, ok := c.val.(string); ok {
+			var rows [][]interface{}
+			var errs []error
+			rows, errs = ConvertBatch([][]string{{s}}, []database.ColumnMapping{m})
+			got, err = rows[0], errs[0]
+			if _, perr := parseValue(s, c.dataType); perr != nil && err == nil {
+				err = perr
+			}
+		} else {
+			rows, errs := ConvertValues([][]interface{}{{c.val}}, []database.ColumnMapping{m})
+			got, err = rows[0], errs[0]
+		}
+
+		d, ok := got[0].(decimal.Decimal)
+		switch {
+		case c.want == "" && err == nil:
+			t.Errorf("%s %v: expected an error, got %v", c.dataType, c.val, got[0])
+		case c.want != "" && (err != nil || !ok || d.String() != c.want):
+			t.Errorf("%s %v: got %#v, %v; want %s", c.dataType, c.val, got[0], err, c.want)
+		}
+	}
+
+	// With the truncate policy, extra places are rounded half away from zero.
+	m := database.ColumnMapping{DBColumn: database.TableColumn{Name: "AMOUNT", DataType: "decimal", Precision: 5, Scale: 2}, Oversize: database.OversizeTruncate}
+	rows, errs := ConvertBatch([][]string{{"1.005"}, {"-1.005"}}, []database.ColumnMapping{m})
+	if errs[0] != nil || rows[0][0].(decimal.Decimal).String() != "1.01" || rows[1][0].(decimal.Decimal).String() != "-1.01" {
+		t.Errorf("rounded to %v, %v (%v)", rows[0][0], rows[1][0], errs[0])
+	}
+}
+
+func TestParseValueTypes(t *testing.T) {
+	guid := mssql.UniqueIdentifier(uuid.MustParse("6F9619FF-8B86-D011-B42D-00C04FC964FF"))
+	cases := []struct {
+		val, dataType string
+		want          interface{} // nil when refused
+	}{
+		{"2026-02-11 17:02:55.1234567 +01:00", "datetimeoffset", time.Date(2026, 2, 11, 17, 2, 55, 123456700, time.FixedZone("", 3600))},
+		{"2026-02-11T17:02:55Z", "datetimeoffset", time.Date(2026, 2, 11, 17, 2, 55, 0, time.UTC)},
+		{"2026-02-11 17:02:55+05", "timestamptz", time.Date(2026, 2, 11, 17, 2, 55, 0, time.FixedZone("", 5*3600))},
+		{"2026-02-11 17:02:55", "datetimeoffset", time.Date(2026, 2, 11, 17, 2, 55, 0, time.UTC)},
+		{"11/02/2026", "datetimeoffset", nil},
+		{"6F9619FF-8B86-D011-B42D-00C04FC964FF", "uniqueidentifier", guid},
+		{"{6f9619ff-8b86-d011-b42d-00c04fc964ff}", "uniqueidentifier", guid},
+		{"{6F9619FF-8B86-D011-B42D-00C04FC964FF}", "uuid", "6f9619ff-8b86-d011-b42d-00c04fc964ff"},
+		{"6F9619FF-8B86", "uniqueidentifier", nil},
+		{"0x0A1bFF", "varbinary", []byte{0x0a, 0x1b, 0xff}},
+		{"base64:ChuA", "varbinary", []byte{0x0a, 0x1b, 0x80}},
+		{"BASE64:Chv_", "bytea", []byte{0x0a, 0x1b, 0xff}},
+		{"base64:Chv//w==", "varbinary", []byte{0x0a, 0x1b, 0xff, 0xff}},
+		{"0xZZ", "binary", nil},
+		{"ChuA", "varbinary", nil},
+		{"CAFE", "varbinary", nil},
+		{"base64:not base64!", "varbinary", nil},
+		{"<a x='1'><b/>text</a><c/>", "xml", "<a x='1'><b/>text</a><c/>"},
+		{"<a><b></a>", "xml", nil},
+		{`{"a": [1, 2]}`, "json", `{"a": [1, 2]}`},
+		{`{"a": }`, "jsonb", nil},
+		{"point (1.5 -2)", "geography", "POINT (1.5 -2)"},
+		{"SRID=4269;MULTIPOINT(1 2, (3 4))", "geometry", "SRID=4269;MULTIPOINT (1 2, (3 4))"},
+		{"POLYGON((0 0,1 0,1 1,0 0),EMPTY)", "geometry", "POLYGON ((0 0, 1 0, 1 1, 0 0), EMPTY)"},
+		{"GEOMETRYCOLLECTION(POINT Z (1 2 3), LINESTRING EMPTY)", "geometry", "GEOMETRYCOLLECTION (POINT Z (1 2 3), LINESTRING EMPTY)"},
+		{"POLYGON(0 0, 1 1)", "geometry", nil},
+		{"POINT(1)", "geography", nil},
+		{"CIRCLE(1 2)", "geometry", nil},
+		{"SRID=x;POINT(1 2)", "geometry", nil},
+		{"/1/3.2/-4/", "hierarchyid", "/1/3.2/-4/"},
+		{"/", "hierarchyid", "/"},
+		{"1/2", "hierarchyid", nil},
+	}
+	for _, c := range cases {
+		got, err := parseValue(c.val, c.dataType)
+		switch {
+		case c.want == nil:
+			if err == nil {
+				t.Errorf("%s %q: expected an error, got %#v", c.dataType, c.val, got)
+			}
+		case err != nil:
+			t.Errorf("%s %q: %v", c.dataType, c.val, err)
+		case !sameValue(got, c.want):
+			t.Errorf("%s %q: got %#v, want %#v", c.dataType, c.val, got, c.want)
+		}
+	}
+
+	// Exported values parse back to the same value.
+	for _, v := range []struct {
+		value    interface{}
+		dataType string
+	}{{guid, "uniqueidentifier"}, {[]byte{0, 1, 0xfe}, "varbinary"}} {
+		if got, err := parseValue(FormatValue(v.value, v.dataType), v.dataType); err != nil || !reflect.DeepEqual(got, v.value) {
+			t.Errorf("%s: %#v did not round-trip: %#v, %v", v.dataType, v.value, got, err)
+		}
+	}
+}
+
+// sameValue compares parsed values, times by instant and offset.
+func sameValue(got, want interface{}) bool {
+	if wt, ok := want.(time.Time); ok {
+		gt, ok := got.(time.Time)
+		_, gotOff := gt.Zone()
+		_, wantOff := wt.Zone()
+		return ok && gt.Equal(wt) && gotOff == wantOff
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func TestConvertBatchValueError(t *testing.T) {
+	mapping := []database.ColumnMapping{
+		{CSVIndex: 0, DBColumn: database.TableColumn{Name: "ID", DataType: "uniqueidentifier"}},
+		{CSVIndex: 1, DBColumn: database.TableColumn{Name: "PLACED", DataType: "datetime2", IsNullable: true}},
+	}
+	_, errs := ConvertBatch([][]string{
+		{"{6F9619FF-8B86-D011-B42D-00C04FC964FF}", "2026-02-11"},
+		{"42", "2026-02-11"},
+		{"6F9619FF-8B86-D011-B42D-00C04FC964FF", "someday"},
+	}, mapping)
+
+	var valueErr *ValueError
+	if errs[0] != nil || !errors.As(errs[1], &valueErr) || valueErr.Column != "ID" {
+		t.Fatalf("errs = %v, want an ID error on row 2 only", errs)
+	}
+	// Types the driver may still take as text are passed through.
+	if errs[2] != nil {
+		t.Errorf("unexpected error for a datetime2 string: %v", errs[2])
+	}
+}
+
+func TestParseBatchStrict(t *testing.T) {
+	mapping := []database.ColumnMapping{
+		{CSVIndex: 0, DBColumn: database.TableColumn{Name: "QTY", DataType: "int"}},
+		{CSVIndex: 1, DBColumn: database.TableColumn{Name: "PLACED", DataType: "datetime2", IsNullable: true}},
+	}
+	_, errs := ParseBatch([][]string{{"3", "2026-02-11"}, {"3", "someday"}, {"three", ""}}, mapping)
+
+	var valueErr *ValueError
+	if errs[0] != nil {
+		t.Errorf("row 1: unexpected error %v", errs[0])
+	}
+	if !errors.As(errs[1], &valueErr) || valueErr.Column != "PLACED" {
+		t.Errorf("row 2: got %v, want a PLACED error", errs[1])
+	}
+	if !errors.As(errs[2], &valueErr) || valueErr.Column != "QTY" {
+		t.Errorf("row 3: got %v, want a QTY error", errs[2])
+	}
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+// SameValue reports whether a and b, both converted for col by ConvertBatch
+// or ConvertValues, hold the same value once stored in col. Numbers and
+// decimals compare by value, binary values by content, and times to the
+// precision col keeps them at.
+func SameValue(a, b interface{}, col database.TableColumn) bool {
+	switch av := a.(type) {
+	case nil:
+		return b == nil
+	case decimal.Decimal:
+		switch bv := b.(type) {
+		case decimal.Decimal:
+			return av.Equal(bv)
+		case int64:
+			return av.Equal(decimal.NewFromInt(bv))
+		}
+		return false
+	case time.Time:
+		bv, ok := b.(time.Time)
+		return ok && sameTime(av, bv, col)
+	case []byte:
+		bv, ok := b.([]byte)
+		return ok && bytes.Equal(av, bv)
+	case int64, float32, float64:
+		return sameNumber(a, b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// sameNumber compares integers exactly and floats at the precision of the
+// less precise side, so a real read back from the table still equals the
+// text it was loaded from.
+func sameNumber(a, b interface{}) bool {
+	ai, aInt := a.(int64)
+	bi, bInt := b.(int64)
+	if aInt && bInt {
+		return ai == bi
+	}
+	af, ok := asFloat(a)
+	if !ok {
+		return false
+	}
+	bf, ok := asFloat(b)
+	if !ok {
+		return false
+	}
+	_, a32 := a.(float32)
+	_, b32 := b.(float32)
+	if a32 || b32 {
+		return float32(af) == float32(bf)
+	}
+	return af == bf
+}
+
+func asFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// sameTime compares times to the precision col stores: whole days for date,
+// SQL Server's 1/300 second for datetime and minutes for smalldatetime,
+// else col.DateTimePrecision fractional digits.
+func sameTime(a, b time.Time, col database.TableColumn) bool {
+	d := a.Sub(b)
+	if d < 0 {
+		d = -d
+	}
+	switch strings.ToLower(col.DataType) {
+	case "date":
+		ay, am, ad := a.Date()
+		by, bm, bd := b.Date()
+		return ay == by && am == bm && ad == bd
+	case "datetime":
+		return d < 2*time.Millisecond
+	case "smalldatetime":
+		return d < 30*time.Second
+	}
+	tolerance := time.Second / 2
+	for range col.DateTimePrecision {
+		tolerance /= 10
+	}
+	return d <= tolerance
+}
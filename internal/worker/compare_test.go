@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+func TestSameValue(t *testing.T) {
+	ts := time.Date(2026, 2, 11, 17, 2, 55, 123000000, time.UTC)
+	col := func(dataType string, precision int) database.TableColumn {
+		return database.TableColumn{Name: "C", DataType: dataType, DateTimePrecision: precision}
+	}
+	cases := []struct {
+		a, b interface{}
+		col  database.TableColumn
+		want bool
+	}{
+		{nil, nil, col("int", 0), true},
+		{nil, "", col("nvarchar", 0), false},
+		{int64(5), float64(5), col("float", 0), true},
+		{float64(0.1), float32(0.1), col("real", 0), true},
+		{int64(5), int64(6), col("int", 0), false},
+		{decimal.RequireFromString("12.50"), decimal.RequireFromString("12.5"), col("decimal", 0), true},
+		{decimal.RequireFromString("12.5"), "12.5", col("decimal", 0), false},
+		{ts, ts.Add(333333), col("datetime", 3), true},
+		{ts, ts.Add(3 * time.Millisecond), col("datetime", 3), false},
+		{ts, ts.Add(40 * time.Nanosecond), col("datetime2", 7), true},
+		{ts, ts.Add(time.Microsecond), col("datetime2", 7), false},
+		{ts, ts.Add(time.Hour), col("date", 0), true},
+		{[]byte{1, 2}, []byte{1, 2}, col("varbinary", 0), true},
+		{"open", "closed", col("nvarchar", 0), false},
+	}
+	for _, c := range cases {
+		if got := SameValue(c.a, c.b, c.col); got != c.want {
+			t.Errorf("SameValue(%#v, %#v, %s) = %v, want %v", c.a, c.b, c.col.DataType, got, c.want)
+		}
+	}
+}
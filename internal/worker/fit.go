@@ -0,0 +1,189 @@
+package worker
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+// SizeError is a value too long or too precise for its column, which the
+// column's oversize policy refuses.
+type SizeError struct {
+	Column string
+	// Problem describes the overflow, e.g. "longer than varchar(50)".
+	Problem string
+}
+
+func (e *SizeError) Error() string {
+	return fmt.Sprintf("value does not fit %s (%s)", e.Column, e.Problem)
+}
+
+// fitValue checks the converted value v against the declared size of m's
+// column and applies m's oversize policy (see database.OversizeTruncate). It
+// returns the value to load, or a description of the overflow when the
+// policy refuses it. Times are rounded to the column's fractional-second
+// precision, as the server would.
+func fitValue(v interface{}, m database.ColumnMapping) (interface{}, string) {
+	col := m.DBColumn
+	if t, ok := v.(time.Time); ok {
+		return roundTime(t, col), ""
+	}
+	problem := sizeProblem(v, col)
+	if problem == "" {
+		return v, ""
+	}
+	switch m.Oversize {
+	case database.OversizeTruncate:
+		if cut, ok := shrink(v, col); ok {
+			return cut, ""
+		}
+	case database.OversizeNull:
+		if col.IsNullable {
+			return nil, ""
+		}
+	}
+	return v, problem
+}
+
+// sizeProblem reports how v overflows col's declared length or precision,
+// or "" if it fits.
+func sizeProblem(v interface{}, col database.TableColumn) string {
+	dt := strings.ToLower(col.DataType)
+	switch v := v.(type) {
+	case string:
+		if isStringType(dt) && col.MaxLength > 0 && textLength(v, col) > col.MaxLength {
+			return fmt.Sprintf("longer than %s(%d)", dt, col.MaxLength)
+		}
+	case []byte:
+		if isBinaryType(dt) && col.MaxLength > 0 && len(v) > col.MaxLength {
+			return fmt.Sprintf("longer than %s(%d)", dt, col.MaxLength)
+		}
+	}
+
+	if d, ok := v.(decimal.Decimal); ok && isDecimalType(dt) {
+		precision, scale := decimalSize(col)
+		text := d.String()
+		switch {
+		case precision == 0:
+		case integerDigits(text) > precision-scale:
+			return fmt.Sprintf("exceed %s(%d,%d)", dt, precision, scale)
+		case fractionDigits(text) > scale:
+			return fmt.Sprintf("more decimal places than %s(%d,%d)", dt, precision, scale)
+		}
+	}
+	return ""
+}
+
+// decimalSize returns the precision and scale of a decimal or money column,
+// or 0, 0 if unknown. SQL Server's money types are fixed at four decimal
+// places.
+func decimalSize(col database.TableColumn) (precision, scale int) {
+	if col.Precision > 0 {
+		return col.Precision, col.Scale
+	}
+	switch strings.ToLower(col.DataType) {
+	case "money":
+		return 19, 4
+	case "smallmoney":
+		return 10, 4
+	}
+	return 0, 0
+}
+
+// shrink cuts v down to fit col, reporting false if it can't be.
+func shrink(v interface{}, col database.TableColumn) (interface{}, bool) {
+	var cut interface{}
+	switch v := v.(type) {
+	case string:
+		cut = truncateText(v, col)
+	case []byte:
+		cut = v[:col.MaxLength]
+	case decimal.Decimal:
+		// Half away from zero, as the server rounds.
+		_, scale := decimalSize(col)
+		cut = v.Round(int32(scale))
+	default:
+		return v, false
+	}
+	// Rounding can carry into another integer digit: 99.999 → 100.00.
+	return cut, sizeProblem(cut, col) == ""
+}
+
+// textLength returns the length of s in the units col's length is declared
+// in: UTF-16 code units for nchar and nvarchar, bytes for char and varchar
+// with a UTF-8 collation, else characters.
+func textLength(s string, col database.TableColumn) int {
+	n := 0
+	for _, r := range s {
+		n += runeUnits(r, col)
+	}
+	return n
+}
+
+// truncateText cuts s to col's length without splitting a character.
+func truncateText(s string, col database.TableColumn) string {
+	n := 0
+	for i, r := range s {
+		if n += runeUnits(r, col); n > col.MaxLength {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// runeUnits returns how many length units r takes in col; see textLength.
+func runeUnits(r rune, col database.TableColumn) int {
+	switch strings.ToLower(col.DataType) {
+	case "nchar", "nvarchar", "ntext":
+		if r > 0xFFFF {
+			return 2
+		}
+	case "char", "varchar", "text":
+		if strings.HasSuffix(strings.ToUpper(col.Collation), "_UTF8") {
+			return utf8.RuneLen(r)
+		}
+	}
+	return 1
+}
+
+// isBinaryType returns true if the SQL data type holds fixed or variable
+// length bytes.
+func isBinaryType(dt string) bool {
+	switch strings.ToLower(dt) {
+	case "binary", "varbinary":
+		return true
+	}
+	return false
+}
+
+// integerDigits counts the significant digits before the decimal point.
+func integerDigits(val string) int {
+	val = strings.TrimLeft(val, "+-")
+	if i := strings.IndexAny(val, ".eE"); i >= 0 {
+		val = val[:i]
+	}
+	return len(strings.TrimLeft(val, "0"))
+}
+
+// fractionDigits counts the digits after the decimal point, ignoring
+// trailing zeros.
+func fractionDigits(val string) int {
+	i := strings.IndexByte(val, '.')
+	if i < 0 {
+		return 0
+	}
+	return len(strings.TrimRight(val[i+1:], "0"))
+}
+
+// roundTime rounds t to col's fractional-second precision, if it has one.
+func roundTime(t time.Time, col database.TableColumn) time.Time {
+	if p := col.DateTimePrecision; p > 0 && p < 9 {
+		return t.Round(time.Duration(math.Pow10(9 - p)))
+	}
+	return t
+}
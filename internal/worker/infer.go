@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+// nvarcharSizes are the lengths SchemaInferrer picks from for text columns,
+// the smallest with room for twice the longest value seen.
+var nvarcharSizes = []int{50, 100, 255, 500, 1000, 2000, 4000}
+
+// maxKeyLength is the longest nvarchar SQL Server can index.
+const maxKeyLength = 450
+
+// plainNumber matches numbers without an exponent or leading zeros, so
+// codes such as "0150" stay text.
+var plainNumber = regexp.MustCompile(`^[+-]?(0|[1-9]\d*)(\.\d+)?$`)
+
+// SchemaInferrer guesses a SQL Server type for each column of a file from a
+// sample of its rows, preferring in turn bit, int, bigint, decimal(p,s),
+// datetime2, datetimeoffset and nvarchar(n). Each guess is checked with the
+// same conversion the import uses, so every sampled value will load.
+type SchemaInferrer struct {
+	columns []*columnGuess
+}
+
+// columnGuess tracks what the values seen so far in a column could be.
+type columnGuess struct {
+	name string
+	seen bool // a non-empty value
+
+	notBit, notInt, notDecimal, notDateTime, notOffset bool
+
+	big       bool // integers outside int's range
+	intDigits int
+	scale     int
+	maxLen    int // in UTF-16 code units, as nvarchar counts
+}
+
+// NewSchemaInferrer returns a SchemaInferrer for a file with headers.
+func NewSchemaInferrer(headers []string) *SchemaInferrer {
+	s := &SchemaInferrer{columns: make([]*columnGuess, len(headers))}
+	for i, h := range headers {
+		s.columns[i] = &columnGuess{name: h}
+	}
+	return s
+}
+
+// Add samples rows of text, such as CSV fields.
+func (s *SchemaInferrer) Add(rows [][]string) {
+	for _, row := range rows {
+		for i, g := range s.columns {
+			if i < len(row) {
+				g.add(strings.TrimSpace(row[i]))
+			}
+		}
+	}
+}
+
+// AddValues samples rows of typed values (see ConvertValues); nil is NULL.
+func (s *SchemaInferrer) AddValues(rows [][]interface{}) {
+	for _, row := range rows {
+		for i, g := range s.columns {
+			var v interface{}
+			if i < len(row) {
+				v = row[i]
+			}
+			switch v := v.(type) {
+			case nil:
+			case string:
+				g.add(strings.TrimSpace(v))
+			case bool:
+				g.add(strconv.FormatBool(v))
+			case float64:
+				g.add(decimal.NewFromFloat(v).String())
+			case float32:
+				g.add(decimal.NewFromFloat32(v).String())
+			case time.Time:
+				g.add(FormatValue(v, "datetime2"))
+			case []byte:
+				if utf8.Valid(v) {
+					g.add(string(v))
+				} else {
+					g.add(FormatValue(v, ""))
+				}
+			default:
+				g.add(FormatValue(v, ""))
+			}
+		}
+	}
+}
+
+func (g *columnGuess) add(val string) {
+	if val == "" {
+		return
+	}
+	g.seen = true
+	if n := textLength(val, database.TableColumn{DataType: "nvarchar"}); n > g.maxLen {
+		g.maxLen = n
+	}
+
+	switch strings.ToLower(val) {
+	case "true", "false", "yes", "no":
+	default:
+		g.notBit = true
+	}
+
+	number := plainNumber.MatchString(val)
+	if !g.notInt {
+		iv, err := strconv.ParseInt(val, 10, 64)
+		switch {
+		case !number || err != nil:
+			g.notInt = true
+		case iv < math.MinInt32 || iv > math.MaxInt32:
+			g.big = true
+		}
+	}
+	if !g.notDecimal {
+		if number {
+			intPart, frac, _ := strings.Cut(strings.TrimLeft(val, "+-"), ".")
+			g.intDigits = max(g.intDigits, len(strings.TrimLeft(intPart, "0")))
+			g.scale = max(g.scale, len(frac))
+		} else {
+			g.notDecimal = true
+		}
+	}
+	if !g.notDateTime && !isDate(parseValue(val, "datetime2")) {
+		g.notDateTime = true
+	}
+	if !g.notOffset && !isDate(parseValue(val, "datetimeoffset")) {
+		g.notOffset = true
+	}
+}
+
+// isDate reports whether parseValue returned a date, not just a time of day.
+func isDate(v interface{}, err error) bool {
+	t, ok := v.(time.Time)
+	return err == nil && ok && t.Year() > 0
+}
+
+// Columns returns the inferred columns, in file order. Columns in
+// pkColumns are NOT NULL, and key text columns are capped at the length SQL
+// Server can index; every other column is nullable, since a sample can't
+// show that a column is never empty.
+func (s *SchemaInferrer) Columns(pkColumns []string) []database.TableColumn {
+	cols := make([]database.TableColumn, len(s.columns))
+	for i, g := range s.columns {
+		col := g.column()
+		col.OrdinalPos = i + 1
+		col.IsNullable = true
+		for _, pk := range pkColumns {
+			if strings.EqualFold(pk, g.name) {
+				col.IsNullable = false
+				if col.DataType == "nvarchar" && (col.MaxLength < 0 || col.MaxLength > maxKeyLength) {
+					col.MaxLength = maxKeyLength
+				}
+			}
+		}
+		cols[i] = col
+	}
+	return cols
+}
+
+// column returns the best type for the values seen.
+func (g *columnGuess) column() database.TableColumn {
+	col := database.TableColumn{Name: g.name}
+	switch {
+	case !g.seen:
+		col.DataType, col.MaxLength = "nvarchar", nvarcharSizes[2]
+	case !g.notBit:
+		col.DataType = "bit"
+	case !g.notInt && !g.big:
+		col.DataType = "int"
+	case !g.notInt:
+		col.DataType = "bigint"
+	case !g.notDecimal && g.intDigits+g.scale <= 36:
+		// Two more integer digits than seen, and at least one.
+		col.DataType = "decimal"
+		col.Precision, col.Scale = max(g.intDigits, 1)+2+g.scale, g.scale
+	case !g.notDateTime:
+		col.DataType = "datetime2"
+	case !g.notOffset:
+		col.DataType = "datetimeoffset"
+	default:
+		col.DataType, col.MaxLength = "nvarchar", -1
+		for _, n := range nvarcharSizes {
+			if n >= 2*g.maxLen {
+				col.MaxLength = n
+				break
+			}
+		}
+	}
+	return col
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+func TestSchemaInferrer(t *testing.T) {
+	headers := []string{"ID", "BIG", "AMOUNT", "ZIP", "ACTIVE", "PLACED", "SHIPPED", "NOTE", "EMPTY", "AT"}
+	inf := NewSchemaInferrer(headers)
+	inf.Add([][]string{
+		{"1", "1", "12.5", "0150", "true", "2026-02-11", "2026-02-11T10:00:00+01:00", "Ann", "", "08:30:00"},
+		{"-2", "3000000000", "-1234.125", "1234", "No", "2026-02-11 17:02:55.123", "2026-02-11 10:00:00", "a longer note", "", "09:00:00"},
+		{"", "", "", "", "", "", "", "", "", ""},
+	})
+	inf.AddValues([][]interface{}{
+		{int64(3), int64(4), 0.1, nil, false, time.Date(2026, 2, 12, 0, 0, 0, 0, time.UTC), nil, []byte("x"), nil, nil},
+	})
+
+	got := inf.Columns([]string{"id", "note"})
+	type col struct {
+		dataType     string
+		length, p, s int
+		nullable     bool
+	}
+	want := []col{
+		{"int", 0, 0, 0, false},
+		{"bigint", 0, 0, 0, true},
+		{"decimal", 0, 9, 3, true},
+		{"nvarchar", 50, 0, 0, true},
+		{"bit", 0, 0, 0, true},
+		{"datetime2", 0, 0, 0, true},
+		{"datetimeoffset", 0, 0, 0, true},
+		{"nvarchar", 50, 0, 0, false},
+		{"nvarchar", 255, 0, 0, true},
+		{"nvarchar", 50, 0, 0, true},
+	}
+	for i, c := range got {
+		if g := (col{c.DataType, c.MaxLength, c.Precision, c.Scale, c.IsNullable}); g != want[i] || c.Name != headers[i] {
+			t.Errorf("%s: got %+v, want %+v", headers[i], g, want[i])
+		}
+	}
+
+	// Long text falls back to nvarchar(max), except in a key.
+	long := NewSchemaInferrer([]string{"A", "B"})
+	text := string(make([]byte, 2001))
+	long.Add([][]string{{"x" + text, "x" + text}})
+	cols := long.Columns([]string{"B"})
+	if cols[0].MaxLength != -1 || cols[1].MaxLength != 450 {
+		t.Errorf("lengths = %d, %d; want -1, 450", cols[0].MaxLength, cols[1].MaxLength)
+	}
+}
+
+func TestSchemaInferrerLoads(t *testing.T) {
+	// Every sampled value converts cleanly into the inferred type.
+	rows := [][]string{{"99.99", "2026-02-11T17:02:55.1234567", "-7"}, {"100", "2026-01-01", "0"}}
+	inf := NewSchemaInferrer([]string{"A", "B", "C"})
+	inf.Add(rows)
+	mapping := make([]database.ColumnMapping, 3)
+	for i, c := range inf.Columns(nil) {
+		mapping[i] = database.ColumnMapping{CSVIndex: i, DBColumn: c}
+	}
+	for _, row := range rows {
+		v := NewValidator(mapping, nil)
+		v.Check([][]string{row}, []int{1})
+		if issues := v.Issues(); len(issues) > 0 {
+			t.Errorf("%v: %v", row, issues)
+		}
+	}
+	if !reflect.DeepEqual([]string{mapping[0].DBColumn.DataType, mapping[1].DBColumn.DataType, mapping[2].DBColumn.DataType},
+		[]string{"decimal", "datetime2", "int"}) {
+		t.Errorf("unexpected types %+v", mapping)
+	}
+}
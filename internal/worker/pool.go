@@ -2,9 +2,13 @@ package worker
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/walkerscm/scaleSyncGo/internal/database"
 )
@@ -13,39 +17,77 @@ import (
 type Job struct {
 	BatchNum int
 	Rows     [][]string
+	// Values holds rows of typed values (see ConvertValues) in place of
+	// Rows, for input formats that carry types.
+	Values [][]interface{}
+	// Lines holds the source line each row starts on, parallel to Rows.
+	Lines []int
+	// Offset and Line mark the position in the source file just past the
+	// batch's last row, echoed back in the Result for checkpointing.
+	Offset int64
+	Line   int
+	// SourceOffset is how far into the file on disk the batch was read,
+	// which differs from Offset for compressed input; it is echoed back in
+	// the Result for progress reporting.
+	SourceOffset int64
 }
 
 // Result reports the outcome of a single batch insert.
 type Result struct {
 	BatchNum int
+	// RowCount is the number of rows committed.
 	RowCount int
-	Err      error
+	// Rejects holds rows the database refused; the rest of the batch was
+	// still committed.
+	Rejects      []Reject
+	Offset       int64
+	Line         int
+	SourceOffset int64
+	// Err is set when the batch could not be loaded for a reason unrelated
+	// to individual rows (lost connection, cancelled context, ...).
+	Err error
+	// Settled holds, when Err is set, the source lines of the rows that
+	// were committed or rejected before the failure, so a retry of the
+	// batch can skip them.
+	Settled []int
+}
+
+// Reject is a source row that could not be inserted.
+type Reject struct {
+	Row  []string
+	Line int
+	// Column is the offending column when it can be determined, else empty.
+	Column string
+	Err    error
 }
 
 // Pool manages a set of worker goroutines that consume jobs from a channel.
 type Pool struct {
-	db          *sql.DB
+	dest        database.Destination
 	schemaTable string
 	columns     []string
 	pkColumns   []string
 	hasIdentity bool
 	mapping     []database.ColumnMapping
 	workers     int
+	logger      *slog.Logger
 	jobs        chan Job
 	results     chan Result
 	wg          sync.WaitGroup
 }
 
 // NewPool creates a worker pool ready to process batches.
-func NewPool(db *sql.DB, schemaTable string, columns []string, pkColumns []string, hasIdentity bool, mapping []database.ColumnMapping, workers int) *Pool {
+// Per-batch timings are logged to logger at debug level.
+func NewPool(dest database.Destination, schemaTable string, columns []string, pkColumns []string, hasIdentity bool, mapping []database.ColumnMapping, workers int, logger *slog.Logger) *Pool {
 	return &Pool{
-		db:          db,
+		dest:        dest,
 		schemaTable: schemaTable,
 		columns:     columns,
 		pkColumns:   pkColumns,
 		hasIdentity: hasIdentity,
 		mapping:     mapping,
 		workers:     workers,
+		logger:      logger,
 		jobs:        make(chan Job, workers*2),
 		results:     make(chan Result, workers*2),
 	}
@@ -58,15 +100,41 @@ func (p *Pool) Start(ctx context.Context) {
 		go func(id int) {
 			defer p.wg.Done()
 			for job := range p.jobs {
-				converted := ConvertBatch(job.Rows, p.mapping)
-				err := database.InsertBatch(ctx, p.db, p.schemaTable, p.columns, p.pkColumns, p.hasIdentity, converted)
+				start := time.Now()
+				var converted [][]interface{}
+				var errs []error
+				if job.Values != nil {
+					converted, errs = ConvertValues(job.Values, p.mapping)
+				} else {
+					converted, errs = ConvertBatch(job.Rows, p.mapping)
+				}
+				rows, rejects := p.refuse(&job, converted, errs)
+				loaded, dbRejects, err := p.insert(ctx, &job, rows, converted)
+				rejects = append(rejects, dbRejects...)
+				var settled []int
 				if err != nil {
 					err = fmt.Errorf("worker %d, batch %d: %w", id, job.BatchNum, err)
+					p.logger.Warn("batch failed", "worker", id, "batch", job.BatchNum,
+						"committed", len(loaded), "rejected", len(rejects), "err", err)
+					for _, i := range loaded {
+						settled = append(settled, job.Lines[i])
+					}
+					for _, r := range rejects {
+						settled = append(settled, r.Line)
+					}
+				} else {
+					p.logger.Debug("batch loaded", "worker", id, "batch", job.BatchNum,
+						"rows", len(loaded), "rejected", len(rejects), "duration", time.Since(start))
 				}
 				p.results <- Result{
-					BatchNum: job.BatchNum,
-					RowCount: len(job.Rows),
-					Err:      err,
+					BatchNum:     job.BatchNum,
+					RowCount:     len(loaded),
+					Rejects:      rejects,
+					Offset:       job.Offset,
+					Line:         job.Line,
+					SourceOffset: job.SourceOffset,
+					Err:          err,
+					Settled:      settled,
 				}
 			}
 		}(i)
@@ -79,6 +147,117 @@ func (p *Pool) Start(ctx context.Context) {
 	}()
 }
 
+// refuse turns the rows whose conversion failed into rejects. It returns
+// the indices of the other rows, with converted compacted to hold just them.
+func (p *Pool) refuse(job *Job, converted [][]interface{}, errs []error) ([]int, []Reject) {
+	var rows []int
+	var rejects []Reject
+	for i, err := range errs {
+		if err == nil {
+			converted[len(rows)] = converted[i]
+			rows = append(rows, i)
+			continue
+		}
+		p.logger.Debug("row rejected", "line", job.Lines[i], "err", err)
+		var column string
+		var sizeErr *SizeError
+		var valueErr *ValueError
+		switch {
+		case errors.As(err, &sizeErr):
+			column = sizeErr.Column
+		case errors.As(err, &valueErr):
+			column = valueErr.Column
+		}
+		rejects = append(rejects, Reject{Row: job.text(i), Line: job.Lines[i], Column: column, Err: err})
+	}
+	return rows, rejects
+}
+
+// insert loads the given rows of job, whose converted values are in
+// converted, bisecting on failure to isolate the rows the database refuses.
+// The good rows are committed and returned, and the bad ones returned as
+// rejects. Only errors the database attributes to row values are bisected;
+// any other error stops the bisection and is returned as-is, along with the
+// rows committed and rejected before it.
+func (p *Pool) insert(ctx context.Context, job *Job, rows []int, converted [][]interface{}) ([]int, []Reject, error) {
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
+	converted = converted[:len(rows)]
+	err := p.insertBatch(ctx, converted)
+	if err == nil {
+		// Capped so appending to the result can't overwrite the other half.
+		return rows[:len(rows):len(rows)], nil, nil
+	}
+	if ctx.Err() != nil || database.ClassifyError(err) != database.ErrorRow {
+		return nil, nil, err
+	}
+
+	line := job.Lines[rows[0]]
+	if len(rows) == 1 {
+		p.logger.Debug("row rejected", "line", line, "err", err)
+		return nil, []Reject{{Row: job.text(rows[0]), Line: line, Column: errorColumn(err, p.columns), Err: err}}, nil
+	}
+	p.logger.Debug("bisecting batch to isolate rejected rows", "rows", len(rows), "first_line", line, "err", err)
+
+	mid := len(rows) / 2
+	l1, r1, err := p.insert(ctx, job, rows[:mid], converted[:mid])
+	if err != nil {
+		return l1, r1, err
+	}
+	l2, r2, err := p.insert(ctx, job, rows[mid:], converted[mid:])
+	return append(l1, l2...), append(r1, r2...), err
+}
+
+// insertRetries is how many times a batch is retried after a transient
+// error such as a deadlock, waiting retryDelay, then twice that, and so on.
+const insertRetries = 3
+
+var retryDelay = 250 * time.Millisecond
+
+// insertBatch inserts rows, retrying them when the database reports a
+// transient error.
+func (p *Pool) insertBatch(ctx context.Context, rows [][]interface{}) error {
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		err := p.dest.InsertBatch(ctx, p.schemaTable, p.columns, p.pkColumns, p.hasIdentity, rows)
+		if err == nil || attempt == insertRetries || database.ClassifyError(err) != database.ErrorRetry {
+			return err
+		}
+		p.logger.Warn("retrying batch", "rows", len(rows), "attempt", attempt+1, "err", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// text returns row i of the job as CSV fields.
+func (j *Job) text(i int) []string {
+	if j.Values != nil {
+		return TextRow(j.Values[i])
+	}
+	return j.Rows[i]
+}
+
+// quotedName matches names the server quotes in error messages, e.g.
+// "... in table 'dbo.X', column 'NAME'".
+var quotedName = regexp.MustCompile(`'([^']+)'`)
+
+// errorColumn returns the column named in a database error, if any.
+func errorColumn(err error, columns []string) string {
+	for _, m := range quotedName.FindAllStringSubmatch(err.Error(), -1) {
+		for _, col := range columns {
+			if strings.EqualFold(m[1], col) {
+				return col
+			}
+		}
+	}
+	return ""
+}
+
 // Submit sends a job to the worker pool.
 func (p *Pool) Submit(job Job) {
 	p.jobs <- job
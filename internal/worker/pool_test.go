@@ -0,0 +1,151 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+// fakeDest is a Destination whose InsertBatch is insert; every other method
+// panics.
+type fakeDest struct {
+	database.Destination
+	mu     sync.Mutex
+	calls  int
+	insert func(rows [][]interface{}) error
+}
+
+func (f *fakeDest) InsertBatch(_ context.Context, _ string, _, _ []string, _ bool, rows [][]interface{}) error {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	return f.insert(rows)
+}
+
+// runPool loads rows of (ID, NAME) into dest through a one-worker pool and
+// returns the single result.
+func runPool(t *testing.T, dest *fakeDest, rows [][]string) Result {
+	t.Helper()
+	mapping := []database.ColumnMapping{
+		{CSVIndex: 0, CSVName: "ID", DBColumn: database.TableColumn{Name: "ID", DataType: "int"}},
+		{CSVIndex: 1, CSVName: "NAME", DBColumn: database.TableColumn{Name: "NAME", DataType: "nvarchar", IsNullable: true}},
+	}
+	lines := make([]int, len(rows))
+	for i := range rows {
+		lines[i] = i + 2
+	}
+	pool := NewPool(dest, "dbo.T", []string{"ID", "NAME"}, []string{"ID"}, false, mapping, 1,
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	pool.Start(context.Background())
+	pool.Submit(Job{BatchNum: 0, Rows: rows, Lines: lines})
+	pool.Done()
+
+	var results []Result
+	for r := range pool.Results() {
+		results = append(results, r)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	return results[0]
+}
+
+func TestPoolBisectsRowErrors(t *testing.T) {
+	dest := &fakeDest{insert: func(rows [][]interface{}) error {
+		for _, row := range rows {
+			if row[1] == "bad" {
+				return fmt.Errorf("merge: %w", mssql.Error{Number: 2627, Message: "Violation of PRIMARY KEY constraint"})
+			}
+		}
+		return nil
+	}}
+	res := runPool(t, dest, [][]string{{"1", "a"}, {"2", "bad"}, {"3", "c"}, {"4", "d"}})
+
+	if res.Err != nil {
+		t.Fatalf("unexpected error: %v", res.Err)
+	}
+	if res.RowCount != 3 || len(res.Rejects) != 1 || res.Rejects[0].Line != 3 {
+		t.Errorf("expected 3 rows loaded and line 3 rejected, got %d loaded, rejects %+v", res.RowCount, res.Rejects)
+	}
+}
+
+func TestPoolFailsBatchOnNonRowError(t *testing.T) {
+	cases := map[string]error{
+		"permission denied": mssql.Error{Number: 229, Message: "The INSERT permission was denied"},
+		"invalid object":    mssql.Error{Number: 208, Message: "Invalid object name 'dbo.T'"},
+		"lock timeout":      mssql.Error{Number: 1222, Message: "Lock request time out period exceeded"},
+		"no error number":   errors.New("connection reset by peer"),
+	}
+	for name, failure := range cases {
+		t.Run(name, func(t *testing.T) {
+			dest := &fakeDest{insert: func([][]interface{}) error { return fmt.Errorf("merge: %w", failure) }}
+			res := runPool(t, dest, [][]string{{"1", "a"}, {"2", "b"}, {"3", "c"}, {"4", "d"}})
+
+			if res.Err == nil || !strings.Contains(res.Err.Error(), failure.Error()) {
+				t.Errorf("expected batch error wrapping %v, got %v", failure, res.Err)
+			}
+			if dest.calls != 1 {
+				t.Errorf("expected 1 insert without bisection, got %d", dest.calls)
+			}
+			if res.RowCount != 0 || len(res.Rejects) != 0 {
+				t.Errorf("expected nothing loaded or rejected, got %d loaded, %d rejected", res.RowCount, len(res.Rejects))
+			}
+		})
+	}
+}
+
+func TestPoolRetriesDeadlock(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	dest := &fakeDest{}
+	dest.insert = func([][]interface{}) error {
+		if dest.calls == 1 {
+			return mssql.Error{Number: 1205, Message: "chosen as the deadlock victim"}
+		}
+		return nil
+	}
+	res := runPool(t, dest, [][]string{{"1", "a"}, {"2", "b"}})
+
+	if res.Err != nil || res.RowCount != 2 {
+		t.Errorf("expected 2 rows loaded after retry, got %d, err %v", res.RowCount, res.Err)
+	}
+	if dest.calls != 2 {
+		t.Errorf("expected 2 inserts, got %d", dest.calls)
+	}
+}
+
+func TestPoolReportsSettledRowsOfFailedBatch(t *testing.T) {
+	// The first half of the batch bisects cleanly, then the server goes away.
+	dest := &fakeDest{insert: func(rows [][]interface{}) error {
+		for _, row := range rows {
+			switch row[1] {
+			case "bad":
+				return mssql.Error{Number: 547, Message: "The INSERT statement conflicted with the FOREIGN KEY constraint"}
+			case "gone":
+				return mssql.Error{Number: 9002, Message: "The transaction log for database 'db' is full"}
+			}
+		}
+		return nil
+	}}
+	res := runPool(t, dest, [][]string{{"1", "a"}, {"2", "bad"}, {"3", "gone"}, {"4", "d"}})
+
+	if res.Err == nil {
+		t.Fatal("expected the batch to fail")
+	}
+	if res.RowCount != 1 || len(res.Rejects) != 1 {
+		t.Errorf("expected 1 row committed and 1 rejected, got %d and %d", res.RowCount, len(res.Rejects))
+	}
+	if len(res.Settled) != 2 || res.Settled[0] != 2 || res.Settled[1] != 3 {
+		t.Errorf("expected lines 2 and 3 settled, got %v", res.Settled)
+	}
+}
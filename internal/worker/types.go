@@ -0,0 +1,351 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// ValueError is a value that can't be converted to its column's type.
+type ValueError struct {
+	Column string
+	// Problem describes the failure, e.g. "not a valid uniqueidentifier".
+	Problem string
+}
+
+func (e *ValueError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Column, e.Problem)
+}
+
+// isCheckedType returns true if the SQL data type is one whose text the
+// driver can't load as is, so a value parseValue fails on is refused with a
+// ValueError rather than passed through.
+func isCheckedType(dt string) bool {
+	switch strings.ToLower(dt) {
+	case "datetimeoffset", "timestamptz",
+		"uniqueidentifier", "uuid",
+		"binary", "varbinary", "image", "bytea",
+		"xml", "json", "jsonb",
+		"geography", "geometry", "hierarchyid":
+		return true
+	}
+	return false
+}
+
+// offsetFormats are the datetimeoffset formats tried before dateTimeFormats,
+// whose values are taken as UTC. Fractional seconds are accepted after the
+// seconds of any of them.
+var offsetFormats = []string{
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02T15:04:05 Z07:00",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05Z07",
+}
+
+// parseOffsetTime parses a datetimeoffset or timestamptz value.
+func parseOffsetTime(val, dataType string) (interface{}, error) {
+	for _, layout := range offsetFormats {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range dateTimeFormats {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t, nil
+		}
+	}
+	return val, fmt.Errorf("not parseable as %s", strings.ToLower(dataType))
+}
+
+// parseGUID parses a GUID with or without braces, hyphens or a "urn:uuid:"
+// prefix. SQL Server gets an mssql.UniqueIdentifier, which the driver sends
+// in the server's byte order; Postgres gets the canonical text.
+func parseGUID(val, dataType string) (interface{}, error) {
+	u, err := uuid.Parse(val)
+	if err != nil {
+		return val, fmt.Errorf("not a valid %s", strings.ToLower(dataType))
+	}
+	if strings.EqualFold(dataType, "uuid") {
+		return u.String(), nil
+	}
+	return mssql.UniqueIdentifier(u), nil
+}
+
+// base64Prefix marks a binary value given as base64.
+const base64Prefix = "base64:"
+
+// parseBinary decodes "0x"-prefixed hex, as FormatValue writes it, or
+// "base64:"-prefixed base64 in either alphabet, padded or not. Anything else
+// is refused rather than guessed at: plain text such as "CAFE" or "test" is
+// valid base64 too.
+func parseBinary(val, dataType string) (interface{}, error) {
+	if len(val) >= 2 && (val[:2] == "0x" || val[:2] == "0X") {
+		b, err := hex.DecodeString(val[2:])
+		if err != nil {
+			return val, fmt.Errorf("not valid hex for %s", strings.ToLower(dataType))
+		}
+		return b, nil
+	}
+	if len(val) >= len(base64Prefix) && strings.EqualFold(val[:len(base64Prefix)], base64Prefix) {
+		enc := val[len(base64Prefix):]
+		for _, e := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if b, err := e.DecodeString(enc); err == nil {
+				return b, nil
+			}
+		}
+		return val, fmt.Errorf("not valid base64 for %s", strings.ToLower(dataType))
+	}
+	return val, fmt.Errorf("not 0x-prefixed hex or base64:-prefixed base64 for %s", strings.ToLower(dataType))
+}
+
+// checkXML checks that val is well-formed XML. Like the xml type, it allows
+// fragments: several top-level elements, or plain text.
+func checkXML(val string) (interface{}, error) {
+	d := xml.NewDecoder(strings.NewReader(val))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return val, nil
+		}
+		if err != nil {
+			var se *xml.SyntaxError
+			if errors.As(err, &se) {
+				return val, fmt.Errorf("not well-formed xml (line %d: %s)", se.Line, se.Msg)
+			}
+			return val, fmt.Errorf("not well-formed xml (%v)", err)
+		}
+	}
+}
+
+// checkJSON checks that val is a single valid JSON value.
+func checkJSON(val, dataType string) (interface{}, error) {
+	if !json.Valid([]byte(val)) {
+		return val, fmt.Errorf("not valid %s", strings.ToLower(dataType))
+	}
+	return val, nil
+}
+
+// hierarchyPath matches the text form of a hierarchyid, e.g. "/1/3.2/-4/".
+var hierarchyPath = regexp.MustCompile(`^/(-?\d+(\.-?\d+)*/)*$`)
+
+// checkHierarchyID checks that val is a hierarchyid path.
+func checkHierarchyID(val string) (interface{}, error) {
+	if !hierarchyPath.MatchString(val) {
+		return val, fmt.Errorf("not a valid hierarchyid path")
+	}
+	return val, nil
+}
+
+// wktTypes lists the geometry types accepted in WKT, with how deeply their
+// coordinates nest in parentheses.
+var wktTypes = map[string]int{
+	"POINT":              1,
+	"LINESTRING":         1,
+	"CIRCULARSTRING":     1,
+	"POLYGON":            2,
+	"MULTIPOINT":         2,
+	"MULTILINESTRING":    2,
+	"MULTIPOLYGON":       3,
+	"GEOMETRYCOLLECTION": 0,
+	"COMPOUNDCURVE":      0,
+	"CURVEPOLYGON":       0,
+}
+
+// parseWKT checks that val is well-known text for geography or geometry,
+// optionally prefixed with "SRID=n;" (EWKT). It returns the text with its
+// keywords upper-cased; without an SRID the column's default applies (4326
+// for geography, 0 for geometry on SQL Server).
+func parseWKT(val, dataType string) (interface{}, error) {
+	text := val
+	prefix := ""
+	if i := strings.IndexByte(val, ';'); i >= 0 && strings.HasPrefix(strings.ToUpper(val), "SRID=") {
+		srid, err := strconv.Atoi(strings.TrimSpace(val[5:i]))
+		if err != nil || srid < 0 {
+			return val, fmt.Errorf("invalid SRID for %s", strings.ToLower(dataType))
+		}
+		prefix = fmt.Sprintf("SRID=%d;", srid)
+		text = val[i+1:]
+	}
+	p := &wktParser{s: strings.TrimSpace(text)}
+	if err := p.geometry(); err != nil {
+		return val, fmt.Errorf("not valid WKT for %s (%v)", strings.ToLower(dataType), err)
+	}
+	if p.pos < len(p.s) {
+		return val, fmt.Errorf("not valid WKT for %s (unexpected %q)", strings.ToLower(dataType), p.s[p.pos:])
+	}
+	return prefix + p.out.String(), nil
+}
+
+// wktParser checks WKT syntax, copying it to out with keywords upper-cased.
+type wktParser struct {
+	s   string
+	pos int
+	out bytes.Buffer
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t' || p.s[p.pos] == '\n' || p.s[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+// word reads a keyword, returning it upper-cased.
+func (p *wktParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] >= 'A' && p.s[p.pos] <= 'Z' || p.s[p.pos] >= 'a' && p.s[p.pos] <= 'z') {
+		p.pos++
+	}
+	return strings.ToUpper(p.s[start:p.pos])
+}
+
+// accept reports whether the next non-space byte is c, consuming it if so.
+func (p *wktParser) accept(c byte) bool {
+	p.skipSpace()
+	if p.pos < len(p.s) && p.s[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// geometry parses one tagged geometry: a type, an optional Z/M/ZM and its
+// body or EMPTY.
+func (p *wktParser) geometry() error {
+	name := p.word()
+	depth, ok := wktTypes[name]
+	if !ok {
+		if name == "" {
+			return fmt.Errorf("missing geometry type")
+		}
+		return fmt.Errorf("unknown geometry type %s", name)
+	}
+	p.out.WriteString(name)
+	save := p.pos
+	switch dims := p.word(); dims {
+	case "Z", "M", "ZM":
+		p.out.WriteString(" " + dims)
+	case "EMPTY":
+		p.out.WriteString(" EMPTY")
+		return nil
+	default:
+		p.pos = save
+	}
+	save = p.pos
+	if p.word() == "EMPTY" {
+		p.out.WriteString(" EMPTY")
+		return nil
+	}
+	p.pos = save
+	p.out.WriteByte(' ')
+	if depth == 0 {
+		return p.collection(name)
+	}
+	return p.coordinates(depth, name == "MULTIPOINT")
+}
+
+// collection parses the members of a GEOMETRYCOLLECTION or curve type, which
+// may be tagged geometries or, for curves, untagged coordinate lists.
+func (p *wktParser) collection(name string) error {
+	if !p.accept('(') {
+		return fmt.Errorf("expected ( after %s", name)
+	}
+	p.out.WriteByte('(')
+	for i := 0; ; i++ {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.skipSpace()
+		var err error
+		if p.pos < len(p.s) && p.s[p.pos] == '(' {
+			err = p.coordinates(1, false)
+		} else {
+			err = p.geometry()
+		}
+		if err != nil {
+			return err
+		}
+		if p.accept(')') {
+			p.out.WriteByte(')')
+			return nil
+		}
+		if !p.accept(',') {
+			return fmt.Errorf("expected , or ) in %s", name)
+		}
+	}
+}
+
+// coordinates parses depth levels of parenthesized lists ending in points.
+// With barePoints, as for MULTIPOINT, the points of the outer list may also
+// be written without their own parentheses.
+func (p *wktParser) coordinates(depth int, barePoints bool) error {
+	if !p.accept('(') {
+		return fmt.Errorf("expected (")
+	}
+	p.out.WriteByte('(')
+	for i := 0; ; i++ {
+		if i > 0 {
+			p.out.WriteString(", ")
+		}
+		p.skipSpace()
+		var err error
+		switch {
+		case depth > 1 && p.pos < len(p.s) && p.s[p.pos] == '(':
+			err = p.coordinates(depth-1, false)
+		case depth > 1 && p.word() == "EMPTY":
+			p.out.WriteString("EMPTY")
+		case depth > 1 && !barePoints:
+			err = fmt.Errorf("expected (")
+		default:
+			err = p.point()
+		}
+		if err != nil {
+			return err
+		}
+		if p.accept(')') {
+			p.out.WriteByte(')')
+			return nil
+		}
+		if !p.accept(',') {
+			return fmt.Errorf("expected , or )")
+		}
+	}
+}
+
+// point parses two to four space-separated numbers.
+func (p *wktParser) point() error {
+	var nums []string
+	for {
+		p.skipSpace()
+		start := p.pos
+		for p.pos < len(p.s) && strings.IndexByte("+-.0123456789eE", p.s[p.pos]) >= 0 {
+			p.pos++
+		}
+		if start == p.pos {
+			break
+		}
+		num := p.s[start:p.pos]
+		if _, err := strconv.ParseFloat(num, 64); err != nil {
+			return fmt.Errorf("invalid coordinate %q", num)
+		}
+		nums = append(nums, num)
+	}
+	if len(nums) < 2 || len(nums) > 4 {
+		return fmt.Errorf("a point needs 2 to 4 coordinates")
+	}
+	p.out.WriteString(strings.Join(nums, " "))
+	return nil
+}
@@ -0,0 +1,200 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+// Issue is one kind of problem found in one column during validation, e.g.
+// "SHIP_DATE: 412 rows not parseable as datetime2, first at line 88".
+type Issue struct {
+	Column    string `json:"column"`
+	Problem   string `json:"problem"`
+	Count     int    `json:"count"`
+	FirstLine int    `json:"first_line"`
+	Example   string `json:"example,omitempty"`
+}
+
+func (i Issue) String() string {
+	s := fmt.Sprintf("%s: %d row(s) %s, first at line %d", i.Column, i.Count, i.Problem, i.FirstLine)
+	if i.Example != "" {
+		s += fmt.Sprintf(" (e.g. %q)", i.Example)
+	}
+	return s
+}
+
+// Validator runs rows through the same conversion as ConvertBatch without
+// writing anything, collecting every value that would fail or be silently
+// mangled on insert.
+type Validator struct {
+	mapping []database.ColumnMapping
+	pkIdx   []int          // indices into mapping of the primary key columns
+	pkName  string         // primary key columns joined, for reporting
+	seen    map[string]int // primary key → first line it appeared on
+	issues  map[string]*Issue
+	rows    int
+}
+
+// NewValidator returns a Validator for rows mapped by mapping. Duplicate
+// primary keys are only checked when every pkColumn is mapped.
+func NewValidator(mapping []database.ColumnMapping, pkColumns []string) *Validator {
+	v := &Validator{
+		mapping: mapping,
+		issues:  make(map[string]*Issue),
+	}
+
+	for _, pk := range pkColumns {
+		idx := -1
+		for i, m := range mapping {
+			if strings.EqualFold(m.DBColumn.Name, pk) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			v.pkIdx = nil
+			break
+		}
+		v.pkIdx = append(v.pkIdx, idx)
+	}
+	if len(v.pkIdx) > 0 {
+		v.pkName = strings.Join(pkColumns, ", ")
+		v.seen = make(map[string]int)
+	}
+	return v
+}
+
+// Check validates a batch of rows; lines holds each row's source line.
+func (v *Validator) Check(rows [][]string, lines []int) {
+	for i, row := range rows {
+		v.checkRow(lines[i], func(m database.ColumnMapping) (string, bool) {
+			return fieldText(row, m)
+		})
+	}
+}
+
+// CheckValues is Check for rows of typed values (see ConvertValues). Values
+// are checked in their text form, which FormatValue guarantees parses back
+// to the same value; nil is NULL.
+func (v *Validator) CheckValues(rows [][]interface{}, lines []int) {
+	for i, row := range rows {
+		v.checkRow(lines[i], func(m database.ColumnMapping) (string, bool) {
+			if m.CSVIndex < 0 || m.CSVIndex >= len(row) {
+				return fieldText(nil, m)
+			}
+			switch val := row[m.CSVIndex].(type) {
+			case nil:
+				return "", false
+			case string:
+				return strings.TrimSpace(val), true
+			default:
+				return FormatValue(val, m.DBColumn.DataType), true
+			}
+		})
+	}
+}
+
+// checkRow validates one row. field returns the row's text for a mapping
+// and whether the row has a value for it.
+func (v *Validator) checkRow(line int, field func(database.ColumnMapping) (string, bool)) {
+	v.rows++
+	for _, m := range v.mapping {
+		col := m.DBColumn
+		val, present := field(m)
+		if val == "" && m.Default != "" {
+			if _, err := evalDefault(m.Default, col.DataType); err != nil {
+				v.add(col.Name, "default "+err.Error(), line, m.Default)
+			}
+			continue
+		}
+		if !present {
+			if !col.IsNullable {
+				v.add(col.Name, "NULL in non-nullable column", line, "")
+			}
+			continue
+		}
+
+		if val == "" {
+			if !col.IsNullable && !isStringType(col.DataType) {
+				v.add(col.Name, fmt.Sprintf("empty in non-nullable %s column", strings.ToLower(col.DataType)), line, "")
+			}
+			continue
+		}
+
+		parsed, err := parseValue(val, col.DataType)
+		if err != nil {
+			v.add(col.Name, err.Error(), line, val)
+			continue
+		}
+		if _, problem := fitValue(parsed, m); problem != "" {
+			v.add(col.Name, problem, line, val)
+		}
+	}
+
+	if v.seen != nil {
+		v.checkKey(line, field)
+	}
+}
+
+// checkKey records the row's primary key and flags repeats. Keys are
+// compared case-insensitively, matching SQL Server's default collation.
+func (v *Validator) checkKey(line int, field func(database.ColumnMapping) (string, bool)) {
+	parts := make([]string, len(v.pkIdx))
+	for i, idx := range v.pkIdx {
+		val, _ := field(v.mapping[idx])
+		parts[i] = strings.ToUpper(val)
+	}
+	key := strings.Join(parts, "\x00")
+	if first, ok := v.seen[key]; ok {
+		v.add(v.pkName, fmt.Sprintf("duplicate primary key (first seen at line %d)", first), line,
+			strings.Join(parts, ", "))
+		return
+	}
+	v.seen[key] = line
+}
+
+func (v *Validator) add(column, problem string, line int, example string) {
+	// Duplicate keys are reported once per column set, not once per first line.
+	key := column + "\x00" + problem
+	if strings.HasPrefix(problem, "duplicate primary key") {
+		key = column + "\x00duplicate"
+	}
+	if is, ok := v.issues[key]; ok {
+		is.Count++
+		return
+	}
+	v.issues[key] = &Issue{Column: column, Problem: problem, Count: 1, FirstLine: line, Example: example}
+}
+
+// Rows returns the number of rows checked.
+func (v *Validator) Rows() int {
+	return v.rows
+}
+
+// Issues returns the problems found, ordered by first occurrence.
+func (v *Validator) Issues() []Issue {
+	out := make([]Issue, 0, len(v.issues))
+	for _, is := range v.issues {
+		out = append(out, *is)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].FirstLine != out[j].FirstLine {
+			return out[i].FirstLine < out[j].FirstLine
+		}
+		return out[i].Column < out[j].Column
+	})
+	return out
+}
+
+// isStringType returns true if the SQL data type holds character data, where
+// an empty string is a valid non-NULL value.
+func isStringType(dt string) bool {
+	switch strings.ToLower(dt) {
+	case "char", "varchar", "nchar", "nvarchar", "text", "ntext", "bpchar":
+		return true
+	}
+	return false
+}
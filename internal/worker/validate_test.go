@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/walkerscm/scaleSyncGo/internal/database"
+)
+
+func TestValidatorIssues(t *testing.T) {
+	mapping := []database.ColumnMapping{
+		{CSVIndex: 0, CSVName: "ID", DBColumn: database.TableColumn{Name: "ID", DataType: "int"}},
+		{CSVIndex: 1, CSVName: "SHIP_DATE", DBColumn: database.TableColumn{Name: "SHIP_DATE", DataType: "datetime2", IsNullable: true}},
+		{CSVIndex: 2, CSVName: "CODE", DBColumn: database.TableColumn{Name: "CODE", DataType: "varchar", MaxLength: 3}},
+		{CSVIndex: 3, CSVName: "AMOUNT", DBColumn: database.TableColumn{Name: "AMOUNT", DataType: "decimal", Precision: 5, Scale: 2, IsNullable: true}},
+	}
+	rows := [][]string{
+		{"1", "2026-01-02", "ABC", "123.45"},
+		{"2", "31/02/2026", "ABCD", "1234.5"},
+		{"1", "", "", ""},
+		{"x", "not a date", "A", "1"},
+	}
+	lines := []int{2, 3, 4, 5}
+
+	v := NewValidator(mapping, []string{"ID"})
+	v.Check(rows, lines)
+
+	if v.Rows() != 4 {
+		t.Errorf("expected 4 rows checked, got %d", v.Rows())
+	}
+
+	got := make(map[string]Issue)
+	for _, is := range v.Issues() {
+		got[is.Column+": "+strings.SplitN(is.Problem, " (", 2)[0]] = is
+	}
+
+	want := map[string]struct{ count, line int }{
+		"SHIP_DATE: not parseable as datetime2": {2, 3},
+		"CODE: longer than varchar(3)":          {1, 3},
+		"AMOUNT: exceed decimal(5,2)":           {1, 3},
+		"ID: duplicate primary key":             {1, 4},
+		"ID: not parseable as int":              {1, 5},
+	}
+	for key, w := range want {
+		is, ok := got[key]
+		if !ok {
+			t.Errorf("missing issue %q; got %v", key, v.Issues())
+			continue
+		}
+		if is.Count != w.count || is.FirstLine != w.line {
+			t.Errorf("%s: expected %d row(s) first at line %d, got %d at %d", key, w.count, w.line, is.Count, is.FirstLine)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d issues, got %d: %v", len(want), len(got), v.Issues())
+	}
+}